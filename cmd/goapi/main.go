@@ -1,10 +1,19 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"goapi/config"
+	"goapi/jobs"
+	"goapi/logger"
+	"goapi/repository"
 	"goapi/routes"
+	"goapi/services"
 
 	_ "goapi/docs" // This will be generated
 )
@@ -17,28 +26,128 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-// @description Type "Bearer" followed by a space and JWT token.
+// @description Type "Bearer" followed by a space and JWT token, or "ApiKey" followed by a space and an API key.
 func main() {
 	// Load configuration based on environment
 	cfg, err := config.LoadConfig()
 
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		logger.Fatal("Failed to load configuration", "error", err)
 	}
 
-	// Initialize database
-	db := config.NewPostgresDB(&cfg.Database)
+	// Initialize the repositories
+	scheduler := jobs.NewScheduler()
+	var repos *repository.Repositories
+	var store *repository.DB
+	// closeDB closes the database once the server has stopped
+	closeDB := func() error { return nil }
 
-	if err := db.Connect(); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	switch cfg.Database.Driver {
+	case config.DriverMemory:
+		logger.Warn("Using the in-memory store, data is lost when the server stops")
+		repos = repository.NewMemoryRepositories()
+
+		if cfg.Database.MemoryAdminEmail != "" {
+			if err := services.EnsureAdmin(context.Background(), repos.Users, cfg.Database.MemoryAdminEmail, cfg.Database.MemoryAdminPassword); err != nil {
+				logger.Fatal("Failed to create admin", "error", err)
+			}
+		}
+	case config.DriverSQLite:
+		db := config.NewSQLiteDB(&cfg.Database)
+
+		if err := db.Connect(); err != nil {
+			logger.Fatal("Failed to open database", "error", err)
+		}
+		closeDB = db.Close
+		logger.Info("Using the SQLite database", "path", cfg.Database.SQLitePath)
+
+		store = repository.NewDB(db.GetDB(), cfg.Database.QueryTimeout)
+		repos = repository.NewSQLiteRepositories(store, cfg.Database.TxMaxRetries)
+	default:
+		db := config.NewPostgresDB(&cfg.Database)
+
+		if err := db.Connect(); err != nil {
+			logger.Fatal("Failed to connect to database", "error", err)
+		}
+		closeDB = db.Close
+
+		// Repositories share the handle that bounds each query with the timeout
+		// and routes reads to the replicas
+		store = repository.NewDB(db.GetDB(), cfg.Database.QueryTimeout).WithReplicas(db.GetReplicas()...)
+		repos = repository.NewPostgresRepositories(store, cfg.Database.TxMaxRetries)
+
+		if store.HasReplicas() {
+			scheduler.Add(jobs.NewReplicaHealthJob(store), cfg.Database.ReplicaCheckInterval)
+		}
+	}
+
+	// Stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start background jobs
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+
+	if cfg.Retention.Enabled {
+		scheduler.Add(jobs.NewRetentionJob(repos.Retention, cfg.Retention), cfg.Retention.Interval)
 	}
+	scheduler.Start(jobsCtx)
+
+	// Re-read LOG_LEVEL on SIGHUP
+	reloadLogLevelOnHangup(cfg.Environment)
 
 	// Setup router
-	router := routes.SetupRouter(db.GetDB())
+	router := routes.SetupRouter(cfg, repos, store)
 
 	// Start server
-	log.Printf("Server starting on port %s", cfg.Server.Port)
-	if err := router.Run(":" + cfg.Server.Port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{
+		Addr:    ":" + cfg.Server.Port,
+		Handler: router,
+	}
+	go func() {
+		logger.Info("Server starting", "port", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start server", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	// A second signal stops the process at once
+	stop()
+	logger.Info("Shutting down, waiting for in-flight requests", "timeout", cfg.Server.ShutdownTimeout)
+
+	// Stop accepting requests and let the in-flight ones finish
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Server shutdown", "error", err)
+	}
+
+	// Then stop the jobs, letting a running one return
+	cancelJobs()
+	scheduler.Wait()
+
+	if err := closeDB(); err != nil {
+		logger.Error("Failed to close database", "error", err)
 	}
+	logger.Info("Server stopped")
+}
+
+// reloadLogLevelOnHangup applies LOG_LEVEL again whenever the process
+// receives SIGHUP, which no longer stops it
+func reloadLogLevelOnHangup(env config.Environment) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		for range hangup {
+			level, err := config.ReloadLogLevel(env)
+			if err != nil {
+				logger.Error("Failed to reload the log level", "error", err)
+				continue
+			}
+			logger.Info("Log level reloaded", "level", level)
+		}
+	}()
 }
@@ -14,7 +14,7 @@ func main() {
 	// Load configuration based on environment
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		logger.Error("Failed to load configuration: %v", err)
+		logger.Errorf("Failed to load configuration: %v", err)
 		os.Exit(1)
 	}
 	// valid flag values
@@ -28,40 +28,49 @@ func main() {
 	}
 
 	// Initialize database
-	db := config.NewPostgresDB(&cfg.Database)
+	var db config.Database
+	switch cfg.Database.Driver {
+	case config.DriverSQLite:
+		db = config.NewSQLiteDB(&cfg.Database)
+	case config.DriverMemory:
+		logger.Error("The in-memory store has no migrations")
+		os.Exit(1)
+	default:
+		db = config.NewPostgresDB(&cfg.Database)
+	}
 	// connect to the database
 	if err := db.Connect(); err != nil {
-		logger.Error("Failed to connect to database: %v", err)
+		logger.Errorf("Failed to connect to database: %v", err)
 		os.Exit(1)
 	}
 
 	// Initialize migration manager
-	manager, err := migrations.NewManager(db.GetDB())
+	manager, err := migrations.NewManager(db.GetDB(), cfg.Database.Driver)
 	if err != nil {
-		logger.Error("Failed to create migration manager: %v", err)
+		logger.Errorf("Failed to create migration manager: %v", err)
 		os.Exit(1)
 	}
 
 	if *up {
 		if err := manager.LoadMigrationsUp("up"); err != nil {
-			logger.Error("Failed to load migrations: %v", err)
+			logger.Errorf("Failed to load migrations: %v", err)
 			os.Exit(1)
 		}
 		if err := manager.RunMigrationsUp(); err != nil {
 			// Log the error and exit
-			logger.Error("Failed to run migrations up: %v", err)
+			logger.Errorf("Failed to run migrations up: %v", err)
 			os.Exit(1)
 		}
 	}
 
 	if *down {
 		if err := manager.LoadMigrationsDown("down"); err != nil {
-			logger.Error("Failed to load migrations: %v", err)
+			logger.Errorf("Failed to load migrations: %v", err)
 			os.Exit(1)
 		}
 		if err := manager.RunMigrationsDown(); err != nil {
 			// Log the error and exit
-			logger.Error("Failed to run migrations down: %v", err)
+			logger.Errorf("Failed to run migrations down: %v", err)
 			os.Exit(1)
 		}
 	}
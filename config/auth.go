@@ -0,0 +1,100 @@
+package config
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported JWT signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+type AuthConfig struct {
+	Algorithm      string
+	Secret         string
+	PublicKeyPath  string
+	PrivateKeyPath string
+	Issuer         string
+	Audience       string
+	Leeway         time.Duration
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	publicKey  *rsa.PublicKey
+	privateKey *rsa.PrivateKey
+}
+
+// LoadKeys reads and parses the RSA keys referenced by the configuration.
+// It is a no-op for HMAC based algorithms.
+func (c *AuthConfig) LoadKeys() error {
+	if c.Algorithm != RS256 {
+		return nil
+	}
+
+	pemBytes, err := os.ReadFile(c.PublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("error reading JWT public key: %v", err)
+	}
+	c.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+	if err != nil {
+		return fmt.Errorf("error parsing JWT public key: %v", err)
+	}
+
+	// The private key is only needed by instances that issue tokens
+	if c.PrivateKeyPath == "" {
+		return nil
+	}
+
+	pemBytes, err = os.ReadFile(c.PrivateKeyPath)
+	if err != nil {
+		return fmt.Errorf("error reading JWT private key: %v", err)
+	}
+	c.privateKey, err = jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+	if err != nil {
+		return fmt.Errorf("error parsing JWT private key: %v", err)
+	}
+
+	return nil
+}
+
+// VerificationKey returns the key used to verify token signatures
+func (c *AuthConfig) VerificationKey() (interface{}, error) {
+	switch c.Algorithm {
+	case HS256:
+		return []byte(c.Secret), nil
+	case RS256:
+		if c.publicKey == nil {
+			return nil, fmt.Errorf("JWT public key is not loaded")
+		}
+		return c.publicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", c.Algorithm)
+	}
+}
+
+// SigningKey returns the key used to sign issued tokens
+func (c *AuthConfig) SigningKey() (interface{}, error) {
+	switch c.Algorithm {
+	case HS256:
+		return []byte(c.Secret), nil
+	case RS256:
+		if c.privateKey == nil {
+			return nil, fmt.Errorf("JWT private key is not loaded")
+		}
+		return c.privateKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %s", c.Algorithm)
+	}
+}
+
+// SigningMethod returns the jwt signing method for the configured algorithm
+func (c *AuthConfig) SigningMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(c.Algorithm)
+}
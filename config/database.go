@@ -1,39 +1,136 @@
 package config
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
+
+	"goapi/logger"
 
 	_ "github.com/lib/pq"
 )
 
+// Database drivers selectable with DB_DRIVER
+const (
+	DriverPostgres = "postgres"
+	// DriverSQLite stores the data in a local file, no server needed
+	DriverSQLite = "sqlite"
+	// DriverMemory keeps all data in memory, for tests and demos
+	DriverMemory = "memory"
+)
+
 type DBConfig struct {
+	Driver   string
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
 	SSLMode  string
+	// SQLitePath is the database file of the SQLite driver
+	SQLitePath string
+	// QueryTimeout bounds every repository query; zero disables the limit
+	QueryTimeout time.Duration
+
+	// Pool limits, zero leaves the database/sql default
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// Session settings sent with every new connection
+	ConnectTimeout   time.Duration
+	StatementTimeout time.Duration
+	ApplicationName  string
+
+	// Connect retries with exponential backoff, starting at ConnectBackoff
+	// and doubling up to ConnectMaxBackoff
+	ConnectRetries    int
+	ConnectBackoff    time.Duration
+	ConnectMaxBackoff time.Duration
+
+	// ReplicaDSNs are the connection strings of read replicas, each complete
+	// with its own credentials. Reads that tolerate lag are spread over the
+	// replicas that pass the health check run every ReplicaCheckInterval.
+	ReplicaDSNs          []string
+	ReplicaCheckInterval time.Duration
+
+	// TxMaxRetries is how often a transaction is retried after a
+	// serialization failure or deadlock
+	TxMaxRetries int
+
+	// MemoryAdminEmail and MemoryAdminPassword create an admin when the
+	// in-memory driver starts, so a demo can be logged into
+	MemoryAdminEmail    string
+	MemoryAdminPassword string
 }
 
 // GetPostgresConfig returns default PostgreSQL configuration
 func GetPostgresConfig() *DBConfig {
 	return &DBConfig{
+		Driver:   DriverPostgres,
 		Host:     "localhost",
 		Port:     "5432",
 		User:     "postgres",
 		Password: "postgres",
 		DBName:   "goapi_db",
 		SSLMode:  "disable",
+
+		SQLitePath: "goapi.db",
+
+		QueryTimeout: 5 * time.Second,
+
+		MaxOpenConns:    25,
+		MaxIdleConns:    25,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+
+		ConnectTimeout:   5 * time.Second,
+		StatementTimeout: 30 * time.Second,
+		ApplicationName:  "goapi",
+
+		ConnectRetries:    5,
+		ConnectBackoff:    500 * time.Millisecond,
+		ConnectMaxBackoff: 10 * time.Second,
+
+		ReplicaCheckInterval: 5 * time.Second,
+
+		TxMaxRetries: 3,
 	}
 }
 
 // GetConnectionString formats the connection string based on the configuration
 func (c *DBConfig) GetConnectionString() string {
-	return fmt.Sprintf(
+	conn := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode,
+		c.Host, c.Port, c.User, quoteConnValue(c.Password), c.DBName, c.SSLMode,
 	)
+	if c.ConnectTimeout > 0 {
+		// connect_timeout only has second precision, round up so short timeouts are not disabled
+		seconds := (c.ConnectTimeout + time.Second - 1) / time.Second
+		conn += fmt.Sprintf(" connect_timeout=%d", seconds)
+	}
+	if c.StatementTimeout > 0 {
+		// Unknown keys are sent to the server as run-time parameters
+		conn += fmt.Sprintf(" statement_timeout=%d", c.StatementTimeout.Milliseconds())
+	}
+	if c.ApplicationName != "" {
+		conn += " application_name=" + quoteConnValue(c.ApplicationName)
+	}
+	return conn
+}
+
+// quoteConnValue quotes a connection string value when it is empty or
+// contains spaces, quotes or backslashes
+func quoteConnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
 }
 
 // Database interface defines methods that any database implementation must satisfy
@@ -41,12 +138,15 @@ type Database interface {
 	Connect() error
 	Close() error
 	GetDB() *sql.DB
+	GetReplicas() []*sql.DB
+	Stats() sql.DBStats
 }
 
 // PostgresDB implements the Database interface
 type PostgresDB struct {
-	config *DBConfig
-	db     *sql.DB
+	config   *DBConfig
+	db       *sql.DB
+	replicas []*sql.DB
 }
 
 // NewPostgresDB creates a new PostgreSQL database instance
@@ -56,30 +156,102 @@ func NewPostgresDB(config *DBConfig) Database {
 	}
 }
 
-// Connect establishes a connection to the PostgreSQL database
+// Connect establishes a connection to the PostgreSQL database. It waits for
+// the database to come up, retrying with exponential backoff.
 func (p *PostgresDB) Connect() error {
 	db, err := sql.Open("postgres", p.config.GetConnectionString())
 	if err != nil {
 		return fmt.Errorf("error connecting to the database: %v", err)
 	}
 
-	if err = db.Ping(); err != nil {
-		return fmt.Errorf("error pinging the database: %v", err)
+	p.configurePool(db)
+
+	backoff := p.config.ConnectBackoff
+	for attempt := 0; ; attempt++ {
+		if err = p.ping(db); err == nil {
+			break
+		}
+		if attempt >= p.config.ConnectRetries {
+			db.Close()
+			return fmt.Errorf("error pinging the database after %d attempts: %v", attempt+1, err)
+		}
+
+		logger.Warnf("Database not reachable (attempt %d of %d), retrying in %s: %v",
+			attempt+1, p.config.ConnectRetries+1, backoff, err)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if p.config.ConnectMaxBackoff > 0 && backoff > p.config.ConnectMaxBackoff {
+			backoff = p.config.ConnectMaxBackoff
+		}
+	}
+
+	// Replicas are optional, so they are not waited for. Reads only move to
+	// them once they pass a health check.
+	for i, dsn := range p.config.ReplicaDSNs {
+		replica, err := sql.Open("postgres", dsn)
+		if err != nil {
+			db.Close()
+			p.closeReplicas()
+			return fmt.Errorf("error opening replica %d: %v", i, err)
+		}
+		p.configurePool(replica)
+		p.replicas = append(p.replicas, replica)
 	}
 
 	p.db = db
 	return nil
 }
 
-// Close closes the database connection
+// configurePool applies the pool limits to db
+func (p *PostgresDB) configurePool(db *sql.DB) {
+	db.SetMaxOpenConns(p.config.MaxOpenConns)
+	db.SetMaxIdleConns(p.config.MaxIdleConns)
+	db.SetConnMaxLifetime(p.config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.config.ConnMaxIdleTime)
+}
+
+// ping checks a connection, bounded by the connect timeout
+func (p *PostgresDB) ping(db *sql.DB) error {
+	ctx := context.Background()
+	if p.config.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.ConnectTimeout)
+		defer cancel()
+	}
+	return db.PingContext(ctx)
+}
+
+// Close closes the database connection and those of the replicas
 func (p *PostgresDB) Close() error {
+	p.closeReplicas()
 	if p.db != nil {
 		return p.db.Close()
 	}
 	return nil
 }
 
+func (p *PostgresDB) closeReplicas() {
+	for _, replica := range p.replicas {
+		replica.Close()
+	}
+	p.replicas = nil
+}
+
 // GetDB returns the database instance
 func (p *PostgresDB) GetDB() *sql.DB {
 	return p.db
 }
+
+// GetReplicas returns the read replicas, if any are configured
+func (p *PostgresDB) GetReplicas() []*sql.DB {
+	return p.replicas
+}
+
+// Stats returns the connection pool statistics
+func (p *PostgresDB) Stats() sql.DBStats {
+	if p.db == nil {
+		return sql.DBStats{}
+	}
+	return p.db.Stats()
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"goapi/logger"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -22,33 +24,50 @@ type Config struct {
 	Server      ServerConfig
 	Database    DBConfig
 	Logger      LoggerConfig
+	Auth        AuthConfig
+	Retention   RetentionConfig
 }
 
 type ServerConfig struct {
 	Port string
 	Host string
+	// ShutdownTimeout bounds the time in-flight requests get to finish once
+	// the server is asked to stop
+	ShutdownTimeout time.Duration
 }
 
+// LoggerConfig holds the logger settings. Level is the logger level, which
+// can change at runtime, while the file and syslog levels are fixed minimums
+// of their outputs on top of it. Format and UseColors are those of stdout.
 type LoggerConfig struct {
 	Level     logger.LogLevel
+	Format    logger.Format
 	UseColors bool
+	Outputs   []string
+	File      LogFileConfig
+	Syslog    LogSyslogConfig
+	// RedactKeys are the patterns of the keys masked in the logs
+	RedactKeys []string
 }
 
 // LoadConfig loads configuration based on the environment
 func LoadConfig() (*Config, error) {
 	env := getEnvironment()
 
-	// Initialize logger first
+	// Load environment file from main folder first, it may set the LOG_*
+	// variables the logger is configured from. Variables set in the process
+	// environment win over the file.
+	_, logLevelFromProcess = os.LookupEnv("LOG_LEVEL")
+	envFile := envFilePath(env)
+	envFileErr := godotenv.Load(envFile)
+
 	logConfig, err := initializeLogger(env)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize logger: %v", err)
 	}
 
-	// Load environment file from main folder
-	envFile := fmt.Sprintf("./env.%s", env)
-
-	if err := godotenv.Load(envFile); err != nil {
-		logger.Warn("No %s file found in main folder, using default environment variables", envFile)
+	if envFileErr != nil {
+		logger.Warn("No environment file found in main folder, using default environment variables", "file", envFile)
 	}
 
 	config := &Config{
@@ -57,14 +76,61 @@ func LoadConfig() (*Config, error) {
 		Server: ServerConfig{
 			Port: getEnvOrDefault("SERVER_PORT", "8080"),
 			Host: getEnvOrDefault("SERVER_HOST", "localhost"),
+
+			ShutdownTimeout: getEnvDurationOrDefault("SERVER_SHUTDOWN_TIMEOUT", 15*time.Second),
 		},
 		Database: DBConfig{
+			Driver:   strings.ToLower(getEnvOrDefault("DB_DRIVER", DriverPostgres)),
 			Host:     resolveSecret(getEnvOrDefault("DB_HOST", "localhost")),
 			Port:     resolveSecret(getEnvOrDefault("DB_PORT", "5432")),
 			User:     resolveSecret(getEnvOrDefault("DB_USER", "postgres")),
 			Password: resolveSecret(getEnvOrDefault("DB_PASSWORD", "postgres")),
 			DBName:   resolveSecret(getEnvOrDefault("DB_NAME", "goapi_db")),
 			SSLMode:  resolveSecret(getEnvOrDefault("DB_SSL_MODE", "disable")),
+
+			SQLitePath: getEnvOrDefault("DB_SQLITE_PATH", "goapi.db"),
+
+			QueryTimeout: getEnvDurationOrDefault("DB_QUERY_TIMEOUT", 5*time.Second),
+
+			MaxOpenConns:    getEnvIntOrDefault("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getEnvIntOrDefault("DB_MAX_IDLE_CONNS", 25),
+			ConnMaxLifetime: getEnvDurationOrDefault("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			ConnMaxIdleTime: getEnvDurationOrDefault("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+
+			ConnectTimeout:   getEnvDurationOrDefault("DB_CONNECT_TIMEOUT", 5*time.Second),
+			StatementTimeout: getEnvDurationOrDefault("DB_STATEMENT_TIMEOUT", 30*time.Second),
+			ApplicationName:  getEnvOrDefault("DB_APPLICATION_NAME", "goapi"),
+
+			ConnectRetries:    getEnvIntOrDefault("DB_CONNECT_RETRIES", 5),
+			ConnectBackoff:    getEnvDurationOrDefault("DB_CONNECT_BACKOFF", 500*time.Millisecond),
+			ConnectMaxBackoff: getEnvDurationOrDefault("DB_CONNECT_MAX_BACKOFF", 10*time.Second),
+
+			ReplicaDSNs:          getEnvListOrDefault("DB_REPLICA_DSNS", nil),
+			ReplicaCheckInterval: getEnvDurationOrDefault("DB_REPLICA_CHECK_INTERVAL", 5*time.Second),
+
+			TxMaxRetries: getEnvIntOrDefault("DB_TX_MAX_RETRIES", 3),
+
+			MemoryAdminEmail:    os.Getenv("DB_MEMORY_ADMIN_EMAIL"),
+			MemoryAdminPassword: resolveSecret(os.Getenv("DB_MEMORY_ADMIN_PASSWORD")),
+		},
+		Auth: AuthConfig{
+			Algorithm:      strings.ToUpper(getEnvOrDefault("JWT_ALGORITHM", HS256)),
+			Secret:         resolveSecret(os.Getenv("JWT_SECRET")),
+			PublicKeyPath:  os.Getenv("JWT_PUBLIC_KEY_PATH"),
+			PrivateKeyPath: os.Getenv("JWT_PRIVATE_KEY_PATH"),
+			Issuer:         getEnvOrDefault("JWT_ISSUER", "goapi"),
+			Audience:       getEnvOrDefault("JWT_AUDIENCE", "goapi"),
+			Leeway:         getEnvDurationOrDefault("JWT_LEEWAY", 30*time.Second),
+
+			AccessTokenTTL:  getEnvDurationOrDefault("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL: getEnvDurationOrDefault("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		},
+		Retention: RetentionConfig{
+			Enabled:   getEnvBoolOrDefault("RETENTION_ENABLED", false),
+			Mode:      strings.ToLower(getEnvOrDefault("RETENTION_MODE", RetentionDelete)),
+			Period:    getEnvDurationOrDefault("RETENTION_PERIOD", 30*24*time.Hour),
+			Interval:  getEnvDurationOrDefault("RETENTION_INTERVAL", time.Hour),
+			BatchSize: getEnvIntOrDefault("RETENTION_BATCH_SIZE", 500),
 		},
 	}
 
@@ -72,10 +138,14 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if err := config.Auth.LoadKeys(); err != nil {
+		return nil, err
+	}
+
 	// Log configuration loaded
-	logger.Info("Configuration loaded for environment: %s", config.Environment)
-	logger.Debug("Server configuration - Host: %s, Port: %s", config.Server.Host, config.Server.Port)
-	logger.Debug("Database configuration - Host: %s, Port: %s, Database: %s",
+	logger.Infof("Configuration loaded for environment: %s", config.Environment)
+	logger.Debugf("Server configuration - Host: %s, Port: %s", config.Server.Host, config.Server.Port)
+	logger.Debugf("Database configuration - Host: %s, Port: %s, Database: %s",
 		config.Database.Host,
 		config.Database.Port,
 		config.Database.DBName,
@@ -84,17 +154,31 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// defaultLogLevels are the log levels per environment without LOG_LEVEL
+var defaultLogLevels = map[Environment]string{
+	Dev:  "DEBUG",
+	Stag: "INFO",
+	Prod: "WARN",
+}
+
+// envFilePath returns the path of the environment file of env
+func envFilePath(env Environment) string {
+	return fmt.Sprintf("./env.%s", env)
+}
+
 func initializeLogger(env Environment) (LoggerConfig, error) {
-	// Default log levels per environment
-	defaultLevel := map[Environment]string{
-		Dev:  "DEBUG",
-		Stag: "INFO",
-		Prod: "WARN",
+	// Get log level from environment or use default
+	level, err := logger.ParseLevel(getEnvOrDefault("LOG_LEVEL", defaultLogLevels[env]))
+	if err != nil {
+		return LoggerConfig{}, err
 	}
 
-	// Get log level from environment or use default
-	levelStr := getEnvOrDefault("LOG_LEVEL", defaultLevel[env])
-	level, err := logger.ParseLevel(levelStr)
+	// JSON by default in production, where the log pipeline parses it
+	defaultFormat := logger.FormatText
+	if env == Prod {
+		defaultFormat = logger.FormatJSON
+	}
+	format, err := logger.ParseFormat(getEnvOrDefault("LOG_FORMAT", string(defaultFormat)))
 	if err != nil {
 		return LoggerConfig{}, err
 	}
@@ -105,13 +189,53 @@ func initializeLogger(env Environment) (LoggerConfig, error) {
 		useColors = strings.ToLower(colorStr) == "true"
 	}
 
-	// Initialize the logger
-	logger.InitLogger(level, useColors)
-
-	return LoggerConfig{
+	cfg := LoggerConfig{
 		Level:     level,
+		Format:    format,
 		UseColors: useColors,
-	}, nil
+		Outputs:   getEnvListOrDefault("LOG_OUTPUTS", []string{LogOutputStdout}),
+		// Personal data and secrets never reach the outputs
+		RedactKeys: getEnvListOrDefault("LOG_REDACT_KEYS", logger.DefaultRedactKeys),
+	}
+
+	// The file is read by tools rather than people, so JSON by default
+	cfg.File = LogFileConfig{
+		Path: getEnvOrDefault("LOG_FILE_PATH", "logs/goapi.log"),
+		Rotate: logger.RotateOptions{
+			MaxSize:    int64(getEnvIntOrDefault("LOG_FILE_MAX_SIZE_MB", 100)) << 20,
+			Interval:   getEnvDurationOrDefault("LOG_FILE_ROTATE_INTERVAL", 24*time.Hour),
+			MaxBackups: getEnvIntOrDefault("LOG_FILE_MAX_BACKUPS", 7),
+			MaxAge:     getEnvDurationOrDefault("LOG_FILE_MAX_AGE", 30*24*time.Hour),
+			Compress:   getEnvBoolOrDefault("LOG_FILE_COMPRESS", true),
+		},
+	}
+	if cfg.File.Level, err = logger.ParseLevel(getEnvOrDefault("LOG_FILE_LEVEL", "DEBUG")); err != nil {
+		return LoggerConfig{}, err
+	}
+	if cfg.File.Format, err = logger.ParseFormat(getEnvOrDefault("LOG_FILE_FORMAT", string(logger.FormatJSON))); err != nil {
+		return LoggerConfig{}, err
+	}
+
+	cfg.Syslog = LogSyslogConfig{Tag: getEnvOrDefault("LOG_SYSLOG_TAG", "goapi")}
+	if cfg.Syslog.Level, err = logger.ParseLevel(getEnvOrDefault("LOG_SYSLOG_LEVEL", "DEBUG")); err != nil {
+		return LoggerConfig{}, err
+	}
+	if cfg.Syslog.Format, err = logger.ParseFormat(getEnvOrDefault("LOG_SYSLOG_FORMAT", string(logger.FormatText))); err != nil {
+		return LoggerConfig{}, err
+	}
+
+	if cfg.File.Rotate.MaxSize < 0 || cfg.File.Rotate.Interval < 0 || cfg.File.Rotate.MaxBackups < 0 || cfg.File.Rotate.MaxAge < 0 {
+		return LoggerConfig{}, fmt.Errorf("log file rotation limits must be >= 0")
+	}
+
+	// Initialize the logger
+	sinks, err := openSinks(cfg)
+	if err != nil {
+		return LoggerConfig{}, err
+	}
+	logger.InitLogger(logger.Options{Level: level, Sinks: sinks, RedactKeys: cfg.RedactKeys})
+
+	return cfg, nil
 }
 
 func getEnvironment() Environment {
@@ -135,6 +259,65 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Warnf("Invalid duration %q for %s, using default %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warnf("Invalid integer %q for %s, using default %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+func getEnvBoolOrDefault(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warnf("Invalid boolean %q for %s, using default %t", value, key, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+// getEnvListOrDefault splits a comma separated value, resolving secrets in
+// each item. Empty items are dropped.
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, resolveSecret(item))
+		}
+	}
+	return items
+}
+
 // resolveSecret handles secret resolution for values starting with "!"
 func resolveSecret(value string) string {
 	if !strings.HasPrefix(value, "!") {
@@ -148,7 +331,7 @@ func resolveSecret(value string) string {
 	// For example, reading from Vault, AWS Secrets Manager, or a local secrets file
 	secretContent, err := os.ReadFile(secretPath)
 	if err != nil {
-		logger.Error("Error reading secret from %s: %v", secretPath, err)
+		logger.Errorf("Error reading secret from %s: %v", secretPath, err)
 		return ""
 	}
 
@@ -163,27 +346,124 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("server host is required")
 	}
 
-	if config.Database.Host == "" {
+	switch config.Database.Driver {
+	case DriverPostgres:
+		if err := validatePostgresConfig(&config.Database); err != nil {
+			return err
+		}
+	case DriverSQLite:
+		if config.Database.SQLitePath == "" {
+			return fmt.Errorf("database sqlite path is required")
+		}
+		if config.Database.QueryTimeout < 0 {
+			return fmt.Errorf("database query timeout must not be negative")
+		}
+		if config.Database.TxMaxRetries < 0 {
+			return fmt.Errorf("database transaction retries must not be negative")
+		}
+	case DriverMemory:
+		if (config.Database.MemoryAdminEmail == "") != (config.Database.MemoryAdminPassword == "") {
+			return fmt.Errorf("memory admin email and password must be set together")
+		}
+	default:
+		return fmt.Errorf("database driver must be %s, %s or %s", DriverPostgres, DriverSQLite, DriverMemory)
+	}
+
+	switch config.Auth.Algorithm {
+	case HS256:
+		if config.Auth.Secret == "" {
+			return fmt.Errorf("jwt secret is required for %s", HS256)
+		}
+	case RS256:
+		if config.Auth.PublicKeyPath == "" {
+			return fmt.Errorf("jwt public key path is required for %s", RS256)
+		}
+	default:
+		return fmt.Errorf("unsupported jwt algorithm: %s", config.Auth.Algorithm)
+	}
+	if config.Auth.Issuer == "" {
+		return fmt.Errorf("jwt issuer is required")
+	}
+	if config.Auth.Audience == "" {
+		return fmt.Errorf("jwt audience is required")
+	}
+	if config.Auth.AccessTokenTTL <= 0 {
+		return fmt.Errorf("access token ttl must be positive")
+	}
+	if config.Auth.RefreshTokenTTL <= 0 {
+		return fmt.Errorf("refresh token ttl must be positive")
+	}
+
+	if config.Retention.Enabled {
+		if config.Retention.Mode != RetentionDelete && config.Retention.Mode != RetentionAnonymize {
+			return fmt.Errorf("retention mode must be %s or %s", RetentionDelete, RetentionAnonymize)
+		}
+		if config.Retention.Period <= 0 {
+			return fmt.Errorf("retention period must be positive")
+		}
+		if config.Retention.Interval <= 0 {
+			return fmt.Errorf("retention interval must be positive")
+		}
+		if config.Retention.BatchSize <= 0 {
+			return fmt.Errorf("retention batch size must be positive")
+		}
+	}
+
+	// No need to validate UseColors as it's a bool with default value
+
+	// Add more validation as needed
+	return nil
+}
+
+// validatePostgresConfig checks the settings used by the postgres driver
+func validatePostgresConfig(db *DBConfig) error {
+	if db.Host == "" {
 		return fmt.Errorf("database host is required")
 	}
-	if config.Database.Port == "" {
+	if db.Port == "" {
 		return fmt.Errorf("database port is required")
 	}
-	if config.Database.User == "" {
+	if db.User == "" {
 		return fmt.Errorf("database user is required")
 	}
-	if config.Database.Password == "" {
+	if db.Password == "" {
 		return fmt.Errorf("database password is required")
 	}
-	if config.Database.DBName == "" {
+	if db.DBName == "" {
 		return fmt.Errorf("database name is required")
 	}
-	if config.Database.SSLMode == "" {
+	if db.SSLMode == "" {
 		return fmt.Errorf("database ssl mode is required")
 	}
-
-	// No need to validate UseColors as it's a bool with default value
-
-	// Add more validation as needed
+	if db.QueryTimeout < 0 {
+		return fmt.Errorf("database query timeout must not be negative")
+	}
+	if db.MaxOpenConns < 0 {
+		return fmt.Errorf("database max open connections must not be negative")
+	}
+	if db.MaxIdleConns < 0 {
+		return fmt.Errorf("database max idle connections must not be negative")
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		return fmt.Errorf("database max idle connections must not exceed max open connections")
+	}
+	if db.ConnMaxLifetime < 0 || db.ConnMaxIdleTime < 0 {
+		return fmt.Errorf("database connection lifetimes must not be negative")
+	}
+	if db.ConnectTimeout < 0 || db.StatementTimeout < 0 {
+		return fmt.Errorf("database connect and statement timeouts must not be negative")
+	}
+	if db.ConnectRetries < 0 {
+		return fmt.Errorf("database connect retries must not be negative")
+	}
+	if db.ConnectRetries > 0 && db.ConnectBackoff <= 0 {
+		return fmt.Errorf("database connect backoff must be positive")
+	}
+	if len(db.ReplicaDSNs) > 0 && db.ReplicaCheckInterval <= 0 {
+		return fmt.Errorf("database replica check interval must be positive")
+	}
+	if db.TxMaxRetries < 0 {
+		return fmt.Errorf("database transaction retries must not be negative")
+	}
 	return nil
 }
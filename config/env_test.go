@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"goapi/logger"
+)
+
+// useEnvFile runs the test in a temporary directory holding env.dev, and
+// unsets the given variables for its duration
+func useEnvFile(t *testing.T, content string, unset ...string) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "env.dev"), []byte(content), 0o600); err != nil {
+		t.Fatalf("writing env.dev: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() error = %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir() error = %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	t.Setenv("GO_ENV", "dev")
+	for _, key := range unset {
+		// Setenv restores the variable once the test is done
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestLoadConfig_EnvFileConfiguresLogger(t *testing.T) {
+	useEnvFile(t, "LOG_LEVEL=ERROR\nLOG_FORMAT=json\nLOG_REDACT_KEYS=ssn,email\nJWT_SECRET=0123456789abcdef0123456789abcdef\n",
+		"LOG_LEVEL", "LOG_FORMAT", "LOG_REDACT_KEYS", "JWT_SECRET")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Logger.Level != logger.ERROR || cfg.Logger.Format != logger.FormatJSON {
+		t.Errorf("LoadConfig() logger = %+v, want the level and format of the env file", cfg.Logger)
+	}
+	if !slices.Equal(cfg.Logger.RedactKeys, []string{"ssn", "email"}) {
+		t.Errorf("LoadConfig() redact keys = %q, want those of the env file", cfg.Logger.RedactKeys)
+	}
+}
+
+func TestLoadConfig_ProcessEnvironmentWinsOverEnvFile(t *testing.T) {
+	useEnvFile(t, "LOG_LEVEL=ERROR\nJWT_SECRET=0123456789abcdef0123456789abcdef\n", "JWT_SECRET")
+	t.Setenv("LOG_LEVEL", "WARN")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Logger.Level != logger.WARN {
+		t.Errorf("LoadConfig() level = %v, want the WARN set in the process environment", cfg.Logger.Level)
+	}
+}
+
+func TestLoadConfig_EnvFileOpensLogFile(t *testing.T) {
+	useEnvFile(t, "LOG_LEVEL=INFO\nLOG_OUTPUTS=file\nLOG_FILE_PATH=logs/app.log\nJWT_SECRET=0123456789abcdef0123456789abcdef\n",
+		"LOG_LEVEL", "LOG_OUTPUTS", "LOG_FILE_PATH", "JWT_SECRET")
+	t.Cleanup(func() {
+		logger.Close()
+		logger.InitLogger(logger.Options{})
+	})
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(cfg.Logger.Outputs) != 1 || cfg.Logger.Outputs[0] != LogOutputFile {
+		t.Fatalf("LoadConfig() outputs = %q, want the file of the env file", cfg.Logger.Outputs)
+	}
+
+	data, err := os.ReadFile(filepath.Join("logs", "app.log"))
+	if err != nil {
+		t.Fatalf("reading the log file: %v", err)
+	}
+	if !strings.Contains(string(data), `"msg":"Configuration loaded`) {
+		t.Errorf("log file = %q, want the JSON records of LoadConfig", data)
+	}
+}
+
+func TestReloadLogLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		processed string
+		edited    string
+		want      logger.LogLevel
+		wantErr   bool
+	}{
+		{name: "edited env file", edited: "LOG_LEVEL=DEBUG\n", want: logger.DEBUG},
+		{name: "removed from the env file", edited: "", want: logger.DEBUG},
+		{name: "process environment wins", processed: "WARN", edited: "LOG_LEVEL=DEBUG\n", want: logger.WARN},
+		{name: "invalid level", edited: "LOG_LEVEL=verbose\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useEnvFile(t, "LOG_LEVEL=ERROR\nJWT_SECRET=0123456789abcdef0123456789abcdef\n", "LOG_LEVEL", "JWT_SECRET")
+			if tt.processed != "" {
+				t.Setenv("LOG_LEVEL", tt.processed)
+			}
+			t.Cleanup(func() { logger.InitLogger(logger.Options{}) })
+
+			if _, err := LoadConfig(); err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if err := os.WriteFile("env.dev", []byte(tt.edited), 0o600); err != nil {
+				t.Fatalf("editing env.dev: %v", err)
+			}
+
+			got, err := ReloadLogLevel(Dev)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ReloadLogLevel() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ReloadLogLevel() = %v, %v, want %v", got, err, tt.want)
+			}
+			if level := logger.Default().Levels()[""].Level; level != tt.want {
+				t.Errorf("logger level = %v, want %v", level, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"goapi/logger"
+
+	"github.com/joho/godotenv"
+)
+
+// Log outputs, each a sink with its own minimum level
+const (
+	LogOutputStdout = "stdout"
+	LogOutputFile   = "file"
+	LogOutputSyslog = "syslog"
+)
+
+// LogFileConfig holds the settings of the rotated log file
+type LogFileConfig struct {
+	Path   string
+	Level  logger.LogLevel
+	Format logger.Format
+	Rotate logger.RotateOptions
+}
+
+// LogSyslogConfig holds the settings of the local syslog output
+type LogSyslogConfig struct {
+	Level  logger.LogLevel
+	Format logger.Format
+	Tag    string
+}
+
+// openSinks opens the sinks of the configured outputs. On failure the
+// sinks already opened are closed.
+func openSinks(cfg LoggerConfig) ([]logger.Sink, error) {
+	var sinks []logger.Sink
+	for _, output := range cfg.Outputs {
+		sink, err := openSink(cfg, output)
+		if err != nil {
+			return nil, errors.Join(err, logger.CloseSinks(sinks))
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+func openSink(cfg LoggerConfig, output string) (logger.Sink, error) {
+	switch output {
+	case LogOutputStdout:
+		// Stdout has no minimum of its own, it follows the logger level
+		return logger.StdoutSink(logger.DEBUG, cfg.Format, cfg.UseColors), nil
+	case LogOutputFile:
+		file, err := logger.OpenRotatingFile(cfg.File.Path, cfg.File.Rotate)
+		if err != nil {
+			return logger.Sink{}, fmt.Errorf("failed to open log file: %v", err)
+		}
+		return logger.Sink{Out: file, Level: cfg.File.Level, Format: cfg.File.Format}, nil
+	case LogOutputSyslog:
+		w, err := logger.DialSyslog(cfg.Syslog.Tag)
+		if err != nil {
+			return logger.Sink{}, fmt.Errorf("failed to connect to syslog: %v", err)
+		}
+		return logger.Sink{Out: w, Level: cfg.Syslog.Level, Format: cfg.Syslog.Format}, nil
+	default:
+		return logger.Sink{}, fmt.Errorf("log output must be %s, %s or %s, got %q", LogOutputStdout, LogOutputFile, LogOutputSyslog, output)
+	}
+}
+
+// logLevelFromProcess tells whether LOG_LEVEL was set in the process
+// environment when the configuration was loaded
+var logLevelFromProcess bool
+
+// ReloadLogLevel reads LOG_LEVEL again and makes it the lasting level of the
+// logger, e.g. on SIGHUP. It follows the precedence of LoadConfig: a level
+// set in the process environment wins, otherwise the environment file is
+// read again, so LOG_LEVEL can be edited there while the server runs.
+func ReloadLogLevel(env Environment) (logger.LogLevel, error) {
+	value := getEnvOrDefault("LOG_LEVEL", defaultLogLevels[env])
+	if !logLevelFromProcess {
+		value = defaultLogLevels[env]
+		if values, err := godotenv.Read(envFilePath(env)); err == nil && values["LOG_LEVEL"] != "" {
+			value = values["LOG_LEVEL"]
+		}
+	}
+
+	level, err := logger.ParseLevel(value)
+	if err != nil {
+		return level, err
+	}
+	logger.Default().SetLevel("", level, 0)
+	return level, nil
+}
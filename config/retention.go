@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// Retention modes for soft deleted users
+const (
+	RetentionDelete    = "delete"
+	RetentionAnonymize = "anonymize"
+)
+
+// RetentionConfig holds the settings of the job that cleans up soft deleted
+// users once they have been deleted for longer than Period
+type RetentionConfig struct {
+	Enabled   bool
+	Mode      string
+	Period    time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
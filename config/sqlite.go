@@ -0,0 +1,86 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteBusyTimeout is how long a connection waits for another one to
+// release the database before giving up
+const sqliteBusyTimeout = 5000
+
+// GetSQLiteConnectionString formats the connection string of the SQLite
+// driver. Foreign keys are off in SQLite unless enabled per connection, and
+// transactions take the write lock up front so that two of them cannot
+// deadlock upgrading their read locks.
+func (c *DBConfig) GetSQLiteConnectionString() string {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", sqliteBusyTimeout))
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+	return "file:" + c.SQLitePath + "?" + params.Encode()
+}
+
+// SQLiteDB implements the Database interface for a SQLite file
+type SQLiteDB struct {
+	config *DBConfig
+	db     *sql.DB
+}
+
+// NewSQLiteDB creates a new SQLite database instance
+func NewSQLiteDB(config *DBConfig) Database {
+	return &SQLiteDB{
+		config: config,
+	}
+}
+
+// Connect opens the database file, creating it if it does not exist
+func (s *SQLiteDB) Connect() error {
+	db, err := sql.Open("sqlite", s.config.GetSQLiteConnectionString())
+	if err != nil {
+		return fmt.Errorf("error opening the database: %v", err)
+	}
+
+	db.SetMaxOpenConns(s.config.MaxOpenConns)
+	db.SetMaxIdleConns(s.config.MaxIdleConns)
+	db.SetConnMaxLifetime(s.config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(s.config.ConnMaxIdleTime)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return fmt.Errorf("error opening the database %s: %v", s.config.SQLitePath, err)
+	}
+
+	s.db = db
+	return nil
+}
+
+// Close closes the database
+func (s *SQLiteDB) Close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// GetDB returns the database instance
+func (s *SQLiteDB) GetDB() *sql.DB {
+	return s.db
+}
+
+// GetReplicas returns nil, SQLite has no replicas
+func (s *SQLiteDB) GetReplicas() []*sql.DB {
+	return nil
+}
+
+// Stats returns the connection pool statistics
+func (s *SQLiteDB) Stats() sql.DBStats {
+	if s.db == nil {
+		return sql.DBStats{}
+	}
+	return s.db.Stats()
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"goapi/logger"
+	"goapi/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsProvider is satisfied by *sql.DB and the database handles wrapping it
+type StatsProvider interface {
+	Stats() sql.DBStats
+}
+
+// LevelController is satisfied by *logger.Logger
+type LevelController interface {
+	SetLevel(component string, level logger.LogLevel, revertAfter time.Duration)
+	ResetLevel(component string)
+	Levels() map[string]logger.LevelStatus
+}
+
+// AdminHandler handles HTTP requests for operational endpoints
+type AdminHandler struct {
+	db     StatsProvider
+	levels LevelController
+}
+
+// NewAdminHandler creates a new admin handler. db may be nil when the
+// server runs without a database, the pool statistics are then unavailable.
+func NewAdminHandler(db StatsProvider, levels LevelController) *AdminHandler {
+	return &AdminHandler{
+		db:     db,
+		levels: levels,
+	}
+}
+
+// GetDBStats godoc
+// @Summary Get database pool statistics
+// @Description Report the state of the database connection pool. Requires the system:monitor permission.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.DBStats
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Security BearerAuth
+// @Router /admin/db/stats [get]
+func (h *AdminHandler) GetDBStats(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewDBStats(h.db.Stats()))
+}
+
+// GetLogLevel godoc
+// @Summary Get the log level
+// @Description Report the log level of the server, and of the components set apart from it. Requires the system:monitor permission.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.LogLevelOutput
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Security BearerAuth
+// @Router /admin/log-level [get]
+func (h *AdminHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewLogLevelOutput(h.levels.Levels()))
+}
+
+// SetLogLevel godoc
+// @Summary Change the log level
+// @Description Change the log level of the server, or of one component such as repository. With revert_after, the previous level is restored once that many seconds have passed. Requires the system:configure permission.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param level body models.LogLevelInput true "Log level"
+// @Success 200 {object} models.LogLevelOutput
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Security BearerAuth
+// @Router /admin/log-level [put]
+func (h *AdminHandler) SetLogLevel(c *gin.Context) {
+	var input models.LogLevelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid request body", err))
+		return
+	}
+
+	level, err := logger.ParseLevel(input.Level)
+	if err != nil {
+		respondError(c, models.ErrInvalidLogLevel)
+		return
+	}
+
+	revertAfter := time.Duration(input.RevertAfter) * time.Second
+	h.levels.SetLevel(input.Component, level, revertAfter)
+	// Logged as a warning so the change shows at the usual production level
+	logger.FromContext(c.Request.Context()).Warn("Log level changed", "log_component", input.Component, "level", level.String(), "revert_after", revertAfter)
+
+	c.JSON(http.StatusOK, models.NewLogLevelOutput(h.levels.Levels()))
+}
+
+// ResetLogLevel godoc
+// @Summary Reset the log level
+// @Description Cancel a timed change of the log level, or make a component follow the server log level again. Requires the system:configure permission.
+// @Tags admin
+// @Produce json
+// @Param component query string false "Component to reset, the server log level when omitted"
+// @Success 200 {object} models.LogLevelOutput
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Security BearerAuth
+// @Router /admin/log-level [delete]
+func (h *AdminHandler) ResetLogLevel(c *gin.Context) {
+	component := c.Query("component")
+	h.levels.ResetLevel(component)
+	logger.FromContext(c.Request.Context()).Warn("Log level reset", "log_component", component)
+
+	c.JSON(http.StatusOK, models.NewLogLevelOutput(h.levels.Levels()))
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+
+	"goapi/models"
+	"goapi/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler handles HTTP requests for authentication
+type AuthHandler struct {
+	authService services.AuthService
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(authService services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+	}
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Exchange an email and password for an access token and a refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginInput true "Login credentials"
+// @Success 200 {object} models.TokenOutput
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var input models.LoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid request body", err))
+		return
+	}
+
+	tokens, err := h.authService.Login(c.Request.Context(), &input)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new token pair. The presented refresh token is revoked.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body models.RefreshTokenInput true "Refresh token"
+// @Success 200 {object} models.TokenOutput
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var input models.RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid request body", err))
+		return
+	}
+
+	tokens, err := h.authService.Refresh(c.Request.Context(), input.RefreshToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke a refresh token and every token rotated from the same login
+// @Tags auth
+// @Accept json
+// @Param token body models.RefreshTokenInput true "Refresh token"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var input models.RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid request body", err))
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), input.RefreshToken); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"goapi/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError writes err as an RFC 7807 problem response
+func respondError(c *gin.Context, err error) {
+	problem := models.NewProblem(c.Request.Context(), err, c.Request.URL.Path)
+	c.Header("Content-Type", models.ProblemContentType)
+	c.JSON(problem.Status, problem)
+}
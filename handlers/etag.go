@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"goapi/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userETag returns the strong entity tag of a user, derived from its version
+func userETag(user *models.UserOutput) string {
+	return fmt.Sprintf(`"%d"`, user.Version)
+}
+
+// setUserETag sets the ETag header for the user
+func setUserETag(c *gin.Context, user *models.UserOutput) {
+	c.Header("ETag", userETag(user))
+}
+
+// parseEntityTags splits an If-Match or If-None-Match header into its
+// entity tags. A "*" is reported as any.
+func parseEntityTags(header string) (tags []string, wildcard bool) {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, false
+}
+
+// parseIfMatch parses the If-Match header into a version precondition, or
+// nil when the header is absent. If-Match uses the strong comparison, so
+// weak and malformed tags never match.
+func parseIfMatch(c *gin.Context) *models.VersionMatch {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	tags, wildcard := parseEntityTags(header)
+	match := &models.VersionMatch{Any: wildcard}
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		if version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil {
+			match.Versions = append(match.Versions, version)
+		}
+	}
+	return match
+}
+
+// notModified reports whether the If-None-Match header matches the user.
+// If-None-Match uses the weak comparison, so a W/ prefix is ignored.
+func notModified(c *gin.Context, user *models.UserOutput) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	tags, wildcard := parseEntityTags(header)
+	if wildcard {
+		return true
+	}
+	etag := userETag(user)
+	for _, tag := range tags {
+		if strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"goapi/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextWithHeader returns a gin context for a request with one header set
+func contextWithHeader(name, value string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	if value != "" {
+		c.Request.Header.Set(name, value)
+	}
+	return c
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   *models.VersionMatch
+	}{
+		{name: "absent", header: ""},
+		{name: "single tag", header: `"3"`, want: &models.VersionMatch{Versions: []int64{3}}},
+		{name: "several tags", header: `"3", "5"`, want: &models.VersionMatch{Versions: []int64{3, 5}}},
+		{name: "wildcard", header: `*`, want: &models.VersionMatch{Any: true}},
+		{name: "weak tags never match", header: `W/"3"`, want: &models.VersionMatch{}},
+		{name: "unquoted and non-numeric tags are skipped", header: `3, "abc", "4"`, want: &models.VersionMatch{Versions: []int64{4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseIfMatch(contextWithHeader("If-Match", tt.header))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIfMatch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	user := &models.UserOutput{Version: 3}
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{name: "absent", header: "", want: false},
+		{name: "current tag", header: `"3"`, want: true},
+		{name: "weak current tag", header: `W/"3"`, want: true},
+		{name: "old tag", header: `"2"`, want: false},
+		{name: "one of several tags", header: `"1", "3"`, want: true},
+		{name: "wildcard", header: `*`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notModified(contextWithHeader("If-None-Match", tt.header), user); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
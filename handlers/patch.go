@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Supported PATCH media types
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// patchOperation is a single JSON Patch (RFC 6902) operation
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// toDocument converts a resource to its generic JSON representation
+func toDocument(resource interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7396) to doc. Members set
+// to null are removed; objects are merged recursively.
+func applyMergePatch(doc map[string]interface{}, body []byte) error {
+	var patch map[string]interface{}
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		return fmt.Errorf("merge patch must be a JSON object")
+	}
+
+	mergeObjects(doc, patch)
+	return nil
+}
+
+func mergeObjects(target, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+
+		patchObject, isObject := value.(map[string]interface{})
+		if !isObject {
+			target[key] = value
+			continue
+		}
+
+		targetObject, ok := target[key].(map[string]interface{})
+		if !ok {
+			targetObject = map[string]interface{}{}
+		}
+		mergeObjects(targetObject, patchObject)
+		target[key] = targetObject
+	}
+}
+
+// applyJSONPatch applies a JSON Patch (RFC 6902) to doc. Users are flat
+// documents, so only top level members can be addressed.
+func applyJSONPatch(doc map[string]interface{}, body []byte) error {
+	var operations []patchOperation
+	if err := json.Unmarshal(body, &operations); err != nil {
+		return fmt.Errorf("JSON patch must be an array of operations")
+	}
+
+	for i, op := range operations {
+		key, err := patchPointer(op.Path)
+		if err != nil {
+			return fmt.Errorf("operation %d: %v", i, err)
+		}
+
+		var value interface{}
+		if op.Value != nil {
+			if err := json.Unmarshal(*op.Value, &value); err != nil {
+				return fmt.Errorf("operation %d: invalid value", i)
+			}
+		}
+
+		_, exists := doc[key]
+		switch op.Op {
+		case "add":
+			if op.Value == nil {
+				return fmt.Errorf("operation %d: value is required", i)
+			}
+			doc[key] = value
+		case "replace":
+			if op.Value == nil {
+				return fmt.Errorf("operation %d: value is required", i)
+			}
+			if !exists {
+				return fmt.Errorf("operation %d: path %s does not exist", i, op.Path)
+			}
+			doc[key] = value
+		case "remove":
+			if !exists {
+				return fmt.Errorf("operation %d: path %s does not exist", i, op.Path)
+			}
+			delete(doc, key)
+		case "test":
+			if !exists || !reflect.DeepEqual(doc[key], value) {
+				return fmt.Errorf("operation %d: test failed for path %s", i, op.Path)
+			}
+		case "move", "copy":
+			from, err := patchPointer(op.From)
+			if err != nil {
+				return fmt.Errorf("operation %d: %v", i, err)
+			}
+			fromValue, ok := doc[from]
+			if !ok {
+				return fmt.Errorf("operation %d: path %s does not exist", i, op.From)
+			}
+			if op.Op == "move" {
+				delete(doc, from)
+			}
+			doc[key] = fromValue
+		default:
+			return fmt.Errorf("operation %d: unsupported op %q", i, op.Op)
+		}
+	}
+
+	return nil
+}
+
+// patchPointer resolves a JSON pointer to a top level member name
+func patchPointer(pointer string) (string, error) {
+	if !strings.HasPrefix(pointer, "/") || strings.Count(pointer, "/") != 1 {
+		return "", fmt.Errorf("unsupported path %q", pointer)
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(pointer[1:]), nil
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"goapi/logger"
+	"goapi/models"
+	"goapi/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubUserService serves a single stored user and records what the
+// handlers asked to change
+type stubUserService struct {
+	services.UserService
+	user    models.UserOutput
+	patch   *models.UserPatch
+	updated *models.UserOutput
+}
+
+func (s *stubUserService) PatchUser(ctx context.Context, id int64, apply services.PatchFunc, match *models.VersionMatch) (*models.UserOutput, error) {
+	current := s.user
+	patch, err := apply(&current)
+	if err != nil {
+		return nil, err
+	}
+	s.patch = patch
+	return &current, nil
+}
+
+func (s *stubUserService) UpdateUser(ctx context.Context, user *models.UserOutput, match *models.VersionMatch) (*models.UserOutput, error) {
+	s.updated = user
+	stored := s.user
+	stored.Name, stored.Email = user.Name, user.Email
+	return &stored, nil
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	logger.InitLogger(logger.Options{})
+}
+
+func newStubUserService() *stubUserService {
+	id := int64(1)
+	return &stubUserService{user: models.UserOutput{
+		ID:        &id,
+		Name:      "Jane Doe",
+		Email:     "jane@example.com",
+		Role:      models.RoleUser,
+		CreatedAt: "2024-01-01T00:00:00Z",
+		UpdatedAt: "2024-01-01T00:00:00Z",
+	}}
+}
+
+// serveUser runs one request through the user routes backed by svc
+func serveUser(svc services.UserService, method, contentType, body string) *httptest.ResponseRecorder {
+	handler := NewUserHandler(svc)
+	router := gin.New()
+	router.PUT("/users/:id", handler.UpdateUser)
+	router.PATCH("/users/:id", handler.PatchUser)
+
+	req := httptest.NewRequest(method, "/users/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPatchUser(t *testing.T) {
+	name, email, admin := "Janet Doe", "janet@example.com", models.RoleAdmin
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantPatch   *models.UserPatch
+	}{
+		{
+			name:        "merge patch changes a field",
+			contentType: mergePatchContentType,
+			body:        `{"name": "Janet Doe"}`,
+			wantStatus:  http.StatusOK,
+			wantPatch:   &models.UserPatch{Name: &name},
+		},
+		{
+			name:        "application/json is a merge patch",
+			contentType: "application/json",
+			body:        `{"email": "janet@example.com", "role": "admin"}`,
+			wantStatus:  http.StatusOK,
+			wantPatch:   &models.UserPatch{Email: &email, Role: &admin},
+		},
+		{
+			name:        "unchanged values are left out",
+			contentType: mergePatchContentType,
+			body:        `{"name": "Jane Doe", "id": 1}`,
+			wantStatus:  http.StatusOK,
+			wantPatch:   &models.UserPatch{},
+		},
+		{
+			name:        "JSON patch",
+			contentType: jsonPatchContentType,
+			body:        `[{"op": "test", "path": "/role", "value": "user"}, {"op": "replace", "path": "/email", "value": "janet@example.com"}]`,
+			wantStatus:  http.StatusOK,
+			wantPatch:   &models.UserPatch{Email: &email},
+		},
+		{
+			name:        "JSON patch copy",
+			contentType: jsonPatchContentType,
+			body:        `[{"op": "replace", "path": "/email", "value": "janet@example.com"}, {"op": "copy", "from": "/email", "path": "/name"}]`,
+			wantStatus:  http.StatusOK,
+			wantPatch:   &models.UserPatch{Name: &email, Email: &email},
+		},
+		{
+			name:        "JSON patch move removes the source",
+			contentType: jsonPatchContentType,
+			body:        `[{"op": "move", "from": "/email", "path": "/name"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "failed JSON patch test",
+			contentType: jsonPatchContentType,
+			body:        `[{"op": "test", "path": "/role", "value": "admin"}, {"op": "replace", "path": "/name", "value": "Janet Doe"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{name: "JSON patch of a nested path", contentType: jsonPatchContentType, body: `[{"op": "replace", "path": "/name/first", "value": "x"}]`, wantStatus: http.StatusBadRequest},
+		{name: "merge patch that is not an object", contentType: mergePatchContentType, body: `["name"]`, wantStatus: http.StatusBadRequest},
+		{name: "read-only field", contentType: mergePatchContentType, body: `{"id": 2}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", contentType: mergePatchContentType, body: `{"password": "new-password"}`, wantStatus: http.StatusBadRequest},
+		{name: "removed field", contentType: mergePatchContentType, body: `{"name": null}`, wantStatus: http.StatusBadRequest},
+		{name: "non-string value", contentType: mergePatchContentType, body: `{"name": 42}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid email", contentType: mergePatchContentType, body: `{"email": "not-an-email"}`, wantStatus: http.StatusBadRequest},
+		{name: "name too short", contentType: mergePatchContentType, body: `{"name": "Jo"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown role", contentType: mergePatchContentType, body: `{"role": "owner"}`, wantStatus: http.StatusBadRequest},
+		{name: "unsupported content type", contentType: "text/plain", body: `name=Janet`, wantStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newStubUserService()
+			rec := serveUser(svc, http.MethodPatch, tt.contentType, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("PATCH status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantPatch != nil && !reflect.DeepEqual(svc.patch, tt.wantPatch) {
+				t.Errorf("PATCH changes = %+v, want %+v", svc.patch, tt.wantPatch)
+			}
+		})
+	}
+}
+
+func TestUpdateUser_ValidatesInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "valid", body: `{"name": "Janet Doe", "email": "janet@example.com", "role": "manager"}`, wantStatus: http.StatusOK},
+		{name: "role may be left out", body: `{"name": "Janet Doe", "email": "janet@example.com"}`, wantStatus: http.StatusOK},
+		{name: "missing name", body: `{"email": "janet@example.com"}`, wantStatus: http.StatusBadRequest},
+		{name: "name too short", body: `{"name": "Jo", "email": "janet@example.com"}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid email", body: `{"name": "Janet Doe", "email": "janet"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown role", body: `{"name": "Janet Doe", "email": "janet@example.com", "role": "owner"}`, wantStatus: http.StatusBadRequest},
+		{name: "password", body: `{"name": "Janet Doe", "email": "janet@example.com", "password": "new-password"}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed JSON", body: `{"name": `, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newStubUserService()
+			rec := serveUser(svc, http.MethodPut, "application/json", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("PUT status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if svc.updated != nil {
+					t.Errorf("PUT updated the user with an invalid body")
+				}
+				return
+			}
+			if svc.updated == nil || *svc.updated.ID != 1 || svc.updated.Name != "Janet Doe" {
+				t.Errorf("PUT updated %+v, want user 1 named Janet Doe", svc.updated)
+			}
+			// The response is the user as stored, not the request body
+			if !strings.Contains(rec.Body.String(), `"created_at":"2024-01-01T00:00:00Z"`) {
+				t.Errorf("PUT response = %s, want the stored user", rec.Body)
+			}
+		})
+	}
+}
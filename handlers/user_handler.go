@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
 
 	"goapi/models"
@@ -9,6 +11,8 @@ import (
 	"goapi/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
 // UserHandler handles HTTP requests for user operations
@@ -25,63 +29,73 @@ func NewUserHandler(userService services.UserService) *UserHandler {
 
 // CreateUser godoc
 // @Summary Create a new user
-// @Description Create a new user with the provided information
+// @Description Create a new user with the provided information. Requires the users:create permission; assigning a role other than user requires roles:assign.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param user body models.UserInput true "User object"
 // @Success 201 {object} models.UserOutput
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Header 201 {string} ETag "Entity tag of the user version"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Security BearerAuth
 // @Router /users [post]
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var user models.UserInput
 	if err := c.ShouldBindJSON(&user); err != nil {
-		errorResp := models.ToErrorResponse(models.NewAppError(http.StatusBadRequest, "invalid request body", err))
-		c.JSON(errorResp.Code, errorResp)
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid request body", err))
 		return
 	}
 
 	createdUser, err := h.userService.CreateUser(c.Request.Context(), &user)
 	if err != nil {
-		errorResp := models.ToErrorResponse(err)
-		c.JSON(errorResp.Code, errorResp)
+		respondError(c, err)
 		return
 	}
 
+	setUserETag(c, createdUser)
 	c.JSON(http.StatusCreated, createdUser)
 }
 
 // GetUserByID godoc
 // @Summary Get a user by ID
-// @Description Get a user by their ID
+// @Description Get a user by their ID. Users can read their own record; reading others requires the users:read permission.
+// @Description Returns 304 when If-None-Match matches the current ETag.
 // @Tags users
 // @Produce json
 // @Param id path int true "User ID"
+// @Param If-None-Match header string false "ETag of a cached representation"
 // @Success 200 {object} models.UserOutput
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Entity tag of the user version"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Security BearerAuth
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUserByID(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		errorResp := models.ToErrorResponse(models.NewAppError(http.StatusBadRequest, "invalid user ID", err))
-		c.JSON(errorResp.Code, errorResp)
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid user ID", err))
 		return
 	}
 
 	user, err := h.userService.GetUserByID(c.Request.Context(), id)
 	if err != nil {
-		errorResp := models.ToErrorResponse(err)
-		c.JSON(errorResp.Code, errorResp)
+		respondError(c, err)
 		return
 	}
 	if user == nil {
-		errorResp := models.ToErrorResponse(models.ErrNotFound)
-		c.JSON(errorResp.Code, errorResp)
+		respondError(c, models.ErrUserNotFound)
+		return
+	}
+
+	setUserETag(c, user)
+	if notModified(c, user) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
@@ -90,23 +104,63 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 
 // ListUsers godoc
 // @Summary List users
-// @Description Get a paginated list of users
+// @Description Get a paginated list of users. Requires the users:list permission.
+// @Description Pass an empty cursor to switch to cursor pagination, then follow next_cursor and prev_cursor.
+// @Description Filters use field=value or field[op]=value on id, name, email, role, created_at and updated_at,
+// @Description with operators eq, ne, gt, gte, lt, lte, like and in (comma separated values), e.g. created_at[gte]=2024-01-01.
 // @Tags users
 // @Produce json
 // @Param limit query int false "Limit"
 // @Param offset query int false "Offset"
-// @Param name query string false "Name filter"
-// @Param email query string false "Email filter"
-// @Param order query string false "Sort order (ASC or DESC)"
+// @Param name query string false "Name contains (same as name[like])"
+// @Param email query string false "Email contains (same as email[like])"
+// @Param role query string false "Role equals"
+// @Param created_at[gte] query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param created_at[lt] query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_at[gte] query string false "Updated at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param updated_at[lt] query string false "Updated before (RFC 3339 or YYYY-MM-DD)"
+// @Param sort query string false "Comma separated sort fields, prefixed with - for descending, e.g. -created_at,name"
+// @Param order query string false "Direction of the default sort by id (ASC or DESC), used when sort is empty"
+// @Param cursor query string false "Opaque cursor from a previous page; empty for the first page"
+// @Param include_total query bool false "Include total_count (default true for offset pagination, false for cursor pagination)"
 // @Success 200 {object} models.UserList
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Security BearerAuth
 // @Router /users [get]
 func (h *UserHandler) ListUsers(c *gin.Context) {
+	h.listUsers(c, false)
+}
+
+// ListDeletedUsers godoc
+// @Summary List deleted users
+// @Description Get a paginated list of soft deleted users, with the same pagination, filters and sorting as GET /users.
+// @Description Requires the users:list and users:restore permissions.
+// @Tags users
+// @Produce json
+// @Param limit query int false "Limit"
+// @Param offset query int false "Offset"
+// @Param sort query string false "Comma separated sort fields, prefixed with - for descending"
+// @Param cursor query string false "Opaque cursor from a previous page; empty for the first page"
+// @Param include_total query bool false "Include total_count"
+// @Success 200 {object} models.UserList
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Security BearerAuth
+// @Router /users/deleted [get]
+func (h *UserHandler) ListDeletedUsers(c *gin.Context) {
+	h.listUsers(c, true)
+}
+
+// listUsers parses the list query parameters and writes the list of live
+// or soft deleted users
+func (h *UserHandler) listUsers(c *gin.Context, deleted bool) {
 	params := users_sql.SearchParams{
-		Limit:  10, // default limit
-		Offset: 0,
+		Limit:   10, // default limit
+		Offset:  0,
+		Deleted: deleted,
 	}
 
 	if limit := c.Query("limit"); limit != "" {
@@ -120,14 +174,36 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		}
 	}
 
-	params.Name = c.Query("name")
-	params.Email = c.Query("email")
 	params.Order = users_sql.SortOrder(c.Query("order"))
 
+	filters, err := users_sql.ParseFilters(c.Request.URL.Query())
+	if err != nil {
+		respondError(c, models.ErrInvalidFilter.Wrap(err))
+		return
+	}
+	params.Filters = filters
+
+	sort, err := users_sql.ParseSort(c.Query("sort"))
+	if err != nil {
+		respondError(c, models.ErrInvalidSort.Wrap(err))
+		return
+	}
+	params.Sort = sort
+
+	// Cursor pagination is opt-in: any cursor parameter, even empty, enables it
+	params.Cursor, params.CursorMode = c.GetQuery("cursor")
+
+	// The total count is included by default only for offset pagination
+	params.IncludeTotal = !params.CursorMode
+	if includeTotal := c.Query("include_total"); includeTotal != "" {
+		if b, err := strconv.ParseBool(includeTotal); err == nil {
+			params.IncludeTotal = b
+		}
+	}
+
 	users, err := h.userService.ListUsers(c.Request.Context(), params)
 	if err != nil {
-		errorResp := models.ToErrorResponse(err)
-		c.JSON(errorResp.Code, errorResp)
+		respondError(c, err)
 		return
 	}
 
@@ -136,68 +212,302 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 
 // UpdateUser godoc
 // @Summary Update a user
-// @Description Update an existing user's information
+// @Description Update an existing user's information. Users can update their own record; updating others requires users:update and changing a role requires roles:assign.
+// @Description The password is set with PUT /users/{id}/password instead.
+// @Description With If-Match, the update only applies if the user still has that ETag.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
+// @Param If-Match header string false "ETag the user must still have"
 // @Param user body models.UserInput true "User object"
 // @Success 200 {object} models.UserOutput
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Header 200 {string} ETag "Entity tag of the new user version"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Security BearerAuth
 // @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		errorResp := models.ToErrorResponse(models.NewAppError(http.StatusBadRequest, "invalid user ID", err))
-		c.JSON(errorResp.Code, errorResp)
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid user ID", err))
 		return
 	}
 
-	var user models.UserOutput
-	if err := c.ShouldBindJSON(&user); err != nil {
-		errorResp := models.ToErrorResponse(models.NewAppError(http.StatusBadRequest, "invalid request body", err))
-		c.JSON(errorResp.Code, errorResp)
+	var input models.UserInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid request body", err))
+		return
+	}
+	if input.Password != "" {
+		respondError(c, models.NewAppError(http.StatusBadRequest,
+			"password cannot be updated here, use PUT /users/{id}/password", nil))
+		return
+	}
+
+	user := models.UserOutput{ID: &id, Name: input.Name, Email: input.Email, Role: input.Role}
+	updated, err := h.userService.UpdateUser(c.Request.Context(), &user, parseIfMatch(c))
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	user.ID = &id
-	if err := h.userService.UpdateUser(c.Request.Context(), &user); err != nil {
-		errorResp := models.ToErrorResponse(err)
-		c.JSON(errorResp.Code, errorResp)
+	setUserETag(c, updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// PatchUser godoc
+// @Summary Partially update a user
+// @Description Update only the provided fields of a user. Accepts a JSON Merge Patch (application/merge-patch+json, also assumed for application/json)
+// @Description or a JSON Patch (application/json-patch+json). The result is validated like UserInput.
+// @Description Users can update their own record; updating others requires users:update and changing a role requires roles:assign.
+// @Description With If-Match, the patch only applies if the user still has that ETag.
+// @Tags users
+// @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param If-Match header string false "ETag the user must still have"
+// @Param patch body object true "Merge patch object or JSON Patch operations"
+// @Success 200 {object} models.UserOutput
+// @Header 200 {string} ETag "Entity tag of the new user version"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 415 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Security BearerAuth
+// @Router /users/{id} [patch]
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid user ID", err))
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != mergePatchContentType && contentType != jsonPatchContentType && contentType != "application/json" {
+		respondError(c, models.NewAppError(http.StatusUnsupportedMediaType,
+			"content type must be "+mergePatchContentType+" or "+jsonPatchContentType, nil))
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid request body", err))
+		return
+	}
+
+	// The service loads the current user and this turns the body into the changed fields
+	apply := func(current *models.UserOutput) (*models.UserPatch, error) {
+		original, err := toDocument(current)
+		if err != nil {
+			return nil, err
+		}
+		patched, _ := toDocument(current)
+
+		if contentType == jsonPatchContentType {
+			err = applyJSONPatch(patched, body)
+		} else {
+			err = applyMergePatch(patched, body)
+		}
+		if err != nil {
+			return nil, models.ErrInvalidPatch.Wrap(err)
+		}
+
+		patch, err := userPatchFromDocuments(original, patched)
+		if err != nil {
+			return nil, models.ErrInvalidPatch.Wrap(err)
+		}
+		return patch, nil
+	}
+
+	user, err := h.userService.PatchUser(c.Request.Context(), id, apply, parseIfMatch(c))
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
+	setUserETag(c, user)
 	c.JSON(http.StatusOK, user)
 }
 
+// userPatchFromDocuments compares a patched user document with the original
+// and returns the changed fields, validated with the UserInput rules
+func userPatchFromDocuments(original, patched map[string]interface{}) (*models.UserPatch, error) {
+	patch := &models.UserPatch{}
+	var input models.UserInput
+	var changed []string
+
+	for key, value := range patched {
+		switch key {
+		case "name", "email", "role":
+		default:
+			if _, known := original[key]; !known {
+				return nil, fmt.Errorf("unknown field %q", key)
+			}
+			if !reflect.DeepEqual(original[key], value) {
+				return nil, fmt.Errorf("field %q is read-only", key)
+			}
+		}
+	}
+
+	for _, key := range []string{"name", "email", "role"} {
+		value, ok := patched[key]
+		if !ok {
+			return nil, fmt.Errorf("field %q cannot be removed", key)
+		}
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("field %q must be a string", key)
+		}
+		if str == original[key] {
+			continue
+		}
+
+		switch key {
+		case "name":
+			input.Name, patch.Name = str, &str
+			changed = append(changed, "Name")
+		case "email":
+			input.Email, patch.Email = str, &str
+			changed = append(changed, "Email")
+		case "role":
+			role := models.Role(str)
+			input.Role, patch.Role = role, &role
+			changed = append(changed, "Role")
+		}
+	}
+
+	if len(changed) > 0 {
+		validate, ok := binding.Validator.Engine().(*validator.Validate)
+		if ok {
+			if err := validate.StructPartial(input, changed...); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return patch, nil
+}
+
+// SetUserPassword godoc
+// @Summary Set the password of a user
+// @Description Set the password the user logs in with, e.g. for users created without one. Users can set their own password; setting others' requires users:update.
+// @Description With If-Match, the password is only set if the user still has that ETag.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param If-Match header string false "ETag the user must still have"
+// @Param password body models.PasswordInput true "New password"
+// @Success 200 {object} models.UserOutput
+// @Header 200 {string} ETag "Entity tag of the new user version"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Security BearerAuth
+// @Router /users/{id}/password [put]
+func (h *UserHandler) SetUserPassword(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid user ID", err))
+		return
+	}
+
+	var input models.PasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid request body", err))
+		return
+	}
+
+	updated, err := h.userService.SetPassword(c.Request.Context(), id, input.Password, parseIfMatch(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setUserETag(c, updated)
+	c.JSON(http.StatusOK, updated)
+}
+
 // DeleteUser godoc
 // @Summary Delete a user
-// @Description Delete a user by their ID
+// @Description Soft delete a user by their ID. Requires the users:delete permission.
+// @Description With hard=true the user and their tokens and API keys are erased permanently, including soft deleted users; this requires users:purge.
+// @Description With If-Match, the user is only deleted if it still has that ETag.
 // @Tags users
 // @Produce json
 // @Param id path int true "User ID"
+// @Param hard query bool false "Permanently delete the user"
+// @Param If-Match header string false "ETag the user must still have"
 // @Success 204 "No Content"
-// @Failure 400 {object} models.ErrorResponse
-// @Failure 404 {object} models.ErrorResponse
-// @Failure 500 {object} models.ErrorResponse
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Security BearerAuth
 // @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		errorResp := models.ToErrorResponse(models.NewAppError(http.StatusBadRequest, "invalid user ID", err))
-		c.JSON(errorResp.Code, errorResp)
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid user ID", err))
 		return
 	}
 
-	if err := h.userService.DeleteUser(c.Request.Context(), id); err != nil {
-		errorResp := models.ToErrorResponse(err)
-		c.JSON(errorResp.Code, errorResp)
+	hard, _ := strconv.ParseBool(c.Query("hard"))
+	if hard {
+		err = h.userService.PurgeUser(c.Request.Context(), id, parseIfMatch(c))
+	} else {
+		err = h.userService.DeleteUser(c.Request.Context(), id, parseIfMatch(c))
+	}
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// RestoreUser godoc
+// @Summary Restore a deleted user
+// @Description Undo the soft deletion of a user. Requires the users:restore permission.
+// @Description Fails with 409 if another user has taken the email since.
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.UserOutput
+// @Header 200 {string} ETag "Entity tag of the user version"
+// @Failure 400 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Security BearerAuth
+// @Router /users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, models.NewAppError(http.StatusBadRequest, "invalid user ID", err))
+		return
+	}
+
+	user, err := h.userService.RestoreUser(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setUserETag(c, user)
+	c.JSON(http.StatusOK, user)
+}
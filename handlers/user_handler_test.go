@@ -0,0 +1,304 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"goapi/config"
+	"goapi/models"
+	"goapi/repository"
+	"goapi/routes"
+	"goapi/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testAdminEmail    = "admin@example.com"
+	testAdminPassword = "Secret123!"
+)
+
+// testAPI is the router of the application backed by the in-memory store
+type testAPI struct {
+	t      *testing.T
+	router *gin.Engine
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		Environment: config.Prod,
+		Auth: config.AuthConfig{
+			Algorithm:       config.HS256,
+			Secret:          "test-secret-that-is-long-enough-for-hs256",
+			Issuer:          "goapi-test",
+			Audience:        "goapi-test",
+			AccessTokenTTL:  time.Minute,
+			RefreshTokenTTL: time.Hour,
+		},
+	}
+
+	repos := repository.NewMemoryRepositories()
+	if err := services.EnsureAdmin(context.Background(), repos.Users, testAdminEmail, testAdminPassword); err != nil {
+		t.Fatalf("EnsureAdmin() error = %v", err)
+	}
+	return &testAPI{t: t, router: routes.SetupRouter(cfg, repos, nil)}
+}
+
+// do sends a request with an optional bearer token, JSON body and headers
+// given as name, value pairs
+func (a *testAPI) do(method, path, token string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			a.t.Fatalf("json.Marshal() error = %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+	return w
+}
+
+// login returns an access token for the credentials
+func (a *testAPI) login(email, password string) string {
+	a.t.Helper()
+	w := a.do(http.MethodPost, "/auth/login", "", map[string]string{"email": email, "password": password})
+	if w.Code != http.StatusOK {
+		a.t.Fatalf("POST /auth/login status = %d, body %s", w.Code, w.Body)
+	}
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	decode(a.t, w, &tokens)
+	return tokens.AccessToken
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+}
+
+// expectProblem checks the status and the code of an error response
+func expectProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code models.ErrorCode) models.Problem {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d, body %s", w.Code, status, w.Body)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != models.ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, models.ProblemContentType)
+	}
+	var problem models.Problem
+	decode(t, w, &problem)
+	if problem.Code != code {
+		t.Errorf("code = %q, want %q", problem.Code, code)
+	}
+	return problem
+}
+
+func TestUserRoutes_RequireAuthentication(t *testing.T) {
+	api := newTestAPI(t)
+
+	w := api.do(http.MethodGet, "/users", "", nil)
+	expectProblem(t, w, http.StatusUnauthorized, models.CodeUnauthorized)
+	if challenge := w.Header().Get("WWW-Authenticate"); challenge != "Bearer" {
+		t.Errorf("WWW-Authenticate = %q, want %q", challenge, "Bearer")
+	}
+
+	// The parser error is not returned to the client
+	w = api.do(http.MethodGet, "/users", "not.a.jwt", nil)
+	problem := expectProblem(t, w, http.StatusUnauthorized, models.CodeUnauthorized)
+	if problem.Detail != "invalid or expired token" {
+		t.Errorf("detail = %q, want %q", problem.Detail, "invalid or expired token")
+	}
+	if challenge := w.Header().Get("WWW-Authenticate"); challenge != `Bearer error="invalid_token"` {
+		t.Errorf("WWW-Authenticate = %q, want the invalid_token challenge", challenge)
+	}
+}
+
+func TestUserRoutes_Lifecycle(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.login(testAdminEmail, testAdminPassword)
+
+	w := api.do(http.MethodPost, "/users", token, models.UserInput{Name: "Jane Doe", Email: "jane@example.com"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /users status = %d, body %s", w.Code, w.Body)
+	}
+	var user models.UserOutput
+	decode(t, w, &user)
+	path := fmt.Sprintf("/users/%d", *user.ID)
+	etag := w.Header().Get("ETag")
+
+	w = api.do(http.MethodGet, path, token, nil, "If-None-Match", etag)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("GET with If-None-Match status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	// Writes with a stale ETag fail, with the current one they succeed
+	update := models.UserInput{Name: "Jane Smith", Email: "jane@example.com"}
+	w = api.do(http.MethodPut, path, token, update, "If-Match", `"99"`)
+	expectProblem(t, w, http.StatusPreconditionFailed, models.CodePreconditionFailed)
+
+	w = api.do(http.MethodPut, path, token, update, "If-Match", etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT with If-Match status = %d, body %s", w.Code, w.Body)
+	}
+	if newETag := w.Header().Get("ETag"); newETag == etag {
+		t.Errorf("PUT kept the ETag %s, want a new version", etag)
+	}
+	etag = w.Header().Get("ETag")
+
+	w = api.do(http.MethodPatch, path, token, `{"name":"Jane Roe"}`, "Content-Type", "application/merge-patch+json", "If-Match", etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH status = %d, body %s", w.Code, w.Body)
+	}
+	decode(t, w, &user)
+	if user.Name != "Jane Roe" {
+		t.Errorf("PATCH name = %q, want %q", user.Name, "Jane Roe")
+	}
+	etag = w.Header().Get("ETag")
+
+	// Soft delete, then the user is only listed among the deleted ones
+	w = api.do(http.MethodDelete, path, token, nil, "If-Match", etag)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, body %s", w.Code, w.Body)
+	}
+	expectProblem(t, api.do(http.MethodGet, path, token, nil), http.StatusNotFound, models.CodeUserNotFound)
+
+	w = api.do(http.MethodGet, "/users/deleted", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /users/deleted status = %d, body %s", w.Code, w.Body)
+	}
+	var deleted models.UserList
+	decode(t, w, &deleted)
+	if len(deleted.Users) != 1 || *deleted.Users[0].ID != *user.ID {
+		t.Fatalf("GET /users/deleted = %+v, want only user %d", deleted.Users, *user.ID)
+	}
+
+	w = api.do(http.MethodPost, path+"/restore", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST restore status = %d, body %s", w.Code, w.Body)
+	}
+	etag = w.Header().Get("ETag")
+	if w = api.do(http.MethodGet, path, token, nil); w.Code != http.StatusOK {
+		t.Fatalf("GET of a restored user status = %d, body %s", w.Code, w.Body)
+	}
+
+	// Deleted users can be purged with the ETag they were deleted with
+	if w = api.do(http.MethodDelete, path, token, nil, "If-Match", etag); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, body %s", w.Code, w.Body)
+	}
+	w = api.do(http.MethodDelete, path+"?hard=true", token, nil, "If-Match", `"99"`)
+	expectProblem(t, w, http.StatusPreconditionFailed, models.CodePreconditionFailed)
+	w = api.do(http.MethodDelete, path+"?hard=true", token, nil, "If-Match", "*")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE ?hard=true status = %d, body %s", w.Code, w.Body)
+	}
+	expectProblem(t, api.do(http.MethodPost, path+"/restore", token, nil), http.StatusNotFound, models.CodeUserNotFound)
+}
+
+func TestUserRoutes_Authorization(t *testing.T) {
+	api := newTestAPI(t)
+	admin := api.login(testAdminEmail, testAdminPassword)
+
+	var jane, john models.UserOutput
+	decode(t, api.do(http.MethodPost, "/users", admin, models.UserInput{Name: "Jane Doe", Email: "jane@example.com"}), &jane)
+	decode(t, api.do(http.MethodPost, "/users", admin, models.UserInput{Name: "John Doe", Email: "john@example.com"}), &john)
+
+	// Users created without a password log in once one is set
+	w := api.do(http.MethodPut, fmt.Sprintf("/users/%d/password", *jane.ID), admin, models.PasswordInput{Password: "Password1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT password status = %d, body %s", w.Code, w.Body)
+	}
+	token := api.login(jane.Email, "Password1")
+
+	if w = api.do(http.MethodGet, fmt.Sprintf("/users/%d", *jane.ID), token, nil); w.Code != http.StatusOK {
+		t.Fatalf("GET self status = %d, body %s", w.Code, w.Body)
+	}
+	expectProblem(t, api.do(http.MethodGet, fmt.Sprintf("/users/%d", *john.ID), token, nil), http.StatusForbidden, models.CodeForbidden)
+	expectProblem(t, api.do(http.MethodGet, "/users", token, nil), http.StatusForbidden, models.CodeForbidden)
+	expectProblem(t, api.do(http.MethodDelete, fmt.Sprintf("/users/%d", *john.ID), token, nil), http.StatusForbidden, models.CodeForbidden)
+	expectProblem(t, api.do(http.MethodPut, fmt.Sprintf("/users/%d/password", *john.ID), token, models.PasswordInput{Password: "Password2"}),
+		http.StatusForbidden, models.CodeForbidden)
+
+	// Changing the own role requires roles:assign
+	w = api.do(http.MethodPatch, fmt.Sprintf("/users/%d", *jane.ID), token, `{"role":"admin"}`, "Content-Type", "application/merge-patch+json")
+	expectProblem(t, w, http.StatusForbidden, models.CodeForbidden)
+}
+
+func TestUserRoutes_ProblemDetails(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.login(testAdminEmail, testAdminPassword)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		status     int
+		code       models.ErrorCode
+		wantDetail string
+	}{
+		{
+			name: "invalid filter", method: http.MethodGet, path: "/users?nickname=jo",
+			status: http.StatusBadRequest, code: models.CodeInvalidFilter, wantDetail: `invalid filter: unknown filter field "nickname"`,
+		},
+		{
+			name: "invalid search parameters", method: http.MethodGet, path: "/users?limit=100",
+			status: http.StatusBadRequest, code: models.CodeInvalidSearchParams, wantDetail: "invalid search parameters: limit must be between 1 and 50",
+		},
+		{
+			name: "malformed JSON", method: http.MethodPost, path: "/users", body: `{"name":`,
+			status: http.StatusBadRequest, code: models.CodeBadRequest, wantDetail: "invalid request body",
+		},
+		{
+			name: "invalid user ID", method: http.MethodGet, path: "/users/abc",
+			status: http.StatusBadRequest, code: models.CodeBadRequest, wantDetail: "invalid user ID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := expectProblem(t, api.do(tt.method, tt.path, token, tt.body), tt.status, tt.code)
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+
+	// Invalid fields are listed one by one
+	w := api.do(http.MethodPost, "/users", token, models.UserInput{Name: "Jo", Email: "not-an-email"})
+	problem := expectProblem(t, w, http.StatusBadRequest, models.CodeValidationFailed)
+	if len(problem.Errors) != 2 {
+		t.Fatalf("errors = %+v, want the name and email", problem.Errors)
+	}
+}
@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"context"
+
+	"goapi/repository"
+)
+
+// ReplicaHealthJob checks the read replicas, so reads only go to the ones
+// that respond and fall back to the primary otherwise
+type ReplicaHealthJob struct {
+	db *repository.DB
+}
+
+// NewReplicaHealthJob creates a new ReplicaHealthJob
+func NewReplicaHealthJob(db *repository.DB) *ReplicaHealthJob {
+	return &ReplicaHealthJob{db: db}
+}
+
+// Name implements Job
+func (j *ReplicaHealthJob) Name() string {
+	return "replica_health"
+}
+
+// Run implements Job
+func (j *ReplicaHealthJob) Run(ctx context.Context) error {
+	j.db.CheckReplicas(ctx)
+	return nil
+}
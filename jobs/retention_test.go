@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"goapi/config"
+	"goapi/logger"
+)
+
+func init() {
+	logger.InitLogger(logger.Options{})
+}
+
+// fakeRetentionRepository holds a number of expired users and records the
+// batches the job asks for
+type fakeRetentionRepository struct {
+	expired   int64
+	locked    bool
+	failAfter int
+	purged    []int64
+	anonymize []int64
+}
+
+func (r *fakeRetentionRepository) WithLock(ctx context.Context, name string, fn func() error) (bool, error) {
+	if r.locked {
+		return false, nil
+	}
+	return true, fn()
+}
+
+func (r *fakeRetentionRepository) batch(done *[]int64, limit int) (int64, error) {
+	if r.failAfter > 0 && len(*done) == r.failAfter {
+		return 0, errors.New("connection reset")
+	}
+	count := min(r.expired, int64(limit))
+	r.expired -= count
+	*done = append(*done, count)
+	return count, nil
+}
+
+func (r *fakeRetentionRepository) PurgeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	return r.batch(&r.purged, limit)
+}
+
+func (r *fakeRetentionRepository) AnonymizeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	return r.batch(&r.anonymize, limit)
+}
+
+func retentionConfig(mode string) config.RetentionConfig {
+	return config.RetentionConfig{Enabled: true, Mode: mode, Period: 30 * 24 * time.Hour, Interval: time.Hour, BatchSize: 10}
+}
+
+func TestRetentionJob_ProcessesBatchesUntilOneIsShort(t *testing.T) {
+	tests := []struct {
+		name    string
+		expired int64
+		want    []int64
+	}{
+		{name: "nothing to do", expired: 0, want: []int64{0}},
+		{name: "one short batch", expired: 7, want: []int64{7}},
+		{name: "exact batches", expired: 20, want: []int64{10, 10, 0}},
+		{name: "backlog", expired: 25, want: []int64{10, 10, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRetentionRepository{expired: tt.expired}
+			if err := NewRetentionJob(repo, retentionConfig(config.RetentionDelete)).Run(context.Background()); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !slices.Equal(repo.purged, tt.want) {
+				t.Errorf("Run() purged batches %v, want %v", repo.purged, tt.want)
+			}
+			if len(repo.anonymize) != 0 {
+				t.Errorf("Run() anonymized %v in delete mode", repo.anonymize)
+			}
+		})
+	}
+}
+
+func TestRetentionJob_Anonymize(t *testing.T) {
+	repo := &fakeRetentionRepository{expired: 12}
+	if err := NewRetentionJob(repo, retentionConfig(config.RetentionAnonymize)).Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !slices.Equal(repo.anonymize, []int64{10, 2}) || len(repo.purged) != 0 {
+		t.Errorf("Run() anonymized %v and purged %v, want [10 2] and none", repo.anonymize, repo.purged)
+	}
+}
+
+func TestRetentionJob_SkipsWhenLocked(t *testing.T) {
+	repo := &fakeRetentionRepository{expired: 5, locked: true}
+	if err := NewRetentionJob(repo, retentionConfig(config.RetentionDelete)).Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(repo.purged) != 0 {
+		t.Errorf("Run() purged %v while another instance held the lock", repo.purged)
+	}
+}
+
+func TestRetentionJob_StopsOnError(t *testing.T) {
+	repo := &fakeRetentionRepository{expired: 50, failAfter: 2}
+	err := NewRetentionJob(repo, retentionConfig(config.RetentionDelete)).Run(context.Background())
+	if err == nil {
+		t.Fatal("Run() error = nil, want the batch error")
+	}
+	if !slices.Equal(repo.purged, []int64{10, 10}) {
+		t.Errorf("Run() purged batches %v, want [10 10] before the error", repo.purged)
+	}
+}
+
+func TestRetentionJob_StopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	repo := &fakeRetentionRepository{expired: 50}
+	if err := NewRetentionJob(repo, retentionConfig(config.RetentionDelete)).Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want %v", err, context.Canceled)
+	}
+	if len(repo.purged) != 0 {
+		t.Errorf("Run() purged %v after the context was canceled", repo.purged)
+	}
+}
+
+// countingJob counts its runs and panics on the first one
+type countingJob struct {
+	runs atomic.Int32
+}
+
+func (j *countingJob) Name() string { return "counting" }
+
+func (j *countingJob) Run(ctx context.Context) error {
+	if j.runs.Add(1) == 1 {
+		panic("first run")
+	}
+	return nil
+}
+
+func TestScheduler_RunsJobsUntilCanceled(t *testing.T) {
+	job := &countingJob{}
+	scheduler := NewScheduler()
+	scheduler.Add(job, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler.Start(ctx)
+
+	// The panic of the first run does not stop the loop
+	deadline := time.Now().Add(5 * time.Second)
+	for job.runs.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	scheduler.Wait()
+
+	runs := job.runs.Load()
+	if runs < 3 {
+		t.Fatalf("job ran %d times, want at least 3", runs)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if job.runs.Load() != runs {
+		t.Error("job kept running after Wait returned")
+	}
+}
@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"goapi/logger"
+)
+
+// logComponent is the log component of the jobs, whose log level can be
+// changed on its own at runtime
+const logComponent = "jobs"
+
+// Job is a unit of background work run periodically by the Scheduler
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type scheduledJob struct {
+	job      Job
+	interval time.Duration
+}
+
+// Scheduler runs jobs in process, each on its own interval
+type Scheduler struct {
+	jobs []scheduledJob
+	wg   sync.WaitGroup
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add registers a job to run every interval. Jobs must be added before Start.
+func (s *Scheduler) Add(job Job, interval time.Duration) {
+	s.jobs = append(s.jobs, scheduledJob{job: job, interval: interval})
+}
+
+// Start runs every job once and then on its interval, until ctx is done
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, scheduled := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, scheduled)
+	}
+}
+
+// Wait blocks until all jobs have stopped after ctx is done
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, scheduled scheduledJob) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(scheduled.interval)
+	defer ticker.Stop()
+
+	logger.FromContext(ctx).Component(logComponent).Info("Job scheduled", "job", scheduled.job.Name(), "interval", scheduled.interval)
+	for {
+		s.run(ctx, scheduled.job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run runs the job once, recovering from panics so the loop keeps going
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	log := logger.FromContext(ctx).Component(logComponent)
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Job panicked", "job", job.Name(), "panic", r)
+		}
+	}()
+
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		log.Error("Job failed", "job", job.Name(), "duration", time.Since(start), "error", err)
+		return
+	}
+	log.Debug("Job finished", "job", job.Name(), "duration", time.Since(start))
+}
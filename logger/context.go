@@ -0,0 +1,20 @@
+package logger
+
+import "context"
+
+type contextKey struct{}
+
+// WithContext returns a context carrying l, so code handling a request logs
+// with the fields of the request
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of ctx, or the default logger when ctx
+// carries none
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return defaultLogger
+}
@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ComponentKey is the key naming the component of a record, see
+// Logger.Component
+const ComponentKey = "component"
+
+// LevelStatus is the runtime level of a logger or of one of its components
+type LevelStatus struct {
+	Level LogLevel
+	// RevertAt is when a timed change reverts, zero for lasting levels
+	RevertAt time.Time
+}
+
+// levels are the runtime levels of a logger: its own level, and the levels
+// of the components overriding it. Reading them is lock free, as it happens
+// for every record.
+type levels struct {
+	level      slog.LevelVar
+	components atomic.Pointer[map[string]slog.Level]
+
+	// mu serializes the changes
+	mu sync.Mutex
+	// kept are the levels timed changes revert to, by component, "" being
+	// the logger level. A component without a kept level reverts to the
+	// logger level.
+	kept    map[string]LogLevel
+	reverts map[string]*levelRevert
+}
+
+// levelRevert is a pending revert of a timed change
+type levelRevert struct {
+	timer *time.Timer
+	at    time.Time
+}
+
+func newLevels(level LogLevel) *levels {
+	l := &levels{
+		kept:    map[string]LogLevel{"": level},
+		reverts: map[string]*levelRevert{},
+	}
+	l.level.Set(slogLevels[level])
+	l.components.Store(&map[string]slog.Level{})
+	return l
+}
+
+// min returns the minimum level of the records of a component
+func (l *levels) min(component string) slog.Level {
+	if component != "" {
+		if level, ok := (*l.components.Load())[component]; ok {
+			return level
+		}
+	}
+	return l.level.Level()
+}
+
+// set changes the level of a component, "" for the logger level. With a
+// positive revertAfter the change is timed, otherwise it lasts and cancels
+// a pending revert.
+func (l *levels) set(component string, level LogLevel, revertAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cancelRevert(component)
+	if revertAfter > 0 {
+		revert := &levelRevert{at: time.Now().Add(revertAfter)}
+		revert.timer = time.AfterFunc(revertAfter, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			// A later change replaced this one
+			if l.reverts[component] == revert {
+				delete(l.reverts, component)
+				l.apply(component)
+			}
+		})
+		l.reverts[component] = revert
+	} else {
+		l.kept[component] = level
+	}
+
+	l.store(component, level)
+}
+
+// reset cancels a timed change of a component, "" for the logger level,
+// and makes the component follow the logger level again
+func (l *levels) reset(component string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cancelRevert(component)
+	if component != "" {
+		delete(l.kept, component)
+	}
+	l.apply(component)
+}
+
+// status returns the levels, under "" for the logger level
+func (l *levels) status() map[string]LevelStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := map[string]LevelStatus{"": {Level: fromSlogLevel(l.level.Level())}}
+	for component, level := range *l.components.Load() {
+		status[component] = LevelStatus{Level: fromSlogLevel(level)}
+	}
+	for component, revert := range l.reverts {
+		if s, ok := status[component]; ok {
+			s.RevertAt = revert.at
+			status[component] = s
+		}
+	}
+	return status
+}
+
+// cancelRevert stops the pending revert of a component. l.mu must be held.
+func (l *levels) cancelRevert(component string) {
+	if revert, ok := l.reverts[component]; ok {
+		revert.timer.Stop()
+		delete(l.reverts, component)
+	}
+}
+
+// apply restores the kept level of a component. l.mu must be held.
+func (l *levels) apply(component string) {
+	if level, ok := l.kept[component]; ok {
+		l.store(component, level)
+		return
+	}
+
+	components := maps.Clone(*l.components.Load())
+	delete(components, component)
+	l.components.Store(&components)
+}
+
+// store publishes the level of a component. l.mu must be held.
+func (l *levels) store(component string, level LogLevel) {
+	if component == "" {
+		l.level.Set(slogLevels[level])
+		return
+	}
+
+	components := maps.Clone(*l.components.Load())
+	components[component] = slogLevels[level]
+	l.components.Store(&components)
+}
+
+// levelGate drops the records below the runtime level of their component
+// before they reach the sinks
+type levelGate struct {
+	next      slog.Handler
+	levels    *levels
+	component string
+}
+
+// Enabled implements slog.Handler
+func (g *levelGate) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= g.levels.min(g.component) && g.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (g *levelGate) Handle(ctx context.Context, r slog.Record) error {
+	return g.next.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler, noting the component of the logger
+func (g *levelGate) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *g
+	for _, a := range attrs {
+		if a.Key == ComponentKey {
+			clone.component = a.Value.String()
+		}
+	}
+	clone.next = g.next.WithAttrs(attrs)
+	return &clone
+}
+
+// WithGroup implements slog.Handler
+func (g *levelGate) WithGroup(name string) slog.Handler {
+	clone := *g
+	clone.next = g.next.WithGroup(name)
+	return &clone
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger_ComponentLevels(t *testing.T) {
+	log, buf := newTestLogger(DEBUG, FormatText)
+	log.SetLevel("", WARN, 0)
+	repo := log.Component("repository")
+	jobs := log.With("job", "retention").Component("jobs")
+
+	log.SetLevel("repository", DEBUG, 0)
+	repo.Debug("query")
+	jobs.Info("batch")
+	log.Info("request")
+	jobs.Warn("lock held")
+
+	got := buf.String()
+	if !strings.Contains(got, "query component=repository") || !strings.Contains(got, "lock held job=retention component=jobs") {
+		t.Errorf("output = %q, want the repository DEBUG and the jobs WARN records", got)
+	}
+	if strings.Contains(got, "batch") || strings.Contains(got, "request") {
+		t.Errorf("output = %q, want the INFO records below the logger level dropped", got)
+	}
+
+	// A component follows the logger level once reset
+	log.ResetLevel("repository")
+	buf.Reset()
+	repo.Debug("query")
+	log.SetLevel("", DEBUG, 0)
+	repo.Debug("after")
+	if got := buf.String(); strings.Contains(got, "query") || !strings.Contains(got, "after") {
+		t.Errorf("output = %q, want the reset component to follow the logger level", got)
+	}
+}
+
+func TestLogger_Levels(t *testing.T) {
+	log, _ := newTestLogger(DEBUG, FormatText)
+	log.SetLevel("", INFO, 0)
+	log.SetLevel("repository", DEBUG, 0)
+	before := time.Now()
+	log.SetLevel("jobs", ERROR, time.Hour)
+
+	status := log.Levels()
+	if len(status) != 3 || status[""].Level != INFO || status["repository"].Level != DEBUG || status["jobs"].Level != ERROR {
+		t.Fatalf("Levels() = %v, want the logger and both component levels", status)
+	}
+	if !status["repository"].RevertAt.IsZero() {
+		t.Errorf("repository RevertAt = %v, want zero for a lasting level", status["repository"].RevertAt)
+	}
+	if at := status["jobs"].RevertAt; at.Before(before.Add(time.Hour)) || at.After(time.Now().Add(time.Hour)) {
+		t.Errorf("jobs RevertAt = %v, want an hour from now", at)
+	}
+
+	// Resetting cancels the timed change
+	log.ResetLevel("jobs")
+	if _, ok := log.Levels()["jobs"]; ok {
+		t.Errorf("Levels() = %v, want the reset component removed", log.Levels())
+	}
+}
+
+// waitForLevel polls the levels until the component reaches the level
+func waitForLevel(t *testing.T, log *Logger, component string, level LogLevel) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, ok := log.Levels()[component]; ok && status.Level == level && status.RevertAt.IsZero() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Levels()[%q] = %v, want %v once the change reverted", component, log.Levels()[component], level)
+}
+
+func TestLogger_TimedLevelReverts(t *testing.T) {
+	log, _ := newTestLogger(DEBUG, FormatText)
+	log.SetLevel("", WARN, 0)
+	log.SetLevel("repository", ERROR, 0)
+
+	log.SetLevel("", DEBUG, 20*time.Millisecond)
+	log.SetLevel("repository", DEBUG, 20*time.Millisecond)
+	if !log.Component("repository").Enabled(DEBUG) {
+		t.Fatal("Enabled(DEBUG) = false during the timed change, want true")
+	}
+
+	// Both revert to their previous lasting level
+	waitForLevel(t, log, "", WARN)
+	waitForLevel(t, log, "repository", ERROR)
+
+	// A component without a lasting level reverts to the logger level
+	log.SetLevel("jobs", DEBUG, 20*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for _, ok := log.Levels()["jobs"]; ok; _, ok = log.Levels()["jobs"] {
+		if time.Now().After(deadline) {
+			t.Fatalf("Levels() = %v, want jobs to follow the logger level again", log.Levels())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// A later lasting change cancels the pending revert
+	log.SetLevel("", ERROR, 20*time.Millisecond)
+	log.SetLevel("", INFO, 0)
+	time.Sleep(50 * time.Millisecond)
+	if level := log.Levels()[""].Level; level != INFO {
+		t.Errorf("logger level = %v, want INFO kept after the canceled revert", level)
+	}
+}
@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
@@ -38,13 +40,53 @@ const (
 	colorReset = "\033[0m"
 )
 
+// slogLevels maps the levels to slog's. slog has no fatal level, so FATAL
+// sits above ERROR.
+var slogLevels = map[LogLevel]slog.Level{
+	DEBUG: slog.LevelDebug,
+	INFO:  slog.LevelInfo,
+	WARN:  slog.LevelWarn,
+	ERROR: slog.LevelError,
+	FATAL: slog.LevelError + 4,
+}
+
+// Format is the output format of a Logger
+type Format string
+
+const (
+	// FormatText writes human readable lines, optionally colored
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per line for log pipelines
+	FormatJSON Format = "json"
+)
+
+// Options configure a Logger
+type Options struct {
+	// Level is the minimum level of the records, which can be changed at
+	// runtime, see Logger.SetLevel
+	Level LogLevel
+	// Sinks are the outputs of the logger, every record goes to each sink
+	// whose level it reaches. Without sinks the logger writes nothing.
+	Sinks []Sink
+	// RedactKeys are the patterns of the keys whose values are masked, see
+	// DefaultRedactKeys. Without patterns nothing is masked.
+	RedactKeys []string
+}
+
+// Logger writes leveled, structured log records. Besides a message, a
+// record carries key/value pairs given as alternating arguments, e.g.
+// Info("user created", "user_id", id).
 type Logger struct {
-	minLevel  LogLevel
-	isColored bool
-	logger    *log.Logger
+	slog   *slog.Logger
+	sinks  []Sink
+	levels *levels
 }
 
-var defaultLogger *Logger
+var defaultLogger = NewLogger(Options{
+	Level:      INFO,
+	Sinks:      []Sink{StdoutSink(DEBUG, FormatText, false)},
+	RedactKeys: DefaultRedactKeys,
+})
 
 // ParseLevel converts a string level to LogLevel
 func ParseLevel(level string) (LogLevel, error) {
@@ -64,50 +106,133 @@ func ParseLevel(level string) (LogLevel, error) {
 	}
 }
 
+// String returns the name of the level, as accepted by ParseLevel
+func (l LogLevel) String() string {
+	return levelNames[l]
+}
+
+// ParseFormat converts a string format to Format
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(format)) {
+	case FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return FormatText, fmt.Errorf("invalid log format: %s", format)
+	}
+}
+
 // InitLogger initializes the default logger
-func InitLogger(level LogLevel, useColors bool) {
-	defaultLogger = NewLogger(level, useColors)
+func InitLogger(opts Options) {
+	defaultLogger = NewLogger(opts)
+}
+
+// Close closes the sinks of the default logger, see Logger.Close
+func Close() error {
+	return defaultLogger.Close()
 }
 
-// NewLogger creates a new logger instance
-func NewLogger(level LogLevel, useColors bool) *Logger {
-	return &Logger{
-		minLevel:  level,
-		isColored: useColors,
-		logger:    log.New(os.Stdout, "", 0),
+// NewLogger creates a new logger instance writing to the sinks of opts
+func NewLogger(opts Options) *Logger {
+	handlers := make(fanoutHandler, len(opts.Sinks))
+	for i, sink := range opts.Sinks {
+		handlers[i] = newSinkHandler(sink)
+	}
+
+	var handler slog.Handler = handlers
+	if len(handlers) == 1 {
+		handler = handlers[0]
+	}
+	if redactor := newRedactor(opts.RedactKeys); redactor != nil {
+		handler = &redactHandler{next: handler, redactor: redactor}
 	}
+
+	levels := newLevels(opts.Level)
+	gate := &levelGate{next: handler, levels: levels}
+	return &Logger{slog: slog.New(gate), sinks: opts.Sinks, levels: levels}
 }
 
-func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
-	if level < l.minLevel {
-		return
+// newHandler creates the slog handler of the format
+func newHandler(out io.Writer, level LogLevel, format Format, useColors bool) slog.Handler {
+	if format == FormatJSON {
+		return slog.NewJSONHandler(out, &slog.HandlerOptions{
+			Level:       slogLevels[level],
+			ReplaceAttr: replaceLevel,
+		})
 	}
+	return newTextHandler(out, slogLevels[level], useColors)
+}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
-	levelName := levelNames[level]
-	
-	var msg string
-	if len(args) > 0 {
-		msg = fmt.Sprintf(format, args...)
-	} else {
-		msg = format
+// replaceLevel writes the level under our names, which include FATAL
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.LevelKey {
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			return slog.String(slog.LevelKey, levelNames[fromSlogLevel(level)])
+		}
 	}
+	return a
+}
 
-	if l.isColored {
-		color := levelColors[level]
-		l.logger.Printf("%s %s%5s%s %s", 
-			timestamp,
-			color,
-			levelName,
-			colorReset,
-			msg,
-		)
-	} else {
-		l.logger.Printf("%s %5s %s",
-			timestamp,
-			levelName,
-			msg,
-		)
+// fromSlogLevel returns the highest level at or below an slog level
+func fromSlogLevel(level slog.Level) LogLevel {
+	for l := FATAL; l > DEBUG; l-- {
+		if level >= slogLevels[l] {
+			return l
+		}
+	}
+	return DEBUG
+}
+
+// With returns a logger that adds the given key/value pairs to every record
+func (l *Logger) With(args ...interface{}) *Logger {
+	return &Logger{slog: l.slog.With(args...), sinks: l.sinks, levels: l.levels}
+}
+
+// Component returns a logger whose records belong to a component, e.g.
+// "repository". The level of a component can be changed on its own.
+func (l *Logger) Component(name string) *Logger {
+	return l.With(ComponentKey, name)
+}
+
+// SetLevel changes the level of a component at runtime, or the level of
+// the logger when component is empty. With a positive revertAfter the
+// previous lasting level is restored once it elapses. Loggers derived with
+// With and Component share the levels.
+func (l *Logger) SetLevel(component string, level LogLevel, revertAfter time.Duration) {
+	l.levels.set(component, level, revertAfter)
+}
+
+// ResetLevel cancels a timed level change and makes a component follow the
+// logger level again
+func (l *Logger) ResetLevel(component string) {
+	l.levels.reset(component)
+}
+
+// Levels returns the level of the logger, under the empty component, and
+// the levels of the components set apart
+func (l *Logger) Levels() map[string]LevelStatus {
+	return l.levels.status()
+}
+
+// Close closes the outputs of the sinks, like log files and syslog
+// connections. The standard streams are left open. Loggers derived with
+// With share the sinks, so they must not be used afterwards.
+func (l *Logger) Close() error {
+	return CloseSinks(l.sinks)
+}
+
+// Enabled reports whether records of the level are written
+func (l *Logger) Enabled(level LogLevel) bool {
+	return l.slog.Enabled(context.Background(), slogLevels[level])
+}
+
+func (l *Logger) log(level LogLevel, msg string, args ...interface{}) {
+	ctx := context.Background()
+	if l.slog.Enabled(ctx, slogLevels[level]) {
+		record := slog.NewRecord(time.Now(), slogLevels[level], msg, 0)
+		record.Add(args...)
+		l.slog.Handler().Handle(ctx, record)
 	}
 
 	if level == FATAL {
@@ -115,23 +240,88 @@ func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
 	}
 }
 
-// Logger interface methods
-func Debug(format string, args ...interface{}) {
-	defaultLogger.log(DEBUG, format, args...)
+// logf formats the message printf style. Without arguments the format is
+// the message, so it may contain a literal %.
+func (l *Logger) logf(level LogLevel, format string, args ...interface{}) {
+	msg := format
+	if len(args) > 0 && l.Enabled(level) {
+		msg = fmt.Sprintf(format, args...)
+	}
+	l.log(level, msg)
 }
 
-func Info(format string, args ...interface{}) {
-	defaultLogger.log(INFO, format, args...)
+// Debug logs a message with key/value pairs at DEBUG level
+func (l *Logger) Debug(msg string, args ...interface{}) { l.log(DEBUG, msg, args...) }
+
+// Info logs a message with key/value pairs at INFO level
+func (l *Logger) Info(msg string, args ...interface{}) { l.log(INFO, msg, args...) }
+
+// Warn logs a message with key/value pairs at WARN level
+func (l *Logger) Warn(msg string, args ...interface{}) { l.log(WARN, msg, args...) }
+
+// Error logs a message with key/value pairs at ERROR level
+func (l *Logger) Error(msg string, args ...interface{}) { l.log(ERROR, msg, args...) }
+
+// Fatal logs a message with key/value pairs at FATAL level and exits
+func (l *Logger) Fatal(msg string, args ...interface{}) { l.log(FATAL, msg, args...) }
+
+// Debugf logs a printf style message at DEBUG level
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(DEBUG, format, args...) }
+
+// Infof logs a printf style message at INFO level
+func (l *Logger) Infof(format string, args ...interface{}) { l.logf(INFO, format, args...) }
+
+// Warnf logs a printf style message at WARN level
+func (l *Logger) Warnf(format string, args ...interface{}) { l.logf(WARN, format, args...) }
+
+// Errorf logs a printf style message at ERROR level
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(ERROR, format, args...) }
+
+// Fatalf logs a printf style message at FATAL level and exits
+func (l *Logger) Fatalf(format string, args ...interface{}) { l.logf(FATAL, format, args...) }
+
+// Default returns the default logger
+func Default() *Logger {
+	return defaultLogger
+}
+
+// Logger interface methods, logging to the default logger
+func Debug(msg string, args ...interface{}) {
+	defaultLogger.log(DEBUG, msg, args...)
+}
+
+func Info(msg string, args ...interface{}) {
+	defaultLogger.log(INFO, msg, args...)
+}
+
+func Warn(msg string, args ...interface{}) {
+	defaultLogger.log(WARN, msg, args...)
+}
+
+func Error(msg string, args ...interface{}) {
+	defaultLogger.log(ERROR, msg, args...)
+}
+
+func Fatal(msg string, args ...interface{}) {
+	defaultLogger.log(FATAL, msg, args...)
+}
+
+func Debugf(format string, args ...interface{}) {
+	defaultLogger.logf(DEBUG, format, args...)
+}
+
+func Infof(format string, args ...interface{}) {
+	defaultLogger.logf(INFO, format, args...)
 }
 
-func Warn(format string, args ...interface{}) {
-	defaultLogger.log(WARN, format, args...)
+func Warnf(format string, args ...interface{}) {
+	defaultLogger.logf(WARN, format, args...)
 }
 
-func Error(format string, args ...interface{}) {
-	defaultLogger.log(ERROR, format, args...)
+func Errorf(format string, args ...interface{}) {
+	defaultLogger.logf(ERROR, format, args...)
 }
 
-func Fatal(format string, args ...interface{}) {
-	defaultLogger.log(FATAL, format, args...)
-} 
\ No newline at end of file
+func Fatalf(format string, args ...interface{}) {
+	defaultLogger.logf(FATAL, format, args...)
+}
@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// newTestLogger returns a logger with a single sink writing to a buffer
+func newTestLogger(level LogLevel, format Format) (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return NewLogger(Options{Sinks: []Sink{{Out: &buf, Level: level, Format: format}}}), &buf
+}
+
+func TestLogger_JSON(t *testing.T) {
+	log, buf := newTestLogger(INFO, FormatJSON)
+
+	log.Debug("not written")
+	log.With("request_id", "abc").Warn("user created", "user_id", 42, "role", "admin")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("output %q is not one JSON record: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{"level": "WARN", "msg": "user created", "request_id": "abc", "user_id": float64(42), "role": "admin"}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("record[%q] = %v, want %v", key, record[key], value)
+		}
+	}
+	if _, ok := record["time"]; !ok {
+		t.Error("record has no time")
+	}
+}
+
+func TestLogger_Text(t *testing.T) {
+	log, buf := newTestLogger(DEBUG, FormatText)
+
+	log.Info("user created", "user_id", 42, "name", "Jane Doe", "note", "")
+	log.With(slog.Group("http", "method", "GET")).Debug("request", "path", "/users")
+	log.Errorf("%d%% done in %s", 100, "1s")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{
+		` INFO user created user_id=42 name="Jane Doe" note=""`,
+		`DEBUG request http.method=GET path=/users`,
+		`ERROR 100% done in 1s`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, line := range lines {
+		// Skip the timestamp, "2006-01-02 15:04:05.000 "
+		if got := line[len("2006-01-02 15:04:05.000 "):]; got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]LogLevel{"debug": DEBUG, "INFO": INFO, "Warn": WARN, "error": ERROR, "FATAL": FATAL} {
+		if got, err := ParseLevel(input); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) error = nil, want an error")
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml) error = nil, want an error")
+	}
+}
@@ -0,0 +1,218 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactedValue replaces the values masked in the logs
+const RedactedValue = "[REDACTED]"
+
+// DefaultRedactKeys are the key patterns masked unless configured otherwise
+var DefaultRedactKeys = []string{"email", "password", "authorization", "token"}
+
+// redactor masks personal data and secrets before records are written. A
+// key matches when it contains one of the patterns, ignoring case. The
+// values of matching keys are masked in the fields, in the structs and maps
+// logged as fields, and in query strings such as ?email=a@example.com.
+type redactor struct {
+	patterns []string
+	// query matches the key=value pairs of query strings with a matching key
+	query *regexp.Regexp
+}
+
+// newRedactor returns nil without patterns, nothing being masked then
+func newRedactor(patterns []string) *redactor {
+	r := &redactor{}
+	var quoted []string
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			r.patterns = append(r.patterns, pattern)
+			quoted = append(quoted, regexp.QuoteMeta(pattern))
+		}
+	}
+	if len(r.patterns) == 0 {
+		return nil
+	}
+
+	key := `[^\s=&;?#]*(?:` + strings.Join(quoted, "|") + `)[^\s=&;?#]*`
+	r.query = regexp.MustCompile(`(?i)((?:^|[\s?&;])` + key + `=)[^\s&;#]*`)
+	return r
+}
+
+// matches reports whether the values of key are masked
+func (r *redactor) matches(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.patterns {
+		if strings.Contains(key, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactString masks the values of the matching keys of query strings in s
+func (r *redactor) redactString(s string) string {
+	if !strings.Contains(s, "=") {
+		return s
+	}
+	return r.query.ReplaceAllString(s, "${1}"+RedactedValue)
+}
+
+func (r *redactor) attr(a slog.Attr) slog.Attr {
+	if r.matches(a.Key) {
+		return slog.String(a.Key, RedactedValue)
+	}
+	a.Value = r.value(a.Value)
+	return a
+}
+
+func (r *redactor) value(v slog.Value) slog.Value {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.StringValue(r.redactString(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		attrs := make([]slog.Attr, len(group))
+		for i, a := range group {
+			attrs[i] = r.attr(a)
+		}
+		return slog.GroupValue(attrs...)
+	case slog.KindAny:
+		return r.any(v.Any())
+	default:
+		return v
+	}
+}
+
+// any masks the values logged as is. Errors and Stringers are masked in
+// their text, structs and maps are converted to groups of their JSON fields.
+func (r *redactor) any(value interface{}) slog.Value {
+	var text string
+	switch x := value.(type) {
+	case error:
+		text = x.Error()
+	case fmt.Stringer:
+		text = x.String()
+	default:
+		return r.composite(value)
+	}
+
+	if redacted := r.redactString(text); redacted != text {
+		return slog.StringValue(redacted)
+	}
+	return slog.AnyValue(value)
+}
+
+func (r *redactor) composite(value interface{}) slog.Value {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+	default:
+		return slog.AnyValue(value)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return slog.AnyValue(value)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return slog.AnyValue(value)
+	}
+
+	if object, ok := decoded.(map[string]interface{}); ok {
+		return r.group(object)
+	}
+	return slog.AnyValue(r.decoded(decoded))
+}
+
+// group converts a JSON object to a group of its fields, by key order
+func (r *redactor) group(object map[string]interface{}) slog.Value {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, len(keys))
+	for i, key := range keys {
+		if r.matches(key) {
+			attrs[i] = slog.String(key, RedactedValue)
+		} else if field, ok := object[key].(map[string]interface{}); ok {
+			attrs[i] = slog.Attr{Key: key, Value: r.group(field)}
+		} else {
+			attrs[i] = slog.Any(key, r.decoded(object[key]))
+		}
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// decoded masks a decoded JSON value that is not turned into a group
+func (r *redactor) decoded(value interface{}) interface{} {
+	switch x := value.(type) {
+	case map[string]interface{}:
+		for key, field := range x {
+			if r.matches(key) {
+				x[key] = RedactedValue
+			} else {
+				x[key] = r.decoded(field)
+			}
+		}
+		return x
+	case []interface{}:
+		for i, item := range x {
+			x[i] = r.decoded(item)
+		}
+		return x
+	case string:
+		return r.redactString(x)
+	default:
+		return x
+	}
+}
+
+// redactHandler masks the records before they reach the sinks
+type redactHandler struct {
+	next     slog.Handler
+	redactor *redactor
+}
+
+// Enabled implements slog.Handler
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, h.redactor.redactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(h.redactor.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs implements slog.Handler
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = h.redactor.attr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(redacted), redactor: h.redactor}
+}
+
+// WithGroup implements slog.Handler
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
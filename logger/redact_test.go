@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type testProfile struct {
+	Email string `json:"email"`
+	City  string `json:"city"`
+}
+
+type testUser struct {
+	ID       int          `json:"id"`
+	Name     string       `json:"name"`
+	Email    string       `json:"email"`
+	Password string       `json:"password,omitempty"`
+	Profile  *testProfile `json:"profile,omitempty"`
+}
+
+func TestLogger_Redact(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(log *Logger)
+		// want are fragments of the JSON record, secret must not appear in it
+		want   []string
+		secret string
+	}{
+		{
+			name:   "top level keys ignoring case",
+			log:    func(log *Logger) { log.Info("login", "Email", "jane@example.com", "user_id", 7) },
+			want:   []string{`"Email":"[REDACTED]"`, `"user_id":7`},
+			secret: "jane@example.com",
+		},
+		{
+			name:   "keys containing a pattern",
+			log:    func(log *Logger) { log.Info("refreshed", "refresh_token", "abc123", "Authorization", "Bearer abc123") },
+			want:   []string{`"refresh_token":"[REDACTED]"`, `"Authorization":"[REDACTED]"`},
+			secret: "abc123",
+		},
+		{
+			name: "keys in groups",
+			log: func(log *Logger) {
+				log.Info("created", slog.Group("user", "email", "jane@example.com", "name", "Jane"))
+			},
+			want:   []string{`"user":{"email":"[REDACTED]","name":"Jane"}`},
+			secret: "jane@example.com",
+		},
+		{
+			name:   "fields added by With",
+			log:    func(log *Logger) { log.With("password", "hunter2").Info("changed") },
+			want:   []string{`"password":"[REDACTED]"`},
+			secret: "hunter2",
+		},
+		{
+			name: "structs logged as fields",
+			log: func(log *Logger) {
+				log.Info("created", "user", &testUser{ID: 1, Name: "Jane", Email: "jane@example.com", Password: "hunter2",
+					Profile: &testProfile{Email: "jane@example.com", City: "Oslo"}})
+			},
+			want: []string{
+				`"user":{"email":"[REDACTED]","id":1,"name":"Jane","password":"[REDACTED]","profile":{"city":"Oslo","email":"[REDACTED]"}}`,
+			},
+			secret: "jane@example.com",
+		},
+		{
+			name:   "maps and slices logged as fields",
+			log:    func(log *Logger) { log.Info("imported", "users", []testUser{{ID: 1, Email: "jane@example.com"}}) },
+			want:   []string{`"users":[{"email":"[REDACTED]","id":1,"name":""}]`},
+			secret: "jane@example.com",
+		},
+		{
+			name:   "query string in the message",
+			log:    func(log *Logger) { log.Info("GET /users?email=jane@example.com&limit=10") },
+			want:   []string{`"msg":"GET /users?email=[REDACTED]&limit=10"`},
+			secret: "jane@example.com",
+		},
+		{
+			name:   "query string in a value",
+			log:    func(log *Logger) { log.Info("request", "query", "limit=10&access_token=abc123;sort=name") },
+			want:   []string{`"query":"limit=10&access_token=[REDACTED];sort=name"`},
+			secret: "abc123",
+		},
+		{
+			name:   "query string in an error",
+			log:    func(log *Logger) { log.Error("failed", "error", errors.New("GET /login?password=hunter2 timed out")) },
+			want:   []string{`"error":"GET /login?password=[REDACTED] timed out"`},
+			secret: "hunter2",
+		},
+		{
+			name: "other values kept",
+			log: func(log *Logger) {
+				log.Info("listed", "filter", "name=Jane", "count", 2, "error", errors.New("not found"))
+			},
+			want:   []string{`"filter":"name=Jane"`, `"count":2`, `"error":"not found"`},
+			secret: "[REDACTED]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			log := NewLogger(Options{Sinks: []Sink{{Out: &buf, Format: FormatJSON}}, RedactKeys: DefaultRedactKeys})
+
+			tt.log(log)
+
+			got := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("record = %s, want %s", got, want)
+				}
+			}
+			if strings.Contains(got, tt.secret) {
+				t.Errorf("record = %s, want %q masked", got, tt.secret)
+			}
+		})
+	}
+}
+
+func TestLogger_RedactKeys(t *testing.T) {
+	var buf strings.Builder
+	log := NewLogger(Options{Sinks: []Sink{{Out: &buf, Format: FormatText}}, RedactKeys: []string{" SSN ", ""}})
+	log.Info("checked", "customer_ssn", "123-45-6789", "email", "jane@example.com")
+	if got := buf.String(); !strings.Contains(got, "customer_ssn=[REDACTED] email=jane@example.com") {
+		t.Errorf("record = %q, want only the configured keys masked", got)
+	}
+
+	buf.Reset()
+	log = NewLogger(Options{Sinks: []Sink{{Out: &buf, Format: FormatText}}})
+	log.Info("login", "password", "hunter2")
+	if got := buf.String(); !strings.Contains(got, "password=hunter2") {
+		t.Errorf("record = %q, want nothing masked without patterns", got)
+	}
+}
@@ -0,0 +1,276 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat stamps rotated files with their rotation time, in UTC
+const backupTimeFormat = "20060102T150405.000"
+
+// RotateOptions configure a RotatingFile. Zero values disable the limit.
+type RotateOptions struct {
+	// MaxSize rotates the file before a write makes it larger, in bytes
+	MaxSize int64
+	// Interval rotates the file at multiples of it since the epoch in UTC,
+	// so 24h rotates at midnight UTC
+	Interval time.Duration
+	// MaxBackups is the number of rotated files kept
+	MaxBackups int
+	// MaxAge removes rotated files older than it
+	MaxAge time.Duration
+	// Compress gzips rotated files
+	Compress bool
+}
+
+// RotatingFile is a log file rotated by size and time. Rotated files are
+// renamed after their rotation time, e.g. goapi-20240101T000000.000.log,
+// then compressed and removed past the retention limits in the background.
+type RotatingFile struct {
+	path string
+	opts RotateOptions
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	// rotateAt is when the file rotates because of the interval
+	rotateAt time.Time
+	// now returns the current time, tests replace it
+	now func() time.Time
+
+	// mill serializes compressing and removing rotated files
+	mill sync.Mutex
+	wg   sync.WaitGroup
+}
+
+// OpenRotatingFile opens, or creates, the log file at path. Its directory
+// is created when missing.
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	return openRotatingFile(path, opts, time.Now)
+}
+
+func openRotatingFile(path string, opts RotateOptions, now func() time.Time) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	f := &RotatingFile{path: path, opts: opts, now: now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write implements io.Writer, rotating the file first when needed
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	// A file that failed to rotate keeps being written to
+	if f.size > 0 && f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return 0, err
+			}
+			fmt.Fprintf(os.Stderr, "logger: failed to rotate %s: %v\n", f.path, err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file, after waiting for the rotated files being
+// compressed or removed
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	f.wg.Wait()
+	return err
+}
+
+// open opens the file for appending. An existing file is considered
+// written since its last modification for the interval.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	openedAt := f.now()
+	if f.size > 0 {
+		openedAt = info.ModTime()
+	}
+	if f.opts.Interval > 0 {
+		f.rotateAt = openedAt.UTC().Truncate(f.opts.Interval).Add(f.opts.Interval)
+	}
+	return nil
+}
+
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.opts.MaxSize > 0 && f.size+n > f.opts.MaxSize {
+		return true
+	}
+	return f.opts.Interval > 0 && !f.now().Before(f.rotateAt)
+}
+
+// rotate renames the file after the current time and opens a new one.
+// When the rename fails, the file is opened again. f.mu must be held.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	renameErr := os.Rename(f.path, f.backupName(f.now()))
+	if err := f.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		if err := f.millBackups(); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: failed to clean up rotated log files: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// backupName returns the name of the file rotated at t
+func (f *RotatingFile) backupName(t time.Time) string {
+	prefix, ext := f.nameParts()
+	return prefix + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// nameParts splits the path around the place of the rotation time
+func (f *RotatingFile) nameParts() (prefix, ext string) {
+	ext = filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-", ext
+}
+
+// backup is a rotated file
+type backup struct {
+	path      string
+	rotatedAt time.Time
+}
+
+// backups lists the rotated files, the most recent first
+func (f *RotatingFile) backups() ([]backup, error) {
+	prefix, ext := f.nameParts()
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []backup
+	for _, entry := range entries {
+		path := filepath.Join(filepath.Dir(f.path), entry.Name())
+		stamp, ok := strings.CutPrefix(path, prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(strings.TrimSuffix(stamp, ".gz"), ext)
+		if !ok {
+			continue
+		}
+		rotatedAt, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: path, rotatedAt: rotatedAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotatedAt.After(backups[j].rotatedAt)
+	})
+	return backups, nil
+}
+
+// millBackups removes the rotated files past the retention limits and
+// compresses the others
+func (f *RotatingFile) millBackups() error {
+	f.mill.Lock()
+	defer f.mill.Unlock()
+
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, b := range backups {
+		expired := f.opts.MaxAge > 0 && f.now().Sub(b.rotatedAt) > f.opts.MaxAge
+		if (f.opts.MaxBackups > 0 && i >= f.opts.MaxBackups) || expired {
+			errs = append(errs, os.Remove(b.path))
+			continue
+		}
+		if f.opts.Compress && !strings.HasSuffix(b.path, ".gz") {
+			errs = append(errs, compressFile(b.path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compressFile gzips a file next to it and removes the original
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,214 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock the test moves forward
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// openTestFile opens app.log in a temporary directory on a clock starting at
+// 2024-01-01 10:30 UTC
+func openTestFile(t *testing.T, opts RotateOptions) (*RotatingFile, *fakeClock, string) {
+	dir := t.TempDir()
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)}
+	f, err := openRotatingFile(filepath.Join(dir, "app.log"), opts, clock.now)
+	if err != nil {
+		t.Fatalf("openRotatingFile() error = %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f, clock, dir
+}
+
+func write(t *testing.T, f *RotatingFile, line string) {
+	t.Helper()
+	if _, err := f.Write([]byte(line)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+}
+
+// files returns the names of the files in dir
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile_MaxSize(t *testing.T) {
+	f, clock, dir := openTestFile(t, RotateOptions{MaxSize: 10})
+
+	write(t, f, "one\n")
+	write(t, f, "more\n")
+	// The next write would make the file larger than 10 bytes
+	clock.advance(time.Second)
+	write(t, f, "second\n")
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := []string{"app-20240101T103001.000.log", "app.log"}
+	if got := files(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("files = %q, want %q", got, want)
+	}
+	if got := readFile(t, filepath.Join(dir, want[0])); got != "one\nmore\n" {
+		t.Errorf("rotated file = %q, want the writes before the rotation", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "app.log")); got != "second\n" {
+		t.Errorf("current file = %q, want the write after the rotation", got)
+	}
+}
+
+func TestRotatingFile_LargeWriteIsNotSplit(t *testing.T) {
+	f, _, dir := openTestFile(t, RotateOptions{MaxSize: 4})
+
+	// A record larger than the limit still goes to one file, and an empty
+	// file is never rotated
+	write(t, f, "longer than the limit\n")
+	f.Close()
+
+	if got := files(t, dir); !slices.Equal(got, []string{"app.log"}) {
+		t.Errorf("files = %q, want only app.log", got)
+	}
+}
+
+func TestRotatingFile_Interval(t *testing.T) {
+	f, clock, dir := openTestFile(t, RotateOptions{Interval: time.Hour})
+
+	write(t, f, "at 10:30\n")
+	clock.advance(29 * time.Minute)
+	write(t, f, "at 10:59\n")
+	clock.advance(time.Minute)
+	write(t, f, "at 11:00\n")
+	clock.advance(30 * time.Minute)
+	write(t, f, "at 11:30\n")
+	f.Close()
+
+	want := []string{"app-20240101T110000.000.log", "app.log"}
+	if got := files(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("files = %q, want %q", got, want)
+	}
+	if got := readFile(t, filepath.Join(dir, want[0])); got != "at 10:30\nat 10:59\n" {
+		t.Errorf("rotated file = %q, want the hour before 11:00", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "app.log")); got != "at 11:00\nat 11:30\n" {
+		t.Errorf("current file = %q, want the hour from 11:00", got)
+	}
+}
+
+func TestRotatingFile_MaxBackupsAndCompress(t *testing.T) {
+	f, clock, dir := openTestFile(t, RotateOptions{MaxSize: 1, MaxBackups: 2, Compress: true})
+
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		write(t, f, line)
+		clock.advance(time.Second)
+	}
+	// Close waits for the rotated files to be compressed and removed
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := []string{"app-20240101T103002.000.log.gz", "app-20240101T103003.000.log.gz", "app.log"}
+	if got := files(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("files = %q, want the two most recent backups compressed: %q", got, want)
+	}
+
+	gz, err := os.Open(filepath.Join(dir, want[1]))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer gz.Close()
+	r, err := gzip.NewReader(gz)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "three\n" {
+		t.Errorf("compressed backup = %q, %v, want %q", data, err, "three\n")
+	}
+}
+
+func TestRotatingFile_MaxAge(t *testing.T) {
+	f, clock, dir := openTestFile(t, RotateOptions{MaxSize: 1, MaxAge: 24 * time.Hour})
+
+	// Backups left by earlier runs, and files that are not backups
+	for _, name := range []string{
+		"app-20231230T100000.000.log",
+		"app-20231231T120000.000.log.gz",
+		"app-notatime.log",
+		"other-20231230T100000.000.log",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("old\n"), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	write(t, f, "one\n")
+	clock.advance(time.Second)
+	write(t, f, "two\n")
+	f.Close()
+
+	want := []string{"app-20231231T120000.000.log.gz", "app-20240101T103001.000.log", "app-notatime.log", "app.log", "other-20231230T100000.000.log"}
+	if got := files(t, dir); !slices.Equal(got, want) {
+		t.Errorf("files = %q, want only the backup older than a day removed: %q", got, want)
+	}
+}
+
+func TestRotatingFile_AppendsToExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "app.log")
+
+	for _, line := range []string{"first run\n", "second run\n"} {
+		f, err := OpenRotatingFile(path, RotateOptions{})
+		if err != nil {
+			t.Fatalf("OpenRotatingFile() error = %v", err)
+		}
+		write(t, f, line)
+		f.Close()
+	}
+
+	if got := readFile(t, path); got != "first run\nsecond run\n" {
+		t.Errorf("file = %q, want both runs appended", got)
+	}
+	if _, err := (&RotatingFile{}).Write([]byte("x")); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("Write() on a closed file error = %v, want os.ErrClosed", err)
+	}
+}
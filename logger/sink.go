@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Sink is an output of a Logger. Each sink formats and filters the records
+// on its own, so the same record can go as text to stdout and as JSON to a
+// file, while only errors reach syslog.
+type Sink struct {
+	// Out receives the formatted records, one write per record. Outputs
+	// implementing LevelWriter also receive the level of each record.
+	Out io.Writer
+	// Level is the minimum level of the records written to Out
+	Level  LogLevel
+	Format Format
+	// UseColors colors the level in the text format
+	UseColors bool
+}
+
+// LevelWriter is implemented by outputs that keep the level of each record
+// themselves, such as syslog with its severities
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level LogLevel, p []byte) (int, error)
+}
+
+// StdoutSink returns a sink writing to stdout
+func StdoutSink(level LogLevel, format Format, useColors bool) Sink {
+	return Sink{Out: os.Stdout, Level: level, Format: format, UseColors: useColors}
+}
+
+// newSinkHandler creates the slog handler of a sink. Records for a
+// LevelWriter go through one handler per level, each writing at its level.
+func newSinkHandler(sink Sink) slog.Handler {
+	lw, ok := sink.Out.(LevelWriter)
+	if !ok {
+		return newHandler(sink.Out, sink.Level, sink.Format, sink.UseColors)
+	}
+
+	handler := make(levelHandler, len(levelNames))
+	for level := range levelNames {
+		handler[level] = newHandler(fixedLevelWriter{out: lw, level: level}, sink.Level, sink.Format, sink.UseColors)
+	}
+	return handler
+}
+
+// CloseSinks closes the outputs of the sinks that can be closed, except the
+// standard streams
+func CloseSinks(sinks []Sink) error {
+	var errs []error
+	for _, sink := range sinks {
+		if sink.Out == os.Stdout || sink.Out == os.Stderr {
+			continue
+		}
+		if closer, ok := sink.Out.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fixedLevelWriter writes to a LevelWriter at a fixed level
+type fixedLevelWriter struct {
+	out   LevelWriter
+	level LogLevel
+}
+
+func (w fixedLevelWriter) Write(p []byte) (int, error) {
+	return w.out.WriteLevel(w.level, p)
+}
+
+// levelHandler hands each record to the handler of its level
+type levelHandler map[LogLevel]slog.Handler
+
+// Enabled implements slog.Handler
+func (h levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h[fromSlogLevel(level)].Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h[fromSlogLevel(r.Level)].Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := make(levelHandler, len(h))
+	for level, handler := range h {
+		clone[level] = handler.WithAttrs(attrs)
+	}
+	return clone
+}
+
+// WithGroup implements slog.Handler
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	clone := make(levelHandler, len(h))
+	for level, handler := range h {
+		clone[level] = handler.WithGroup(name)
+	}
+	return clone
+}
+
+// fanoutHandler hands each record to every handler enabled for its level
+type fanoutHandler []slog.Handler
+
+// Enabled implements slog.Handler
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements slog.Handler. A failing sink does not keep the record
+// from the others.
+func (h fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		clone[i] = handler.WithAttrs(attrs)
+	}
+	return clone
+}
+
+// WithGroup implements slog.Handler
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	clone := make(fanoutHandler, len(h))
+	for i, handler := range h {
+		clone[i] = handler.WithGroup(name)
+	}
+	return clone
+}
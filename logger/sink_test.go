@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// levelRecorder is a LevelWriter keeping the level of each write
+type levelRecorder struct {
+	levels []LogLevel
+	lines  []string
+	closed bool
+}
+
+func (r *levelRecorder) Write(p []byte) (int, error) { return r.WriteLevel(INFO, p) }
+
+func (r *levelRecorder) WriteLevel(level LogLevel, p []byte) (int, error) {
+	r.levels = append(r.levels, level)
+	r.lines = append(r.lines, string(p))
+	return len(p), nil
+}
+
+func (r *levelRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestNewLogger_Sinks(t *testing.T) {
+	var text, jsonOut bytes.Buffer
+	log := NewLogger(Options{Sinks: []Sink{
+		{Out: &text, Level: DEBUG, Format: FormatText},
+		{Out: &jsonOut, Level: WARN, Format: FormatJSON},
+	}})
+
+	log.With("request_id", "abc").Debug("cache miss")
+	log.Warn("slow query", "duration_ms", 1200)
+
+	if got := strings.Count(text.String(), "\n"); got != 2 {
+		t.Errorf("text sink got %d records, want both:\n%s", got, text.String())
+	}
+	if !strings.Contains(text.String(), "cache miss request_id=abc") {
+		t.Errorf("text sink = %q, want the fields added by With", text.String())
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(jsonOut.Bytes(), &record); err != nil {
+		t.Fatalf("JSON sink = %q, want the WARN record only: %v", jsonOut.String(), err)
+	}
+	if record["msg"] != "slow query" || record["level"] != "WARN" {
+		t.Errorf("JSON sink record = %v, want the WARN record", record)
+	}
+
+	if !log.Enabled(DEBUG) {
+		t.Error("Enabled(DEBUG) = false, want true while a sink takes DEBUG")
+	}
+	if NewLogger(Options{}).Enabled(FATAL) {
+		t.Error("Enabled() of a logger without sinks = true, want false")
+	}
+}
+
+func TestNewLogger_LevelWriter(t *testing.T) {
+	recorder := &levelRecorder{}
+	log := NewLogger(Options{Sinks: []Sink{{Out: recorder, Level: INFO, Format: FormatText}}})
+
+	log.Debug("dropped")
+	log.With("job", "retention").Info("started")
+	log.Error("failed")
+
+	want := []LogLevel{INFO, ERROR}
+	if len(recorder.levels) != len(want) || recorder.levels[0] != want[0] || recorder.levels[1] != want[1] {
+		t.Fatalf("levels = %v, want %v", recorder.levels, want)
+	}
+	if !strings.Contains(recorder.lines[0], "started job=retention") {
+		t.Errorf("record = %q, want the fields added by With", recorder.lines[0])
+	}
+}
+
+func TestCloseSinks(t *testing.T) {
+	recorder := &levelRecorder{}
+	if err := CloseSinks([]Sink{StdoutSink(INFO, FormatText, false), {Out: recorder}, {Out: &bytes.Buffer{}}}); err != nil {
+		t.Fatalf("CloseSinks() error = %v", err)
+	}
+	if !recorder.closed {
+		t.Error("CloseSinks() did not close the sink output")
+	}
+	if _, err := os.Stdout.Stat(); err != nil {
+		t.Errorf("CloseSinks() closed stdout: %v", err)
+	}
+}
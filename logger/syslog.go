@@ -0,0 +1,57 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"bytes"
+	"log/syslog"
+)
+
+// SyslogWriter writes records to the local syslog daemon, with the
+// severity of their level
+type SyslogWriter struct {
+	w *syslog.Writer
+}
+
+// DialSyslog connects to the local syslog socket, tagging the messages with
+// tag. Messages are logged with the user facility.
+func DialSyslog(tag string) (*SyslogWriter, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogWriter{w: w}, nil
+}
+
+// Write implements io.Writer, at INFO level
+func (s *SyslogWriter) Write(p []byte) (int, error) {
+	return s.WriteLevel(INFO, p)
+}
+
+// WriteLevel implements LevelWriter
+func (s *SyslogWriter) WriteLevel(level LogLevel, p []byte) (int, error) {
+	msg := string(bytes.TrimSuffix(p, []byte("\n")))
+
+	var err error
+	switch level {
+	case DEBUG:
+		err = s.w.Debug(msg)
+	case INFO:
+		err = s.w.Info(msg)
+	case WARN:
+		err = s.w.Warning(msg)
+	case ERROR:
+		err = s.w.Err(msg)
+	default:
+		err = s.w.Crit(msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to syslog
+func (s *SyslogWriter) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,29 @@
+//go:build windows || plan9
+
+package logger
+
+import "errors"
+
+// SyslogWriter writes records to the local syslog daemon. There is none on
+// this platform.
+type SyslogWriter struct{}
+
+// DialSyslog fails, as syslog is not supported on this platform
+func DialSyslog(tag string) (*SyslogWriter, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
+
+// Write implements io.Writer
+func (s *SyslogWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("syslog is not supported on this platform")
+}
+
+// WriteLevel implements LevelWriter
+func (s *SyslogWriter) WriteLevel(level LogLevel, p []byte) (int, error) {
+	return s.Write(p)
+}
+
+// Close implements io.Closer
+func (s *SyslogWriter) Close() error {
+	return nil
+}
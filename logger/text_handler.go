@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// textHandler is the slog handler of the text format. It writes the time,
+// the level and the message, followed by the key/value pairs as key=value:
+//
+//	2024-01-01 12:00:00.000  INFO user created user_id=42
+type textHandler struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  slog.Leveler
+	colors bool
+
+	// attrs are the pairs added by With, already formatted
+	attrs []byte
+	// group prefixes the keys of the pairs added after WithGroup
+	group string
+}
+
+func newTextHandler(out io.Writer, level slog.Leveler, colors bool) *textHandler {
+	return &textHandler{mu: &sync.Mutex{}, out: out, level: level, colors: colors}
+}
+
+// Enabled implements slog.Handler
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256)
+	buf = r.Time.AppendFormat(buf, "2006-01-02 15:04:05.000")
+
+	level := fromSlogLevel(r.Level)
+	if h.colors {
+		buf = fmt.Appendf(buf, " %s%5s%s ", levelColors[level], levelNames[level], colorReset)
+	} else {
+		buf = fmt.Appendf(buf, " %5s ", levelNames[level])
+	}
+	buf = append(buf, r.Message...)
+
+	buf = append(buf, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = appendAttr(buf, h.group, a)
+		return true
+	})
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(buf)
+	return err
+}
+
+// WithAttrs implements slog.Handler
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]byte(nil), h.attrs...)
+	for _, a := range attrs {
+		clone.attrs = appendAttr(clone.attrs, h.group, a)
+	}
+	return &clone
+}
+
+// WithGroup implements slog.Handler
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = h.group + name + "."
+	return &clone
+}
+
+// appendAttr appends " key=value", flattening groups into dotted keys
+func appendAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, member := range a.Value.Group() {
+			buf = appendAttr(buf, prefix, member)
+		}
+		return buf
+	}
+
+	buf = append(buf, ' ')
+	buf = append(buf, prefix...)
+	buf = append(buf, a.Key...)
+	buf = append(buf, '=')
+
+	var value string
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		value = a.Value.Time().Format(time.RFC3339Nano)
+	default:
+		value = a.Value.String()
+	}
+	if needsQuoting(value) {
+		return strconv.AppendQuote(buf, value)
+	}
+	return append(buf, value...)
+}
+
+// needsQuoting reports whether a value must be quoted to be read back
+// unambiguously
+func needsQuoting(value string) bool {
+	if value == "" {
+		return true
+	}
+	return strings.IndexFunc(value, func(r rune) bool {
+		return r == '"' || r == '=' || unicode.IsSpace(r) || !unicode.IsPrint(r)
+	}) >= 0
+}
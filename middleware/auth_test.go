@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"goapi/config"
+	"goapi/logger"
+	"goapi/models"
+	"goapi/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testAuthConfig = &config.AuthConfig{
+	Algorithm: config.HS256,
+	Secret:    "test-secret",
+	Issuer:    "goapi-test",
+	Audience:  "goapi-test-clients",
+}
+
+// testAPIKey is the only key fakeAPIKeyService accepts
+const testAPIKey = "gak_0123456789ab_secret"
+
+// fakeAPIKeyService authenticates testAPIKey as user 7 limited to users:read
+type fakeAPIKeyService struct {
+	services.APIKeyService
+}
+
+func (fakeAPIKeyService) Authenticate(ctx context.Context, key string) (*models.Principal, error) {
+	if key != testAPIKey {
+		return nil, models.ErrInvalidAPIKey
+	}
+	return &models.Principal{UserID: 7, Role: models.RoleUser, APIKeyID: 1, Scopes: []models.Permission{models.PermUsersRead}}, nil
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	logger.InitLogger(logger.Options{})
+}
+
+func signTestToken(t *testing.T, secret string, claims jwt.Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func validTestClaims() jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Subject:   "42",
+		Issuer:    testAuthConfig.Issuer,
+		Audience:  jwt.ClaimStrings{testAuthConfig.Audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+// serveAuth runs one request through AuthMiddleware and a handler that
+// echoes the authenticated user id
+func serveAuth(t *testing.T, authHeader string) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.New()
+	router.GET("/", AuthMiddleware(testAuthConfig, fakeAPIKeyService{}), func(c *gin.Context) {
+		principal, ok := models.PrincipalFromContext(c.Request.Context())
+		if !ok || principal.UserID != c.GetInt64(UserIDKey) {
+			t.Errorf("principal %+v does not match user id %d", principal, c.GetInt64(UserIDKey))
+		}
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetInt64(UserIDKey)})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthMiddlewareAcceptsValidToken(t *testing.T) {
+	rec := serveAuth(t, "Bearer "+signTestToken(t, testAuthConfig.Secret, validTestClaims()))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var body struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body.UserID != 42 {
+		t.Errorf("user_id = %d, want 42", body.UserID)
+	}
+}
+
+func TestAuthMiddlewareAcceptsAPIKey(t *testing.T) {
+	rec := serveAuth(t, "ApiKey "+testAPIKey)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `"user_id":7`) {
+		t.Errorf("body = %s, want the key owner", rec.Body)
+	}
+}
+
+func TestAuthMiddlewareRejectsInvalidAPIKey(t *testing.T) {
+	rec := serveAuth(t, "ApiKey gak_0123456789ab_wrong")
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+	if got := rec.Header().Get("WWW-Authenticate"); got != "ApiKey" {
+		t.Errorf("WWW-Authenticate = %q, want %q", got, "ApiKey")
+	}
+}
+
+func TestAuthMiddlewareRejectsMissingCredentials(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing header", ""},
+		{"basic scheme", "Basic dXNlcjpwYXNz"},
+		{"empty bearer", "Bearer "},
+		{"empty API key", "ApiKey "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveAuth(t, tt.header)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", rec.Code)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, "Bearer")
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareRejectsInvalidTokens(t *testing.T) {
+	expired := validTestClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+
+	wrongIssuer := validTestClaims()
+	wrongIssuer.Issuer = "someone-else"
+
+	wrongAudience := validTestClaims()
+	wrongAudience.Audience = jwt.ClaimStrings{"someone-else"}
+
+	badSubject := validTestClaims()
+	badSubject.Subject = "not-a-number"
+
+	noExpiry := validTestClaims()
+	noExpiry.ExpiresAt = nil
+
+	unknownRole := &models.TokenClaims{RegisteredClaims: validTestClaims(), Role: "owner"}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not-a-jwt"},
+		{"wrong secret", signTestToken(t, "other-secret", validTestClaims())},
+		{"expired", signTestToken(t, testAuthConfig.Secret, expired)},
+		{"missing expiry", signTestToken(t, testAuthConfig.Secret, noExpiry)},
+		{"wrong issuer", signTestToken(t, testAuthConfig.Secret, wrongIssuer)},
+		{"wrong audience", signTestToken(t, testAuthConfig.Secret, wrongAudience)},
+		{"non-numeric subject", signTestToken(t, testAuthConfig.Secret, badSubject)},
+		{"unknown role", signTestToken(t, testAuthConfig.Secret, unknownRole)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveAuth(t, "Bearer "+tt.token)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401", rec.Code)
+			}
+			if got, want := rec.Header().Get("WWW-Authenticate"), `Bearer error="invalid_token"`; got != want {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, want)
+			}
+
+			// Every rejection carries the same detail, whatever the cause
+			var body models.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.Detail != invalidTokenMessage {
+				t.Errorf("detail = %q, want %q", body.Detail, invalidTokenMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"goapi/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DBSession returns a gin middleware that tracks the database writes of each
+// request, so reads made after a write see it instead of a lagging replica
+func DBSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(repository.WithSession(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -8,7 +8,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// Logger returns a gin middleware that logs requests using our custom logger
+// logComponent is the log component of the HTTP middleware, whose log level
+// can be changed on its own at runtime
+const logComponent = "http"
+
+// Logger returns a gin middleware that logs requests using our custom logger.
+// It logs with the request logger, so it must come after RequestID.
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Start timer
@@ -35,36 +40,26 @@ func Logger() gin.HandlerFunc {
 		// Get error message if any
 		errorMessage := c.Errors.ByType(gin.ErrorTypePrivate).String()
 
-		// Log the request
+		fields := []interface{}{
+			"method", method,
+			"path", path,
+			"query", raw,
+			"status", statusCode,
+			"latency", latency,
+			"client_ip", clientIP,
+		}
+		if errorMessage != "" {
+			fields = append(fields, "error", errorMessage)
+		}
+
+		// Log the request, with the user once authentication added it
+		requestLogger := logger.FromContext(c.Request.Context()).Component(logComponent)
 		if statusCode >= 500 {
-			logger.Error("[GIN] %s | %3d | %13v | %15s | %s %s %s",
-				method,
-				statusCode,
-				latency,
-				clientIP,
-				path,
-				raw,
-				errorMessage,
-			)
+			requestLogger.Error("request", fields...)
 		} else if statusCode >= 400 {
-			logger.Warn("[GIN] %s | %3d | %13v | %15s | %s %s %s",
-				method,
-				statusCode,
-				latency,
-				clientIP,
-				path,
-				raw,
-				errorMessage,
-			)
+			requestLogger.Warn("request", fields...)
 		} else {
-			logger.Info("[GIN] %s | %3d | %13v | %15s | %s %s",
-				method,
-				statusCode,
-				latency,
-				clientIP,
-				path,
-				raw,
-			)
+			requestLogger.Info("request", fields...)
 		}
 	}
 }
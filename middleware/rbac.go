@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"goapi/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission creates a middleware that only lets callers holding every given permission through.
+// It must be registered after AuthMiddleware.
+func RequirePermission(permissions ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := models.PrincipalFromContext(c.Request.Context())
+		if !ok {
+			abortWithError(c, models.ErrUnauthorized)
+			return
+		}
+
+		for _, permission := range permissions {
+			if !principal.Can(permission) {
+				abortWithError(c, models.ErrForbidden)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// abortWithError stops the request and writes err as an RFC 7807 problem response
+func abortWithError(c *gin.Context, err error) {
+	problem := models.NewProblem(c.Request.Context(), err, c.Request.URL.Path)
+	c.Header("Content-Type", models.ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"goapi/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *models.Principal
+		wantStatus int
+	}{
+		{"unauthenticated", nil, http.StatusUnauthorized},
+		{"role without the permission", &models.Principal{UserID: 1, Role: models.RoleUser}, http.StatusForbidden},
+		{"role missing one of the permissions", &models.Principal{UserID: 1, Role: models.RoleManager}, http.StatusForbidden},
+		{"role with every permission", &models.Principal{UserID: 1, Role: models.RoleAdmin}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.principal != nil {
+					c.Request = c.Request.WithContext(models.WithPrincipal(c.Request.Context(), tt.principal))
+				}
+			})
+			router.GET("/", RequirePermission(models.PermUsersList, models.PermUsersDelete), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
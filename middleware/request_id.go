@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"goapi/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the id of a request in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the gin context key of the request id
+const RequestIDKey = "request_id"
+
+// requestIDPattern limits the ids accepted from clients, so they are safe to
+// log and to echo in a header
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID returns a gin middleware that identifies each request. It keeps
+// a valid X-Request-ID sent by the client or generates one, echoes it in the
+// response and stores a logger with the request id and route in the request
+// context, see logger.FromContext.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+
+		requestLogger := logger.Default().With("request_id", id, "route", c.FullPath())
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), requestLogger))
+		c.Next()
+	}
+}
+
+// newRequestID returns a random request id
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"goapi/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "kept from the client", header: "req-42.a:b_c", want: "req-42.a:b_c"},
+		{name: "generated when missing"},
+		{name: "replaced when unsafe to log", header: "bad id\nwith a newline"},
+		{name: "replaced when too long", header: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored string
+			var requestLogger *logger.Logger
+			router := gin.New()
+			router.GET("/users/:id", RequestID(), func(c *gin.Context) {
+				stored = c.GetString(RequestIDKey)
+				requestLogger = logger.FromContext(c.Request.Context())
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			got := w.Header().Get(RequestIDHeader)
+			if tt.want != "" && got != tt.want {
+				t.Errorf("%s = %q, want %q", RequestIDHeader, got, tt.want)
+			}
+			if tt.want == "" && (len(got) != 32 || got == tt.header) {
+				t.Errorf("%s = %q, want a generated id", RequestIDHeader, got)
+			}
+			if stored != got {
+				t.Errorf("context request id = %q, want the %q echoed", stored, got)
+			}
+			if requestLogger == nil || requestLogger == logger.Default() {
+				t.Error("the request context carries no request logger")
+			}
+		})
+	}
+}
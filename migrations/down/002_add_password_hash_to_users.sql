@@ -0,0 +1,2 @@
+-- drop password hash from users;
+alter table users drop column if exists password_hash;
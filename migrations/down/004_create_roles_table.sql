@@ -0,0 +1,3 @@
+-- drop role from users and table roles;
+alter table users drop column if exists role;
+drop table if exists roles;
@@ -0,0 +1,2 @@
+-- drop version from users;
+alter table users drop column if exists version;
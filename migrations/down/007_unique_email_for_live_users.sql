@@ -0,0 +1,21 @@
+-- restore unique constraint on users email;
+-- Soft deleted users may share their email with another user since the up
+-- migration. Rather than dropping or renaming rows, stop the rollback and
+-- leave the duplicates to be purged by hand, e.g. with DELETE /users/{id}?hard=true.
+do $$
+declare
+    duplicates bigint;
+begin
+    select count(*) into duplicates
+    from (select email from users group by email having count(*) > 1) d;
+
+    if duplicates > 0 then
+        raise exception 'cannot restore users_email_key: % emails are shared by several users, purge the soft deleted ones first', duplicates
+            using errcode = 'unique_violation';
+    end if;
+end
+$$;
+
+drop index if exists idx_users_deleted_at;
+drop index if exists idx_users_email_live;
+alter table users add constraint users_email_key unique (email);
@@ -0,0 +1,2 @@
+-- drop anonymized_at from users;
+alter table users drop column if exists anonymized_at;
@@ -3,6 +3,7 @@ package migrations
 import (
 	"database/sql"
 	"fmt"
+	"goapi/config"
 	"goapi/logger"
 	"os"
 	"path/filepath"
@@ -17,15 +18,27 @@ type MigrationFile struct {
 type Manager struct {
 	files                     []MigrationFile
 	db                        *sql.DB
+	driver                    string
 	defaultUpMigrationsPath   string
 	defaultDownMigrationsPath string
 }
 
-func NewManager(db *sql.DB) (*Manager, error) {
-	m := &Manager{db: db}
-
-	m.defaultUpMigrationsPath = "./migrations/up"
-	m.defaultDownMigrationsPath = "./migrations/down"
+// NewManager creates a manager for the migrations of the given database
+// driver. SQLite cannot alter most constraints, so it has its own
+// migrations under ./migrations/sqlite.
+func NewManager(db *sql.DB, driver string) (*Manager, error) {
+	m := &Manager{db: db, driver: driver}
+
+	switch driver {
+	case config.DriverPostgres:
+		m.defaultUpMigrationsPath = "./migrations/up"
+		m.defaultDownMigrationsPath = "./migrations/down"
+	case config.DriverSQLite:
+		m.defaultUpMigrationsPath = "./migrations/sqlite/up"
+		m.defaultDownMigrationsPath = "./migrations/sqlite/down"
+	default:
+		return nil, fmt.Errorf("driver %s has no migrations", driver)
+	}
 
 	if err := m.createMigrationsTable(); err != nil {
 		return nil, err
@@ -43,6 +56,16 @@ func (m *Manager) createMigrationsTable() error {
 			undone_at TIMESTAMP WITH TIME ZONE DEFAULT NULL
 		)
 	`
+	if m.driver == config.DriverSQLite {
+		query = `
+		CREATE TABLE IF NOT EXISTS migrations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			undone_at TIMESTAMP DEFAULT NULL
+		)
+	`
+	}
 	_, err := m.db.Exec(query)
 	return err
 }
@@ -82,7 +105,7 @@ func (m *Manager) RunMigrationsUp() error {
 			return fmt.Errorf("error committing migration %s: %w", file.Name, err)
 		}
 
-		logger.Info("Successfully executed migration: %s", file.Name)
+		logger.Infof("Successfully executed migration: %s", file.Name)
 	}
 
 	return nil
@@ -106,7 +129,7 @@ func (m *Manager) RunMigrationsDown() error {
 		}
 
 		// Record migration
-		_, err = tx.Exec("UPDATE migrations SET undone_at = NOW() WHERE name = $1 AND undone_at IS NULL", file.Name)
+		_, err = tx.Exec("UPDATE migrations SET undone_at = CURRENT_TIMESTAMP WHERE name = $1 AND undone_at IS NULL", file.Name)
 
 		if err != nil {
 			tx.Rollback()
@@ -118,7 +141,7 @@ func (m *Manager) RunMigrationsDown() error {
 			return fmt.Errorf("error committing migration %s: %w", file.Name, err)
 		}
 
-		logger.Info("Successfully executed migration: %s", file.Name)
+		logger.Infof("Successfully executed migration: %s", file.Name)
 	}
 
 	return nil
@@ -147,7 +170,7 @@ func (m *Manager) LoadMigrationsUp(dir string) error {
 			return fmt.Errorf("error checking migration status: %w", err)
 		}
 		if executed {
-			logger.Debug("Migration %s already executed, skipping...", file.Name())
+			logger.Debugf("Migration %s already executed, skipping...", file.Name())
 			continue
 		}
 
@@ -177,8 +200,9 @@ func (m *Manager) LoadMigrationsDown(dir string) error {
 		return fmt.Errorf("failed to read migrations directory: %w", err)
 	}
 
-	// Process each file
-	for _, file := range files {
+	// Process each file, newest first so dependent objects are dropped before their dependencies
+	for i := len(files) - 1; i >= 0; i-- {
+		file := files[i]
 		if file.IsDir() {
 			continue
 		}
@@ -192,7 +216,7 @@ func (m *Manager) LoadMigrationsDown(dir string) error {
 			return fmt.Errorf("error checking migration status: %w", err)
 		}
 		if !exist {
-			logger.Debug("Migration down %s already executed, skipping...", file.Name())
+			logger.Debugf("Migration down %s already executed, skipping...", file.Name())
 			continue
 		}
 
@@ -219,6 +243,6 @@ func (file *MigrationFile) PrintMigrations(dir string) {
 	} else if dir == "up" {
 		logger.Debug("=== Up Migration ===")
 	}
-	logger.Debug("File: %s", file.Name)
+	logger.Debugf("File: %s", file.Name)
 	logger.Debug("----------------------------------------")
 }
@@ -0,0 +1,5 @@
+-- drop the schema, dependent tables first;
+drop table if exists api_keys;
+drop table if exists refresh_tokens;
+drop table if exists users;
+drop table if exists roles;
@@ -0,0 +1,2 @@
+-- Add password hash to users
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash VARCHAR(255) DEFAULT NULL;
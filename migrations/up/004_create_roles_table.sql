@@ -0,0 +1,14 @@
+-- Create Roles Table
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Manages every user'),
+    ('manager', 'Reads every user'),
+    ('user', 'Reads and updates their own record')
+ON CONFLICT (name) DO NOTHING;
+
+-- Assign a role to every user
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(50) NOT NULL DEFAULT 'user' REFERENCES roles(name);
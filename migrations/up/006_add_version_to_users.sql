@@ -0,0 +1,2 @@
+-- Add version to users, incremented on every update and exposed as the ETag
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,8 @@
+-- Only enforce unique emails among users that are not soft deleted,
+-- so the email of a deleted user can be reused
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_live ON users(email) WHERE deleted_at IS NULL;
+
+-- Index for listing deleted users
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Add anonymized_at to users, set by the retention job when it anonymizes a deleted user
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP DEFAULT NULL;
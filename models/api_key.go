@@ -0,0 +1,46 @@
+package models
+
+import "strings"
+
+type APIKeyInput struct {
+	Name      string       `json:"name" binding:"required,min=3,max=255"`
+	Scopes    []Permission `json:"scopes" binding:"required,min=1"`
+	ExpiresIn int64        `json:"expires_in,omitempty" binding:"omitempty,min=60"` // seconds, omit for keys that never expire
+}
+
+type APIKeyOutput struct {
+	ID         *int64       `json:"id"`
+	OwnerID    int64        `json:"owner_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Scopes     []Permission `json:"scopes"`
+	ExpiresAt  *string      `json:"expires_at"`
+	LastUsedAt *string      `json:"last_used_at"`
+	RevokedAt  *string      `json:"revoked_at"`
+	CreatedAt  string       `json:"created_at"`
+}
+
+// APIKeyCreated is returned once when a key is minted; the plain key is never stored
+type APIKeyCreated struct {
+	APIKeyOutput
+	Key string `json:"key"`
+}
+
+// FormatScopes joins scopes into the space separated form stored in the database
+func FormatScopes(scopes []Permission) string {
+	parts := make([]string, len(scopes))
+	for i, scope := range scopes {
+		parts[i] = string(scope)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseScopes splits the space separated form stored in the database
+func ParseScopes(value string) []Permission {
+	fields := strings.Fields(value)
+	scopes := make([]Permission, len(fields))
+	for i, field := range fields {
+		scopes[i] = Permission(field)
+	}
+	return scopes
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type LoginInput struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type TokenOutput struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// RefreshToken represents a persisted refresh token. Only the hash of the
+// token is stored; tokens rotated from the same login share a family.
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	FamilyID  string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// TokenClaims represents the claims carried by an access token
+type TokenClaims struct {
+	jwt.RegisteredClaims
+	Email string `json:"email,omitempty"`
+	Role  Role   `json:"role,omitempty"`
+}
+
+// Principal represents the authenticated caller of a request. Callers using
+// an API key act as the key's owner, limited to the key's scopes.
+type Principal struct {
+	UserID   int64
+	Role     Role
+	Claims   *TokenClaims
+	APIKeyID int64
+	Scopes   []Permission
+}
+
+// Can reports whether the principal has been granted the permission
+func (p *Principal) Can(permission Permission) bool {
+	return p.Role.HasPermission(permission) && p.HasScope(permission)
+}
+
+// HasScope reports whether the credential used allows the permission.
+// Bearer tokens are not scoped.
+func (p *Principal) HasScope(permission Permission) bool {
+	if !p.IsAPIKey() {
+		return true
+	}
+	for _, scope := range p.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIKey reports whether the principal authenticated with an API key
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKeyID != 0
+}
+
+// IsSelf reports whether the principal is the user with the given id
+func (p *Principal) IsSelf(userID int64) bool {
+	return p.UserID == userID
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal stored in ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
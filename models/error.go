@@ -5,18 +5,12 @@ import (
 	"net/http"
 )
 
-// ErrorResponse represents a standardized error response
-type ErrorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
-}
-
 // AppError represents an application error with HTTP status code
 type AppError struct {
-	Code    int
-	Message string
-	Err     error
+	Code      int
+	ErrorCode ErrorCode
+	Message   string
+	Err       error
 }
 
 func (e *AppError) Error() string {
@@ -26,24 +20,64 @@ func (e *AppError) Error() string {
 	return e.Message
 }
 
+// StatusClientClosedRequest is the non-standard status, introduced by nginx,
+// for requests the client abandoned before the response was ready
+const StatusClientClosedRequest = 499
+
 // Common application errors
 var (
-	ErrInvalidName  = &AppError{Code: http.StatusBadRequest, Message: "invalid name"}
-	ErrInvalidEmail = &AppError{Code: http.StatusBadRequest, Message: "invalid email"}
-	ErrNotFound     = &AppError{Code: http.StatusNotFound, Message: "resource not found"}
-	ErrInternal     = &AppError{Code: http.StatusInternalServerError, Message: "internal server error"}
-	ErrDuplicate    = &AppError{Code: http.StatusConflict, Message: "duplicate resource"}
+	ErrInvalidName  = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeInvalidName, Message: "invalid name"}
+	ErrInvalidEmail = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeInvalidEmail, Message: "invalid email"}
+	ErrNotFound     = &AppError{Code: http.StatusNotFound, ErrorCode: CodeNotFound, Message: "resource not found"}
+	ErrInternal     = &AppError{Code: http.StatusInternalServerError, ErrorCode: CodeInternal, Message: "internal server error"}
+	ErrDuplicate    = &AppError{Code: http.StatusConflict, ErrorCode: CodeConflict, Message: "duplicate resource"}
+	ErrUnauthorized = &AppError{Code: http.StatusUnauthorized, ErrorCode: CodeUnauthorized, Message: "unauthorized"}
+	ErrForbidden    = &AppError{Code: http.StatusForbidden, ErrorCode: CodeForbidden, Message: "forbidden"}
+	ErrInvalidRole  = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeInvalidRole, Message: "invalid role"}
+
+	ErrUserNotFound   = &AppError{Code: http.StatusNotFound, ErrorCode: CodeUserNotFound, Message: "user not found"}
+	ErrEmailTaken     = &AppError{Code: http.StatusConflict, ErrorCode: CodeEmailTaken, Message: "email is already in use"}
+	ErrAPIKeyNotFound = &AppError{Code: http.StatusNotFound, ErrorCode: CodeAPIKeyNotFound, Message: "API key not found"}
+
+	ErrPreconditionFailed = &AppError{Code: http.StatusPreconditionFailed, ErrorCode: CodePreconditionFailed, Message: "resource has been modified"}
+
+	ErrReferenceViolation  = &AppError{Code: http.StatusConflict, ErrorCode: CodeReferenceViolation, Message: "referenced resource does not exist"}
+	ErrConstraintViolation = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeConstraintViolation, Message: "value violates a data constraint"}
+	ErrTransactionConflict = &AppError{Code: http.StatusConflict, ErrorCode: CodeTransactionConflict, Message: "conflicting concurrent update, please retry"}
+	ErrQueryCanceled       = &AppError{Code: http.StatusServiceUnavailable, ErrorCode: CodeQueryCanceled, Message: "query canceled, please retry"}
+	ErrQueryTimeout        = &AppError{Code: http.StatusServiceUnavailable, ErrorCode: CodeQueryTimeout, Message: "query timed out, please retry"}
+	ErrClientClosedRequest = &AppError{Code: StatusClientClosedRequest, ErrorCode: CodeClientClosedRequest, Message: "client closed request"}
+
+	ErrInvalidCredentials  = &AppError{Code: http.StatusUnauthorized, ErrorCode: CodeInvalidCredentials, Message: "invalid email or password"}
+	ErrInvalidRefreshToken = &AppError{Code: http.StatusUnauthorized, ErrorCode: CodeInvalidRefreshToken, Message: "invalid or expired refresh token"}
+	ErrInvalidAPIKey       = &AppError{Code: http.StatusUnauthorized, ErrorCode: CodeInvalidAPIKey, Message: "invalid, expired or revoked API key"}
+	ErrInvalidScope        = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeInvalidScope, Message: "invalid scope"}
+
+	ErrInvalidFilter       = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeInvalidFilter, Message: "invalid filter"}
+	ErrInvalidSort         = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeInvalidSort, Message: "invalid sort"}
+	ErrInvalidPatch        = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeInvalidPatch, Message: "invalid patch"}
+	ErrInvalidSearchParams = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeInvalidSearchParams, Message: "invalid search parameters"}
+	ErrInvalidLogLevel     = &AppError{Code: http.StatusBadRequest, ErrorCode: CodeInvalidLogLevel, Message: "invalid log level"}
 )
 
-// NewAppError creates a new application error
+// NewAppError creates a new application error with the generic error code
+// of its status
 func NewAppError(code int, message string, err error) *AppError {
 	return &AppError{
-		Code:    code,
-		Message: message,
-		Err:     err,
+		Code:      code,
+		ErrorCode: codeForStatus(code),
+		Message:   message,
+		Err:       err,
 	}
 }
 
+// Wrap returns a copy of the error with err as its cause
+func (e *AppError) Wrap(err error) *AppError {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
 // IsAppError checks if an error is an AppError
 func IsAppError(err error) (*AppError, bool) {
 	var appErr *AppError
@@ -52,21 +86,3 @@ func IsAppError(err error) (*AppError, bool) {
 	}
 	return nil, false
 }
-
-// ToErrorResponse converts an error to ErrorResponse
-func ToErrorResponse(err error) ErrorResponse {
-	if appErr, ok := IsAppError(err); ok {
-		return ErrorResponse{
-			Code:    appErr.Code,
-			Message: appErr.Message,
-			Error:   appErr.Error(),
-		}
-	}
-
-	// Default to internal server error for unknown errors
-	return ErrorResponse{
-		Code:    http.StatusInternalServerError,
-		Message: "internal server error",
-		Error:   err.Error(),
-	}
-}
@@ -0,0 +1,73 @@
+package models
+
+import "net/http"
+
+// ErrorCode is a stable, machine-readable error identifier returned in the
+// code member of error responses. Codes are part of the API: never rename one.
+type ErrorCode string
+
+const (
+	CodeBadRequest           ErrorCode = "BAD_REQUEST"
+	CodeValidationFailed     ErrorCode = "VALIDATION_FAILED"
+	CodeInvalidName          ErrorCode = "INVALID_NAME"
+	CodeInvalidEmail         ErrorCode = "INVALID_EMAIL"
+	CodeInvalidRole          ErrorCode = "INVALID_ROLE"
+	CodeInvalidScope         ErrorCode = "INVALID_SCOPE"
+	CodeInvalidFilter        ErrorCode = "INVALID_FILTER"
+	CodeInvalidSort          ErrorCode = "INVALID_SORT"
+	CodeInvalidPatch         ErrorCode = "INVALID_PATCH"
+	CodeInvalidSearchParams  ErrorCode = "INVALID_SEARCH_PARAMETERS"
+	CodeInvalidLogLevel      ErrorCode = "INVALID_LOG_LEVEL"
+	CodeUnauthorized         ErrorCode = "UNAUTHORIZED"
+	CodeInvalidCredentials   ErrorCode = "INVALID_CREDENTIALS"
+	CodeInvalidRefreshToken  ErrorCode = "INVALID_REFRESH_TOKEN"
+	CodeInvalidAPIKey        ErrorCode = "INVALID_API_KEY"
+	CodeForbidden            ErrorCode = "FORBIDDEN"
+	CodeNotFound             ErrorCode = "NOT_FOUND"
+	CodeUserNotFound         ErrorCode = "USER_NOT_FOUND"
+	CodeAPIKeyNotFound       ErrorCode = "API_KEY_NOT_FOUND"
+	CodeConflict             ErrorCode = "CONFLICT"
+	CodeEmailTaken           ErrorCode = "EMAIL_TAKEN"
+	CodePreconditionFailed   ErrorCode = "PRECONDITION_FAILED"
+	CodeReferenceViolation   ErrorCode = "REFERENCE_VIOLATION"
+	CodeConstraintViolation  ErrorCode = "CONSTRAINT_VIOLATION"
+	CodeTransactionConflict  ErrorCode = "TRANSACTION_CONFLICT"
+	CodeQueryCanceled        ErrorCode = "QUERY_CANCELED"
+	CodeQueryTimeout         ErrorCode = "QUERY_TIMEOUT"
+	CodeClientClosedRequest  ErrorCode = "CLIENT_CLOSED_REQUEST"
+	CodeUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	CodeInternal             ErrorCode = "INTERNAL_ERROR"
+)
+
+// statusErrorCodes are the codes of errors created without one
+var statusErrorCodes = map[int]ErrorCode{
+	http.StatusBadRequest:           CodeBadRequest,
+	http.StatusUnauthorized:         CodeUnauthorized,
+	http.StatusForbidden:            CodeForbidden,
+	http.StatusNotFound:             CodeNotFound,
+	http.StatusConflict:             CodeConflict,
+	http.StatusPreconditionFailed:   CodePreconditionFailed,
+	http.StatusUnsupportedMediaType: CodeUnsupportedMediaType,
+	http.StatusInternalServerError:  CodeInternal,
+}
+
+// clientSafeErrorCodes are the codes of errors wrapping causes written for
+// clients, such as the unknown field of a filter. The text of their cause is
+// added to the detail of the problem; other causes are only logged.
+var clientSafeErrorCodes = map[ErrorCode]bool{
+	CodeInvalidFilter:       true,
+	CodeInvalidSort:         true,
+	CodeInvalidPatch:        true,
+	CodeInvalidSearchParams: true,
+}
+
+// codeForStatus returns the generic error code for an HTTP status
+func codeForStatus(status int) ErrorCode {
+	if code, ok := statusErrorCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"goapi/logger"
+)
+
+// LogLevelInput changes the log level of the server, or of one component
+// when Component is set
+type LogLevelInput struct {
+	Level       string `json:"level" binding:"required"`
+	Component   string `json:"component,omitempty" binding:"omitempty,max=64"`
+	RevertAfter int64  `json:"revert_after,omitempty" binding:"omitempty,min=1"` // seconds, omit for a lasting change
+}
+
+// LogLevelStatus is a runtime log level
+type LogLevelStatus struct {
+	Level    string  `json:"level"`
+	RevertAt *string `json:"revert_at"` // set while a timed change is active
+}
+
+// LogLevelOutput reports the log level of the server and of the components
+// set apart from it
+type LogLevelOutput struct {
+	LogLevelStatus
+	Components map[string]LogLevelStatus `json:"components"`
+}
+
+// NewLogLevelOutput converts the levels of a logger
+func NewLogLevelOutput(levels map[string]logger.LevelStatus) *LogLevelOutput {
+	output := &LogLevelOutput{Components: map[string]LogLevelStatus{}}
+	for component, status := range levels {
+		if component == "" {
+			output.LogLevelStatus = newLogLevelStatus(status)
+		} else {
+			output.Components[component] = newLogLevelStatus(status)
+		}
+	}
+	return output
+}
+
+func newLogLevelStatus(status logger.LevelStatus) LogLevelStatus {
+	s := LogLevelStatus{Level: status.Level.String()}
+	if !status.RevertAt.IsZero() {
+		revertAt := status.RevertAt.UTC().Format(time.RFC3339)
+		s.RevertAt = &revertAt
+	}
+	return s
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"goapi/logger"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// Problem represents an RFC 7807 problem details error response. Code,
+// Errors, CorrelationID and InternalError are extension members.
+type Problem struct {
+	Type          string       `json:"type"`
+	Title         string       `json:"title"`
+	Status        int          `json:"status"`
+	Detail        string       `json:"detail,omitempty"`
+	Instance      string       `json:"instance,omitempty"`
+	Code          ErrorCode    `json:"code"`
+	Errors        []FieldError `json:"errors,omitempty"`
+	CorrelationID string       `json:"correlation_id,omitempty"`
+	InternalError string       `json:"internal_error,omitempty"`
+}
+
+// exposeInternalErrors returns the text of internal errors to clients.
+// It must stay off in production.
+var exposeInternalErrors bool
+
+// ExposeInternalErrors sets whether problems for internal errors include
+// the error text, which is otherwise only logged
+func ExposeInternalErrors(expose bool) {
+	exposeInternalErrors = expose
+}
+
+// FieldError describes why a single request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// NewProblem converts an error to a problem for the request path instance.
+// Validation and JSON type errors wrapped in an AppError are listed per field.
+// Other wrapped errors are only logged, unless their code is client safe.
+// Internal errors are logged, with the request logger of ctx, along with a
+// correlation id returned to the client.
+func NewProblem(ctx context.Context, err error, instance string) *Problem {
+	appErr, ok := IsAppError(err)
+	if !ok {
+		appErr = ErrInternal
+	}
+
+	title := http.StatusText(appErr.Code)
+	if title == "" {
+		title = appErr.Message
+	}
+
+	problem := &Problem{
+		Type:     "about:blank",
+		Title:    title,
+		Status:   appErr.Code,
+		Detail:   appErr.Message,
+		Instance: instance,
+		Code:     appErr.ErrorCode,
+	}
+	if problem.Code == "" {
+		problem.Code = codeForStatus(appErr.Code)
+	}
+
+	if appErr.Code >= http.StatusInternalServerError {
+		problem.CorrelationID = newCorrelationID()
+		logger.FromContext(ctx).Error("Internal error", "correlation_id", problem.CorrelationID, "instance", instance, "error", err)
+		if exposeInternalErrors {
+			problem.InternalError = err.Error()
+		}
+		return problem
+	}
+
+	if appErr.Err == nil {
+		return problem
+	}
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(appErr.Err, &validationErrs):
+		problem.Code = CodeValidationFailed
+		for _, fe := range validationErrs {
+			problem.Errors = append(problem.Errors, FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Message: validationMessage(fe),
+			})
+		}
+	case errors.As(appErr.Err, &typeErr) && typeErr.Field != "":
+		problem.Code = CodeValidationFailed
+		problem.Errors = append(problem.Errors, FieldError{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be a %s", typeErr.Field, jsonTypeName(typeErr.Type)),
+		})
+	case clientSafeErrorCodes[appErr.ErrorCode]:
+		problem.Detail = appErr.Message + ": " + appErr.Err.Error()
+	default:
+		// The cause may reveal internals, such as a parser error
+		logger.FromContext(ctx).Debug("Client error", "instance", instance, "code", problem.Code, "error", appErr.Err)
+	}
+
+	return problem
+}
+
+// newCorrelationID returns a random id tying an error response to its log entry
+func newCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// JSONFieldName names struct fields after their JSON key in validation
+// errors. Register it with RegisterTagNameFunc on the validator.
+func JSONFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// fieldPath returns the path of the field without the top level struct name
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+// validationMessage returns a human readable message for a failed rule
+func validationMessage(fe validator.FieldError) string {
+	field := fe.Field()
+	isString := fe.Kind() == reflect.String
+
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	case "min":
+		if isString {
+			return fmt.Sprintf("%s must be at least %s characters long", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "max":
+		if isString {
+			return fmt.Sprintf("%s must be at most %s characters long", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "len":
+		if isString {
+			return fmt.Sprintf("%s must be exactly %s characters long", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must contain exactly %s items", field, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "gt", "gte", "lt", "lte":
+		operators := map[string]string{"gt": "greater than", "gte": "at least", "lt": "less than", "lte": "at most"}
+		return fmt.Sprintf("%s must be %s %s", field, operators[fe.Tag()], fe.Param())
+	default:
+		return fmt.Sprintf("%s is invalid", field)
+	}
+}
+
+// jsonTypeName returns the JSON name of a Go type
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"goapi/logger"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	logger.InitLogger(logger.Options{})
+}
+
+func TestNewProblem(t *testing.T) {
+	validate := validator.New()
+	validate.SetTagName("binding")
+	validate.RegisterTagNameFunc(JSONFieldName)
+	validationErr := validate.Struct(UserInput{Name: "Jo", Email: "not-an-email"})
+
+	var typeErr error = &json.UnmarshalTypeError{Field: "name", Type: reflect.TypeOf("")}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantDetail string
+		wantCode   ErrorCode
+		wantErrors []FieldError
+	}{
+		{name: "application error", err: ErrNotFound, wantStatus: http.StatusNotFound, wantDetail: "resource not found", wantCode: CodeNotFound},
+		{name: "unexpected error", err: errors.New("pq: connection refused"), wantStatus: http.StatusInternalServerError, wantDetail: "internal server error", wantCode: CodeInternal},
+		{
+			name:       "wrapped internal error",
+			err:        NewAppError(http.StatusInternalServerError, "internal server error", errors.New("pq: connection refused")),
+			wantStatus: http.StatusInternalServerError,
+			wantDetail: "internal server error",
+			wantCode:   CodeInternal,
+		},
+		{
+			name:       "catalog code",
+			err:        ErrEmailTaken,
+			wantStatus: http.StatusConflict,
+			wantDetail: "email is already in use",
+			wantCode:   CodeEmailTaken,
+		},
+		{
+			name:       "generic code of the status",
+			err:        NewAppError(http.StatusForbidden, "admins only", nil),
+			wantStatus: http.StatusForbidden,
+			wantDetail: "admins only",
+			wantCode:   CodeForbidden,
+		},
+		{
+			name:       "validation errors per field",
+			err:        NewAppError(http.StatusBadRequest, "invalid request body", validationErr),
+			wantStatus: http.StatusBadRequest,
+			wantDetail: "invalid request body",
+			wantCode:   CodeValidationFailed,
+			wantErrors: []FieldError{
+				{Field: "name", Rule: "min", Message: "name must be at least 3 characters long"},
+				{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+			},
+		},
+		{
+			name:       "JSON type error",
+			err:        NewAppError(http.StatusBadRequest, "invalid request body", typeErr),
+			wantStatus: http.StatusBadRequest,
+			wantDetail: "invalid request body",
+			wantCode:   CodeValidationFailed,
+			wantErrors: []FieldError{{Field: "name", Rule: "type", Message: "name must be a string"}},
+		},
+		{
+			name:       "client safe cause",
+			err:        ErrInvalidFilter.Wrap(errors.New(`unknown filter field "foo"`)),
+			wantStatus: http.StatusBadRequest,
+			wantDetail: `invalid filter: unknown filter field "foo"`,
+			wantCode:   CodeInvalidFilter,
+		},
+		{
+			name:       "other causes are hidden",
+			err:        NewAppError(http.StatusBadRequest, "invalid request body", errors.New("invalid character '}' looking for beginning of value")),
+			wantStatus: http.StatusBadRequest,
+			wantDetail: "invalid request body",
+			wantCode:   CodeBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := NewProblem(context.Background(), tt.err, "/users")
+			if problem.Status != tt.wantStatus || problem.Title != http.StatusText(tt.wantStatus) {
+				t.Errorf("NewProblem() status = %d %q, want %d", problem.Status, problem.Title, tt.wantStatus)
+			}
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("NewProblem() detail = %q, want %q", problem.Detail, tt.wantDetail)
+			}
+			if problem.Code != tt.wantCode {
+				t.Errorf("NewProblem() code = %q, want %q", problem.Code, tt.wantCode)
+			}
+			if !reflect.DeepEqual(problem.Errors, tt.wantErrors) {
+				t.Errorf("NewProblem() errors = %+v, want %+v", problem.Errors, tt.wantErrors)
+			}
+			if problem.Instance != "/users" || problem.Type != "about:blank" {
+				t.Errorf("NewProblem() = %+v, want type about:blank for /users", problem)
+			}
+		})
+	}
+}
+
+func TestNewProblem_InternalErrors(t *testing.T) {
+	err := errors.New("pq: connection refused")
+
+	problem := NewProblem(context.Background(), err, "/users")
+	if problem.CorrelationID == "" {
+		t.Error("NewProblem() has no correlation id for an internal error")
+	}
+	if problem.InternalError != "" {
+		t.Errorf("NewProblem() internal error = %q, want it hidden", problem.InternalError)
+	}
+	if other := NewProblem(context.Background(), err, "/users"); other.CorrelationID == problem.CorrelationID {
+		t.Errorf("NewProblem() reused correlation id %q", other.CorrelationID)
+	}
+
+	ExposeInternalErrors(true)
+	defer ExposeInternalErrors(false)
+	if problem := NewProblem(context.Background(), err, "/users"); problem.InternalError != err.Error() {
+		t.Errorf("NewProblem() internal error = %q, want %q in development", problem.InternalError, err.Error())
+	}
+
+	if problem := NewProblem(context.Background(), ErrNotFound, "/users/1"); problem.CorrelationID != "" || problem.InternalError != "" {
+		t.Errorf("NewProblem() = %+v, want no correlation id or internal error for a client error", problem)
+	}
+}
+
+func TestNewProblem_NonStandardStatus(t *testing.T) {
+	problem := NewProblem(context.Background(), ErrClientClosedRequest, "/users")
+	if problem.Status != StatusClientClosedRequest || problem.Title != "client closed request" || problem.Code != CodeClientClosedRequest {
+		t.Errorf("NewProblem() = %+v, want status 499 titled after the error", problem)
+	}
+}
+
+func TestWrapCopiesTheSentinel(t *testing.T) {
+	cause := errors.New("unknown sort field")
+	wrapped := ErrInvalidSort.Wrap(cause)
+	if ErrInvalidSort.Err != nil {
+		t.Fatal("Wrap() modified the sentinel")
+	}
+	if wrapped.Code != ErrInvalidSort.Code || wrapped.ErrorCode != CodeInvalidSort || wrapped.Message != ErrInvalidSort.Message || !errors.Is(wrapped.Err, cause) {
+		t.Errorf("Wrap() = %+v, want the sentinel with the cause", wrapped)
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+// Role represents the role assigned to a user
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleManager Role = "manager"
+	RoleUser    Role = "user"
+)
+
+// Permission represents an action that can be granted to a role
+type Permission string
+
+const (
+	PermUsersCreate Permission = "users:create"
+	PermUsersRead   Permission = "users:read"
+	PermUsersList   Permission = "users:list"
+	PermUsersUpdate Permission = "users:update"
+	PermUsersDelete Permission = "users:delete"
+	PermRolesAssign Permission = "roles:assign"
+
+	// Soft deleted users can only be seen and restored with users:restore,
+	// and permanently deleted with users:purge
+	PermUsersRestore Permission = "users:restore"
+	PermUsersPurge   Permission = "users:purge"
+
+	PermAPIKeysManage Permission = "api_keys:manage"
+
+	// Operational endpoints such as the database pool statistics, and
+	// changes to the running server such as its log level
+	PermSystemMonitor   Permission = "system:monitor"
+	PermSystemConfigure Permission = "system:configure"
+)
+
+// rolePermissions lists the permissions granted to each role. Permissions
+// apply to every user; any user can always read and update their own record.
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermUsersCreate,
+		PermUsersRead,
+		PermUsersList,
+		PermUsersUpdate,
+		PermUsersDelete,
+		PermRolesAssign,
+		PermUsersRestore,
+		PermUsersPurge,
+		PermAPIKeysManage,
+		PermSystemMonitor,
+		PermSystemConfigure,
+	},
+	RoleManager: {
+		PermUsersRead,
+		PermUsersList,
+	},
+	RoleUser: {},
+}
+
+// IsValid reports whether the role is a known role
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions returns the permissions granted to the role
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// HasPermission reports whether the role grants the permission
+func (r Role) HasPermission(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValid reports whether the permission is granted by any role
+func (p Permission) IsValid() bool {
+	for _, permissions := range rolePermissions {
+		for _, permission := range permissions {
+			if permission == p {
+				return true
+			}
+		}
+	}
+	return false
+}
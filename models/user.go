@@ -1,21 +1,74 @@
 package models
 
 type UserInput struct {
-	Name  string `json:"name" binding:"required,min=3"`
-	Email string `json:"email" binding:"required,email"`
+	Name     string `json:"name" binding:"required,min=3"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password,omitempty" binding:"omitempty,min=8,max=72"`
+	Role     Role   `json:"role,omitempty" binding:"omitempty,oneof=admin manager user"`
+}
+
+// PasswordInput sets the password of a user, letting them log in
+type PasswordInput struct {
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// UserPatch holds the fields of a partial update; nil fields are left unchanged
+type UserPatch struct {
+	Name  *string
+	Email *string
+	Role  *Role
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (p *UserPatch) IsEmpty() bool {
+	return p.Name == nil && p.Email == nil && p.Role == nil
 }
 
 type UserOutput struct {
 	ID        *int64 `json:"id"`
 	Name      string `json:"name"`
 	Email     string `json:"email"`
+	Role      Role   `json:"role"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+	// DeletedAt is only set on soft deleted users
+	DeletedAt *string `json:"deleted_at,omitempty"`
+	// Version is exposed through the ETag header
+	Version int64 `json:"-"`
+}
+
+// VersionMatch is a parsed If-Match precondition
+type VersionMatch struct {
+	Any      bool
+	Versions []int64
+}
+
+// Matches reports whether a resource at the given version satisfies the precondition
+func (m *VersionMatch) Matches(version int64) bool {
+	if m.Any {
+		return true
+	}
+	for _, v := range m.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
 }
 
 type UserList struct {
 	Users      []UserOutput `json:"users"`
-	TotalCount int64        `json:"total_count"`
+	TotalCount *int64       `json:"total_count,omitempty"`
 	Limit      int          `json:"limit"`
 	Offset     int          `json:"offset"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	PrevCursor string       `json:"prev_cursor,omitempty"`
+}
+
+// UserCredentials holds the data needed to authenticate a user
+type UserCredentials struct {
+	ID           int64
+	Email        string
+	PasswordHash string
+	Role         Role
 }
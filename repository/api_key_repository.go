@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"goapi/models"
+	"goapi/repository/api_keys_sql"
+)
+
+// APIKeyRepository defines the interface for API key data operations. Missing
+// keys are reported as sql.ErrNoRows, other failures as the errors of mapError.
+type APIKeyRepository interface {
+	Create(ctx context.Context, ownerID int64, input *models.APIKeyInput, prefix, secretHash string, ttl time.Duration) (*models.APIKeyOutput, error)
+	GetByID(ctx context.Context, id int64) (*models.APIKeyOutput, error)
+	GetActiveByPrefix(ctx context.Context, prefix string) (*models.APIKeyOutput, string, error)
+	ListByOwner(ctx context.Context, ownerID int64) ([]*models.APIKeyOutput, error)
+	Revoke(ctx context.Context, id int64) error
+	Touch(ctx context.Context, id int64) error
+}
+
+// PostgresAPIKeyRepository implements APIKeyRepository for PostgreSQL
+type PostgresAPIKeyRepository struct {
+	db *DB
+}
+
+// NewPostgresAPIKeyRepository creates a new PostgresAPIKeyRepository
+func NewPostgresAPIKeyRepository(db *DB) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db}
+}
+
+// Create implements the Create method of APIKeyRepository. A zero ttl creates a key that never expires.
+func (r *PostgresAPIKeyRepository) Create(ctx context.Context, ownerID int64, input *models.APIKeyInput, prefix, secretHash string, ttl time.Duration) (*models.APIKeyOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := api_keys_sql.CreateSQL
+	seconds := sql.NullFloat64{Float64: ttl.Seconds(), Valid: ttl > 0}
+
+	row := r.db.conn(ctx).QueryRowContext(ctx, query, ownerID, input.Name, prefix, secretHash, models.FormatScopes(input.Scopes), seconds)
+	key, err := scanAPIKey(row)
+	if err != nil {
+		return nil, mapError(ctx, err, sql.ErrNoRows)
+	}
+	return key, nil
+}
+
+// GetByID implements the GetByID method of APIKeyRepository
+func (r *PostgresAPIKeyRepository) GetByID(ctx context.Context, id int64) (*models.APIKeyOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	key, err := scanAPIKey(r.db.conn(ctx).QueryRowContext(ctx, api_keys_sql.GetByIDSQL, id))
+	if err != nil {
+		return nil, mapError(ctx, err, sql.ErrNoRows)
+	}
+	return key, nil
+}
+
+// GetActiveByPrefix implements the GetActiveByPrefix method of APIKeyRepository.
+// It returns the key along with its secret hash.
+func (r *PostgresAPIKeyRepository) GetActiveByPrefix(ctx context.Context, prefix string) (*models.APIKeyOutput, string, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	var secretHash string
+	key, err := scanAPIKey(r.db.conn(ctx).QueryRowContext(ctx, api_keys_sql.GetActiveByPrefixSQL, prefix), &secretHash)
+	if err != nil {
+		return nil, "", mapError(ctx, err, sql.ErrNoRows)
+	}
+	return key, secretHash, nil
+}
+
+// ListByOwner implements the ListByOwner method of APIKeyRepository
+func (r *PostgresAPIKeyRepository) ListByOwner(ctx context.Context, ownerID int64) ([]*models.APIKeyOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.conn(ctx).QueryContext(ctx, api_keys_sql.ListByOwnerSQL, ownerID)
+	if err != nil {
+		return nil, mapError(ctx, err, sql.ErrNoRows)
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKeyOutput{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, mapError(ctx, err, sql.ErrNoRows)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(ctx, err, sql.ErrNoRows)
+	}
+
+	return keys, nil
+}
+
+// Revoke implements the Revoke method of APIKeyRepository
+func (r *PostgresAPIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, api_keys_sql.RevokeSQL, id)
+	return mapError(ctx, err, sql.ErrNoRows)
+}
+
+// Touch implements the Touch method of APIKeyRepository
+func (r *PostgresAPIKeyRepository) Touch(ctx context.Context, id int64) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, api_keys_sql.TouchSQL, id)
+	return mapError(ctx, err, sql.ErrNoRows)
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey scans an API key row. Extra destinations are scanned before the key columns.
+func scanAPIKey(row rowScanner, extra ...interface{}) (*models.APIKeyOutput, error) {
+	key := &models.APIKeyOutput{}
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullString
+
+	dest := append(extra,
+		&key.ID,
+		&key.OwnerID,
+		&key.Name,
+		&key.Prefix,
+		&scopes,
+		&expiresAt,
+		&lastUsedAt,
+		&revokedAt,
+		&key.CreatedAt,
+	)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	key.Scopes = models.ParseScopes(scopes)
+	key.ExpiresAt = nullableString(expiresAt)
+	key.LastUsedAt = nullableString(lastUsedAt)
+	key.RevokedAt = nullableString(revokedAt)
+	return key, nil
+}
+
+func nullableString(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
@@ -0,0 +1,78 @@
+package api_keys_sql
+
+const CreateSQL = `
+-- name: CreateAPIKey
+-- Params:
+--   $1: owner_id (int64)
+--   $2: name (string)
+--   $3: prefix (string)
+--   $4: secret_hash (string)
+--   $5: scopes (string) - space separated
+--   $6: ttl (seconds, nullable) - NULL never expires
+INSERT INTO api_keys (
+    owner_id,
+    name,
+    prefix,
+    secret_hash,
+    scopes,
+    expires_at,
+    created_at
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    now() + make_interval(secs => $6),
+    now()
+)
+RETURNING
+    id,
+    owner_id,
+    name,
+    prefix,
+    scopes,
+    expires_at,
+    last_used_at,
+    revoked_at,
+    created_at
+    `
+
+const SQLiteCreateSQL = `
+-- name: CreateAPIKey (SQLite)
+-- Params:
+--   $1: owner_id (int64)
+--   $2: name (string)
+--   $3: prefix (string)
+--   $4: secret_hash (string)
+--   $5: scopes (string) - space separated
+--   $6: ttl (seconds, nullable) - NULL never expires
+INSERT INTO api_keys (
+    owner_id,
+    name,
+    prefix,
+    secret_hash,
+    scopes,
+    expires_at,
+    created_at
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || $6 || ' seconds'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+RETURNING
+    id,
+    owner_id,
+    name,
+    prefix,
+    scopes,
+    expires_at,
+    last_used_at,
+    revoked_at,
+    created_at`
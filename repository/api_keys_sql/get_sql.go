@@ -0,0 +1,63 @@
+package api_keys_sql
+
+const GetByIDSQL = `
+-- name: GetAPIKeyByID
+-- Params:
+--   $1: id (int64)
+-- Returns: Single row with API key data
+SELECT
+    id,
+    owner_id,
+    name,
+    prefix,
+    scopes,
+    expires_at,
+    last_used_at,
+    revoked_at,
+    created_at
+FROM api_keys
+WHERE id = $1`
+
+const GetActiveByPrefixSQL = `
+-- name: GetActiveAPIKeyByPrefix
+-- Params:
+--   $1: prefix (string)
+-- Returns: Single row with the secret hash and API key data, if the key is neither revoked nor expired
+SELECT
+    secret_hash,
+    id,
+    owner_id,
+    name,
+    prefix,
+    scopes,
+    expires_at,
+    last_used_at,
+    revoked_at,
+    created_at
+FROM api_keys
+WHERE
+    prefix = $1 AND
+    revoked_at IS NULL AND
+    (expires_at IS NULL OR expires_at > now())`
+
+const SQLiteGetActiveByPrefixSQL = `
+-- name: GetActiveAPIKeyByPrefix (SQLite)
+-- Params:
+--   $1: prefix (string)
+-- Returns: Single row with the secret hash and API key data, if the key is neither revoked nor expired
+SELECT
+    secret_hash,
+    id,
+    owner_id,
+    name,
+    prefix,
+    scopes,
+    expires_at,
+    last_used_at,
+    revoked_at,
+    created_at
+FROM api_keys
+WHERE
+    prefix = $1 AND
+    revoked_at IS NULL AND
+    (expires_at IS NULL OR expires_at > strftime('%Y-%m-%d %H:%M:%f', 'now'))`
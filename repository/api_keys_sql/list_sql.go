@@ -0,0 +1,20 @@
+package api_keys_sql
+
+const ListByOwnerSQL = `
+-- name: ListAPIKeysByOwner
+-- Params:
+--   $1: owner_id (int64)
+-- Returns: Multiple rows of API key data, newest first
+SELECT
+    id,
+    owner_id,
+    name,
+    prefix,
+    scopes,
+    expires_at,
+    last_used_at,
+    revoked_at,
+    created_at
+FROM api_keys
+WHERE owner_id = $1
+ORDER BY id DESC`
@@ -0,0 +1,41 @@
+package api_keys_sql
+
+const RevokeSQL = `
+-- name: RevokeAPIKey
+-- Params:
+--   $1: id (int64)
+-- Returns: Number of rows affected
+UPDATE api_keys
+SET revoked_at = now()
+WHERE
+    id = $1 AND
+    revoked_at IS NULL`
+
+const TouchSQL = `
+-- name: TouchAPIKey
+-- Params:
+--   $1: id (int64)
+-- Returns: Number of rows affected
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1`
+
+const SQLiteRevokeSQL = `
+-- name: RevokeAPIKey (SQLite)
+-- Params:
+--   $1: id (int64)
+-- Returns: Number of rows affected
+UPDATE api_keys
+SET revoked_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE
+    id = $1 AND
+    revoked_at IS NULL`
+
+const SQLiteTouchSQL = `
+-- name: TouchAPIKey (SQLite)
+-- Params:
+--   $1: id (int64)
+-- Returns: Number of rows affected
+UPDATE api_keys
+SET last_used_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = $1`
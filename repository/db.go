@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"goapi/logger"
+)
+
+// DB is the database handle shared by the Postgres repositories. It bounds
+// every query with the configured timeout and routes reads that tolerate
+// replication lag to healthy replicas.
+type DB struct {
+	*sql.DB
+	queryTimeout time.Duration
+
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// replica is a read-only copy of the primary. It only serves reads once a
+// health check succeeded.
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// NewDB wraps db. A zero queryTimeout leaves queries bounded only by the
+// caller's context.
+func NewDB(db *sql.DB, queryTimeout time.Duration) *DB {
+	return &DB{DB: db, queryTimeout: queryTimeout}
+}
+
+// WithReplicas adds read replicas. They stay unused until CheckReplicas
+// finds them healthy.
+func (d *DB) WithReplicas(replicas ...*sql.DB) *DB {
+	for _, db := range replicas {
+		d.replicas = append(d.replicas, &replica{db: db})
+	}
+	return d
+}
+
+// HasReplicas reports whether any read replicas are configured
+func (d *DB) HasReplicas() bool {
+	return len(d.replicas) > 0
+}
+
+// CheckReplicas pings every replica and updates its health. Reads fall back
+// to the primary while no replica is healthy.
+func (d *DB) CheckReplicas(ctx context.Context) {
+	for i, r := range d.replicas {
+		pingCtx, cancel := d.withTimeout(ctx)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				logger.FromContext(ctx).Component(logComponent).Info("Replica is healthy, routing reads to it", "replica", i)
+			} else {
+				logger.FromContext(ctx).Component(logComponent).Warn("Replica is unhealthy, routing its reads to the primary", "replica", i, "error", err)
+			}
+		}
+	}
+}
+
+// withTimeout derives the context a single query runs with. The returned
+// cancel must be called once the query's rows have been read.
+func (d *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d.queryTimeout)
+}
+
+// querier is satisfied by *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn returns the handle a query runs on: the transaction of ctx if there
+// is one, the primary otherwise
+func (d *DB) conn(ctx context.Context) querier {
+	if state := txFromContext(ctx); state != nil {
+		return state.tx
+	}
+	return d.DB
+}
+
+// reader returns the handle for a read that may see slightly stale data: a
+// healthy replica picked round robin, or the primary when there is none or
+// the request already wrote. Reads inside a transaction always use it.
+func (d *DB) reader(ctx context.Context) querier {
+	if len(d.replicas) == 0 || primaryRequired(ctx) {
+		return d.conn(ctx)
+	}
+
+	start := d.next.Add(1)
+	for i := range d.replicas {
+		r := d.replicas[(start+uint64(i))%uint64(len(d.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return d.DB
+}
+
+// wrote records a write, so the rest of the request reads from the primary
+// and sees its own writes
+func (d *DB) wrote(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		s.wrote.Store(true)
+	}
+}
+
+type sessionKey struct{}
+
+// session tracks the writes of a single request
+type session struct {
+	wrote atomic.Bool
+}
+
+// WithSession returns a context that tracks writes, so reads made after a
+// write in the same request go to the primary
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// primaryRequired reports whether reads in ctx must see the primary
+func primaryRequired(ctx context.Context) bool {
+	if txFromContext(ctx) != nil {
+		return true
+	}
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return ok && s.wrote.Load()
+}
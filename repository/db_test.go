@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"goapi/logger"
+
+	"github.com/lib/pq"
+)
+
+func init() {
+	logger.InitLogger(logger.Options{})
+}
+
+// stubConnector opens connections that answer pings and record the
+// statements and transactions run on them. They fail when down is set, and
+// the first failCommits commits fail with a serialization failure.
+type stubConnector struct {
+	down        bool
+	failCommits int
+
+	mu  sync.Mutex
+	log []string
+}
+
+func (c *stubConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.down {
+		return nil, errors.New("connection refused")
+	}
+	return &stubConn{c: c}, nil
+}
+
+func (c *stubConnector) Driver() driver.Driver { return stubDriver{} }
+
+func (c *stubConnector) record(statement string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.log = append(c.log, statement)
+}
+
+// statements returns what ran so far
+func (c *stubConnector) statements() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.log)
+}
+
+type stubDriver struct{}
+
+func (stubDriver) Open(name string) (driver.Conn, error) { return nil, errors.New("not supported") }
+
+type stubConn struct {
+	c *stubConnector
+}
+
+func (conn *stubConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (conn *stubConn) Close() error { return nil }
+
+func (conn *stubConn) Begin() (driver.Tx, error) {
+	conn.c.record("BEGIN")
+	return &stubTx{c: conn.c}, nil
+}
+
+func (conn *stubConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	conn.c.record(query)
+	return driver.RowsAffected(0), nil
+}
+
+type stubTx struct {
+	c *stubConnector
+}
+
+func (tx *stubTx) Commit() error {
+	tx.c.record("COMMIT")
+	tx.c.mu.Lock()
+	defer tx.c.mu.Unlock()
+	if tx.c.failCommits > 0 {
+		tx.c.failCommits--
+		return &pq.Error{Code: pgSerializationFailure}
+	}
+	return nil
+}
+
+func (tx *stubTx) Rollback() error {
+	tx.c.record("ROLLBACK")
+	return nil
+}
+
+func newStubDB(t *testing.T, down bool) *sql.DB {
+	db := sql.OpenDB(&stubConnector{down: down})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestDB_Reader(t *testing.T) {
+	primary, first, second := newStubDB(t, false), newStubDB(t, false), newStubDB(t, false)
+
+	if got := NewDB(primary, 0).reader(context.Background()); got != primary {
+		t.Error("reader() without replicas did not return the primary")
+	}
+
+	db := NewDB(primary, 0).WithReplicas(first, second)
+	if got := db.reader(context.Background()); got != primary {
+		t.Error("reader() returned a replica before any health check")
+	}
+
+	db.replicas[0].healthy.Store(true)
+	db.replicas[1].healthy.Store(true)
+	seen := map[querier]bool{}
+	for range 4 {
+		seen[db.reader(context.Background())] = true
+	}
+	if !seen[first] || !seen[second] || seen[primary] {
+		t.Error("reader() did not spread reads over the healthy replicas")
+	}
+
+	db.replicas[0].healthy.Store(false)
+	for range 4 {
+		if got := db.reader(context.Background()); got != second {
+			t.Fatal("reader() returned an unhealthy replica or the primary while a replica is healthy")
+		}
+	}
+
+	tx, err := primary.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	defer tx.Rollback()
+	if got := db.reader(context.WithValue(context.Background(), txKey{}, &txState{tx: tx})); got != tx {
+		t.Error("reader() in a transaction did not return the transaction")
+	}
+}
+
+func TestDB_ReadYourWrites(t *testing.T) {
+	primary, replica := newStubDB(t, false), newStubDB(t, false)
+	db := NewDB(primary, 0).WithReplicas(replica)
+	db.replicas[0].healthy.Store(true)
+
+	ctx := WithSession(context.Background())
+	if got := db.reader(ctx); got != replica {
+		t.Fatal("reader() before a write did not return the replica")
+	}
+	db.wrote(ctx)
+	if got := db.reader(ctx); got != primary {
+		t.Error("reader() after a write in the session did not return the primary")
+	}
+	if got := db.reader(WithSession(context.Background())); got != replica {
+		t.Error("a write in one session sent the reads of another to the primary")
+	}
+}
+
+func TestDB_CheckReplicas(t *testing.T) {
+	db := NewDB(newStubDB(t, false), 0).WithReplicas(newStubDB(t, false), newStubDB(t, true))
+	db.CheckReplicas(context.Background())
+
+	if !db.replicas[0].healthy.Load() {
+		t.Error("CheckReplicas() left a reachable replica unhealthy")
+	}
+	if db.replicas[1].healthy.Load() {
+		t.Error("CheckReplicas() marked an unreachable replica healthy")
+	}
+}
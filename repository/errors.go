@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"goapi/models"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgNotNullViolation     = "23502"
+	pgForeignKeyViolation  = "23503"
+	pgUniqueViolation      = "23505"
+	pgCheckViolation       = "23514"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgQueryCanceled        = "57014"
+)
+
+// constraintErrors maps named constraints to the domain error a violation means
+var constraintErrors = map[string]*models.AppError{
+	"users_email_key":      models.ErrEmailTaken,
+	"idx_users_email_live": models.ErrEmailTaken,
+	"users_role_fkey":      models.ErrInvalidRole,
+}
+
+// sqliteConstraintErrors maps the columns SQLite names in a unique
+// violation to the domain error it means. SQLite does not report
+// constraint names.
+var sqliteConstraintErrors = map[string]*models.AppError{
+	"users.email": models.ErrEmailTaken,
+}
+
+// mapError translates a database error into a domain error, returning
+// notFound for sql.ErrNoRows. A query stopped by its context maps to the
+// reason: the client went away or the query timed out. Errors without a
+// domain meaning are returned unchanged and end up as internal errors.
+func mapError(ctx context.Context, err error, notFound error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+
+	// The driver reports cancellation in several ways, the context knows why
+	switch ctx.Err() {
+	case context.Canceled:
+		return models.ErrClientClosedRequest
+	case context.DeadlineExceeded:
+		return models.ErrQueryTimeout
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return mapSQLiteError(sqliteErr)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	if domainErr, ok := constraintErrors[pqErr.Constraint]; ok {
+		return domainErr
+	}
+
+	switch pqErr.Code {
+	case pgUniqueViolation:
+		return models.ErrDuplicate
+	case pgForeignKeyViolation:
+		return models.ErrReferenceViolation
+	case pgCheckViolation, pgNotNullViolation:
+		return models.ErrConstraintViolation
+	case pgSerializationFailure, pgDeadlockDetected:
+		return models.ErrTransactionConflict
+	case pgQueryCanceled:
+		return models.ErrQueryCanceled
+	default:
+		return err
+	}
+}
+
+// mapSQLiteError is the part of mapError for SQLite errors
+func mapSQLiteError(err *sqlite.Error) error {
+	switch err.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		// The message ends with the columns, e.g. "UNIQUE constraint failed: users.email (2067)"
+		message := err.Error()
+		columns := message[strings.LastIndex(message, "failed: ")+len("failed: "):]
+		columns, _, _ = strings.Cut(columns, " (")
+		if domainErr, ok := sqliteConstraintErrors[columns]; ok {
+			return domainErr
+		}
+		return models.ErrDuplicate
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return models.ErrReferenceViolation
+	case sqlite3.SQLITE_CONSTRAINT_CHECK, sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+		return models.ErrConstraintViolation
+	}
+
+	// Another connection held the database past the busy timeout
+	switch err.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return models.ErrTransactionConflict
+	case sqlite3.SQLITE_INTERRUPT:
+		return models.ErrQueryCanceled
+	default:
+		return err
+	}
+}
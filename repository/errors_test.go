@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"goapi/models"
+
+	"github.com/lib/pq"
+)
+
+func TestMapError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "no error", err: nil, want: nil},
+		{name: "no rows", err: sql.ErrNoRows, want: models.ErrUserNotFound},
+		{name: "wrapped no rows", err: fmt.Errorf("scan: %w", sql.ErrNoRows), want: models.ErrUserNotFound},
+		{name: "email constraint", err: &pq.Error{Code: pgUniqueViolation, Constraint: "users_email_key"}, want: models.ErrEmailTaken},
+		{name: "live email index", err: &pq.Error{Code: pgUniqueViolation, Constraint: "idx_users_email_live"}, want: models.ErrEmailTaken},
+		{name: "role foreign key", err: &pq.Error{Code: pgForeignKeyViolation, Constraint: "users_role_fkey"}, want: models.ErrInvalidRole},
+		{name: "other unique violation", err: &pq.Error{Code: pgUniqueViolation, Constraint: "api_keys_prefix_key"}, want: models.ErrDuplicate},
+		{name: "other foreign key", err: &pq.Error{Code: pgForeignKeyViolation}, want: models.ErrReferenceViolation},
+		{name: "check violation", err: &pq.Error{Code: pgCheckViolation}, want: models.ErrConstraintViolation},
+		{name: "not null violation", err: &pq.Error{Code: pgNotNullViolation}, want: models.ErrConstraintViolation},
+		{name: "serialization failure", err: &pq.Error{Code: pgSerializationFailure}, want: models.ErrTransactionConflict},
+		{name: "deadlock", err: &pq.Error{Code: pgDeadlockDetected}, want: models.ErrTransactionConflict},
+		{name: "query canceled", err: &pq.Error{Code: pgQueryCanceled}, want: models.ErrQueryCanceled},
+		{name: "wrapped pq error", err: fmt.Errorf("insert: %w", &pq.Error{Code: pgCheckViolation}), want: models.ErrConstraintViolation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mapError(context.Background(), tt.err, models.ErrUserNotFound); got != tt.want {
+				t.Errorf("mapError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapError_KeepsErrorsWithoutDomainMeaning(t *testing.T) {
+	for _, err := range []error{
+		errors.New("driver: bad connection"),
+		&pq.Error{Code: "53300"}, // too many connections
+	} {
+		if got := mapError(context.Background(), err, models.ErrUserNotFound); got != err {
+			t.Errorf("mapError(%v) = %v, want the error unchanged", err, got)
+		}
+	}
+}
+
+func TestMapError_StoppedByContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		err        error
+		want       *models.AppError
+		wantStatus int
+	}{
+		{name: "client went away", ctx: canceled, err: context.Canceled, want: models.ErrClientClosedRequest, wantStatus: models.StatusClientClosedRequest},
+		{name: "driver reports the cancellation", ctx: canceled, err: &pq.Error{Code: pgQueryCanceled}, want: models.ErrClientClosedRequest, wantStatus: models.StatusClientClosedRequest},
+		{name: "query timed out", ctx: expired, err: context.DeadlineExceeded, want: models.ErrQueryTimeout, wantStatus: http.StatusServiceUnavailable},
+		{name: "driver reports the timeout", ctx: expired, err: &pq.Error{Code: pgQueryCanceled}, want: models.ErrQueryTimeout, wantStatus: http.StatusServiceUnavailable},
+		{name: "canceled by the server", ctx: context.Background(), err: &pq.Error{Code: pgQueryCanceled}, want: models.ErrQueryCanceled, wantStatus: http.StatusServiceUnavailable},
+		{name: "no rows still means not found", ctx: canceled, err: sql.ErrNoRows, want: models.ErrUserNotFound, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mapError(tt.ctx, tt.err, models.ErrUserNotFound)
+			if got != tt.want {
+				t.Fatalf("mapError() = %v, want %v", got, tt.want)
+			}
+			if tt.want.Code != tt.wantStatus {
+				t.Errorf("mapError() status = %d, want %d", tt.want.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMapError_SQLite(t *testing.T) {
+	db, _ := newSQLiteDB(t)
+	if _, err := db.Exec(`
+		INSERT INTO users (name, email, created_at, updated_at) VALUES ('Jane Doe', 'jane@example.com', '2024-01-01', '2024-01-01');
+		INSERT INTO api_keys (owner_id, name, prefix, secret_hash, created_at) VALUES (1, 'ci', 'abc', 'hash', '2024-01-01');
+		CREATE TABLE checked (n INTEGER CHECK (n > 0))`); err != nil {
+		t.Fatalf("seeding the database: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  error
+	}{
+		{name: "live email index", query: `INSERT INTO users (name, email, created_at, updated_at) VALUES ('Jane Again', 'jane@example.com', '2024-01-01', '2024-01-01')`, want: models.ErrEmailTaken},
+		{name: "other unique constraint", query: `INSERT INTO api_keys (owner_id, name, prefix, secret_hash, created_at) VALUES (1, 'ci', 'abc', 'hash', '2024-01-01')`, want: models.ErrDuplicate},
+		{name: "foreign key", query: `INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at, created_at) VALUES (42, 'hash', 'family', '2024-01-01', '2024-01-01')`, want: models.ErrReferenceViolation},
+		{name: "not null", query: `INSERT INTO users (name, email, created_at, updated_at) VALUES (NULL, 'john@example.com', '2024-01-01', '2024-01-01')`, want: models.ErrConstraintViolation},
+		{name: "check", query: `INSERT INTO checked (n) VALUES (0)`, want: models.ErrConstraintViolation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.Exec(tt.query)
+			if err == nil {
+				t.Fatal("Exec() error = nil, want a constraint violation")
+			}
+			if got := mapError(context.Background(), err, models.ErrUserNotFound); got != tt.want {
+				t.Errorf("mapError(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapError_SQLiteBusy(t *testing.T) {
+	db, cfg := newSQLiteDB(t)
+
+	// A second handle that gives up on the write lock at once
+	impatient, err := sql.Open("sqlite", "file:"+cfg.SQLitePath+"?_pragma=busy_timeout(0)&_txlock=immediate")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer impatient.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = impatient.Exec(`INSERT INTO users (name, email, created_at, updated_at) VALUES ('Jane Doe', 'jane@example.com', '2024-01-01', '2024-01-01')`)
+	if got := mapError(context.Background(), err, models.ErrUserNotFound); got != models.ErrTransactionConflict {
+		t.Errorf("mapError(%v) = %v, want %v", err, got, models.ErrTransactionConflict)
+	}
+}
+
+func TestMapError_SQLiteStoppedByContext(t *testing.T) {
+	db, _ := newSQLiteDB(t)
+	// Counts long enough to still be running when the context ends
+	const slowQuery = `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000000000) SELECT count(*) FROM n`
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		want       *models.AppError
+		wantStatus int
+	}{
+		{name: "client went away", ctx: canceled, want: models.ErrClientClosedRequest, wantStatus: models.StatusClientClosedRequest},
+		{name: "query timed out", ctx: expired, want: models.ErrQueryTimeout, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var count int64
+			err := db.QueryRowContext(tt.ctx, slowQuery).Scan(&count)
+			if err == nil {
+				t.Fatal("QueryRowContext() error = nil, want the query stopped")
+			}
+			got := mapError(tt.ctx, err, models.ErrUserNotFound)
+			if got != tt.want {
+				t.Fatalf("mapError(%v) = %v, want %v", err, got, tt.want)
+			}
+			if tt.want.Code != tt.wantStatus {
+				t.Errorf("mapError() status = %d, want %d", tt.want.Code, tt.wantStatus)
+			}
+		})
+	}
+}
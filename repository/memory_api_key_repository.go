@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"goapi/models"
+)
+
+// MemoryAPIKeyRepository implements APIKeyRepository on a MemoryStore
+type MemoryAPIKeyRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryAPIKeyRepository creates a new MemoryAPIKeyRepository
+func NewMemoryAPIKeyRepository(store *MemoryStore) *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{store: store}
+}
+
+// Create implements the Create method of APIKeyRepository. A zero ttl creates a key that never expires.
+func (r *MemoryAPIKeyRepository) Create(ctx context.Context, ownerID int64, input *models.APIKeyInput, prefix, secretHash string, ttl time.Duration) (*models.APIKeyOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := r.store.users[ownerID]; !ok {
+		return nil, models.ErrReferenceViolation
+	}
+	for _, key := range r.store.apiKeys {
+		if key.prefix == prefix {
+			return nil, models.ErrDuplicate
+		}
+	}
+
+	now := r.store.now(ctx)
+	key := memoryAPIKey{
+		id:         r.store.nextID("api_keys"),
+		ownerID:    ownerID,
+		name:       input.Name,
+		prefix:     prefix,
+		secretHash: secretHash,
+		scopes:     append([]models.Permission(nil), input.Scopes...),
+		createdAt:  now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		key.expiresAt = &expiresAt
+	}
+	r.store.apiKeys[key.id] = key
+	return key.output(), nil
+}
+
+// GetByID implements the GetByID method of APIKeyRepository
+func (r *MemoryAPIKeyRepository) GetByID(ctx context.Context, id int64) (*models.APIKeyOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	key, ok := r.store.apiKeys[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return key.output(), nil
+}
+
+// GetActiveByPrefix implements the GetActiveByPrefix method of APIKeyRepository.
+// It returns the key along with its secret hash.
+func (r *MemoryAPIKeyRepository) GetActiveByPrefix(ctx context.Context, prefix string) (*models.APIKeyOutput, string, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	defer unlock()
+
+	now := r.store.now(ctx)
+	for _, key := range r.store.apiKeys {
+		if key.prefix != prefix || key.revokedAt != nil {
+			continue
+		}
+		if key.expiresAt != nil && !key.expiresAt.After(now) {
+			continue
+		}
+		return key.output(), key.secretHash, nil
+	}
+	return nil, "", sql.ErrNoRows
+}
+
+// ListByOwner implements the ListByOwner method of APIKeyRepository
+func (r *MemoryAPIKeyRepository) ListByOwner(ctx context.Context, ownerID int64) ([]*models.APIKeyOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	keys := []*models.APIKeyOutput{}
+	for _, key := range r.store.apiKeys {
+		if key.ownerID == ownerID {
+			keys = append(keys, key.output())
+		}
+	}
+	// Newest first
+	sort.Slice(keys, func(i, j int) bool {
+		return *keys[i].ID > *keys[j].ID
+	})
+	return keys, nil
+}
+
+// Revoke implements the Revoke method of APIKeyRepository
+func (r *MemoryAPIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if key, ok := r.store.apiKeys[id]; ok && key.revokedAt == nil {
+		now := r.store.now(ctx)
+		key.revokedAt = &now
+		r.store.apiKeys[id] = key
+	}
+	return nil
+}
+
+// Touch implements the Touch method of APIKeyRepository
+func (r *MemoryAPIKeyRepository) Touch(ctx context.Context, id int64) error {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if key, ok := r.store.apiKeys[id]; ok {
+		now := r.store.now(ctx)
+		key.lastUsedAt = &now
+		r.store.apiKeys[id] = key
+	}
+	return nil
+}
+
+// output converts the stored key to its output form
+func (k memoryAPIKey) output() *models.APIKeyOutput {
+	id := k.id
+	return &models.APIKeyOutput{
+		ID:         &id,
+		OwnerID:    k.ownerID,
+		Name:       k.name,
+		Prefix:     k.prefix,
+		Scopes:     append([]models.Permission(nil), k.scopes...),
+		ExpiresAt:  formatNullableTime(k.expiresAt),
+		LastUsedAt: formatNullableTime(k.lastUsedAt),
+		RevokedAt:  formatNullableTime(k.revokedAt),
+		CreatedAt:  formatTime(k.createdAt),
+	}
+}
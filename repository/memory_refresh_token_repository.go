@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"goapi/models"
+)
+
+// MemoryRefreshTokenRepository implements RefreshTokenRepository on a MemoryStore
+type MemoryRefreshTokenRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryRefreshTokenRepository creates a new MemoryRefreshTokenRepository
+func NewMemoryRefreshTokenRepository(store *MemoryStore) *MemoryRefreshTokenRepository {
+	return &MemoryRefreshTokenRepository{store: store}
+}
+
+// Create implements the Create method of RefreshTokenRepository
+func (r *MemoryRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken, ttl time.Duration) error {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := r.store.users[token.UserID]; !ok {
+		return models.ErrReferenceViolation
+	}
+	if _, ok := r.findByHash(token.TokenHash); ok {
+		return models.ErrDuplicate
+	}
+
+	now := r.store.now(ctx)
+	stored := memoryRefreshToken{
+		id:        r.store.nextID("refresh_tokens"),
+		userID:    token.UserID,
+		tokenHash: token.TokenHash,
+		familyID:  token.FamilyID,
+		expiresAt: now.Add(ttl),
+		createdAt: now,
+	}
+	r.store.tokens[stored.id] = stored
+
+	token.ID = stored.id
+	token.ExpiresAt = stored.expiresAt
+	return nil
+}
+
+// Consume implements the Consume method of RefreshTokenRepository
+func (r *MemoryRefreshTokenRepository) Consume(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	now := r.store.now(ctx)
+	token, ok := r.findByHash(tokenHash)
+	if !ok || token.revokedAt != nil || !token.expiresAt.After(now) {
+		return nil, sql.ErrNoRows
+	}
+
+	token.revokedAt = &now
+	r.store.tokens[token.id] = token
+	return token.output(), nil
+}
+
+// GetByHash implements the GetByHash method of RefreshTokenRepository
+func (r *MemoryRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	token, ok := r.findByHash(tokenHash)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return token.output(), nil
+}
+
+// RevokeFamily implements the RevokeFamily method of RefreshTokenRepository
+func (r *MemoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	now := r.store.now(ctx)
+	for id, token := range r.store.tokens {
+		if token.familyID == familyID && token.revokedAt == nil {
+			token.revokedAt = &now
+			r.store.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) findByHash(tokenHash string) (memoryRefreshToken, bool) {
+	for _, token := range r.store.tokens {
+		if token.tokenHash == tokenHash {
+			return token, true
+		}
+	}
+	return memoryRefreshToken{}, false
+}
+
+// output converts the stored token to its model
+func (t memoryRefreshToken) output() *models.RefreshToken {
+	token := &models.RefreshToken{
+		ID:        t.id,
+		UserID:    t.userID,
+		TokenHash: t.tokenHash,
+		FamilyID:  t.familyID,
+		ExpiresAt: t.expiresAt,
+	}
+	if t.revokedAt != nil {
+		revokedAt := *t.revokedAt
+		token.RevokedAt = &revokedAt
+	}
+	return token
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MemoryRetentionRepository implements RetentionRepository on a MemoryStore
+type MemoryRetentionRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryRetentionRepository creates a new MemoryRetentionRepository
+func NewMemoryRetentionRepository(store *MemoryStore) *MemoryRetentionRepository {
+	return &MemoryRetentionRepository{store: store}
+}
+
+// WithLock implements the WithLock method of RetentionRepository. The store
+// belongs to a single process, so the lock does too.
+func (r *MemoryRetentionRepository) WithLock(ctx context.Context, name string, fn func() error) (bool, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return false, err
+	}
+	if r.store.locks[name] {
+		unlock()
+		return false, nil
+	}
+	r.store.locks[name] = true
+	unlock()
+
+	defer func() {
+		// Release the lock even after cancellation
+		unlock, _ := r.store.lock(context.WithoutCancel(ctx))
+		delete(r.store.locks, name)
+		unlock()
+	}()
+
+	return true, fn()
+}
+
+// PurgeDeleted implements the PurgeDeleted method of RetentionRepository
+func (r *MemoryRetentionRepository) PurgeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	batch := r.batch(ctx, olderThan, limit, false)
+	for _, id := range batch {
+		r.store.deleteUser(id)
+	}
+	return int64(len(batch)), nil
+}
+
+// AnonymizeDeleted implements the AnonymizeDeleted method of RetentionRepository
+func (r *MemoryRetentionRepository) AnonymizeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	now := r.store.now(ctx)
+	batch := r.batch(ctx, olderThan, limit, true)
+	for _, id := range batch {
+		user := r.store.users[id]
+		user.name = "Deleted user"
+		user.email = fmt.Sprintf("deleted-%d@anonymized.invalid", id)
+		user.passwordHash = ""
+		user.anonymizedAt = &now
+		user.updatedAt = now
+		user.version++
+		r.store.users[id] = user
+		r.store.deleteCredentials(id)
+	}
+	return int64(len(batch)), nil
+}
+
+// batch returns the ids of up to limit users deleted before the retention
+// period, oldest deletion first, skipping anonymized users if asked to
+func (r *MemoryRetentionRepository) batch(ctx context.Context, olderThan time.Duration, limit int, skipAnonymized bool) []int64 {
+	cutoff := r.store.now(ctx).Add(-olderThan)
+
+	var users []memoryUser
+	for _, user := range r.store.users {
+		if user.deletedAt == nil || !user.deletedAt.Before(cutoff) {
+			continue
+		}
+		if skipAnonymized && user.anonymizedAt != nil {
+			continue
+		}
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].deletedAt.Before(*users[j].deletedAt)
+	})
+
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	ids := make([]int64, len(users))
+	for i, user := range users {
+		ids[i] = user.id
+	}
+	return ids
+}
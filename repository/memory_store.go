@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"goapi/models"
+)
+
+// MemoryStore holds the data of the in-memory repositories. They share one
+// store so that deleting a user removes its tokens and API keys, like the
+// foreign keys of the Postgres schema do. Data is lost when the process exits.
+type MemoryStore struct {
+	mu sync.Mutex
+	memoryData
+
+	// Like sequences, ids are not reused when a transaction rolls back
+	lastID    map[string]int64
+	timestamp time.Time
+	locks     map[string]bool
+}
+
+// memoryData is the state a transaction can roll back
+type memoryData struct {
+	users   map[int64]memoryUser
+	apiKeys map[int64]memoryAPIKey
+	tokens  map[int64]memoryRefreshToken
+}
+
+type memoryUser struct {
+	id           int64
+	name         string
+	email        string
+	role         models.Role
+	passwordHash string
+	createdAt    time.Time
+	updatedAt    time.Time
+	deletedAt    *time.Time
+	anonymizedAt *time.Time
+	version      int64
+}
+
+type memoryAPIKey struct {
+	id         int64
+	ownerID    int64
+	name       string
+	prefix     string
+	secretHash string
+	scopes     []models.Permission
+	expiresAt  *time.Time
+	lastUsedAt *time.Time
+	revokedAt  *time.Time
+	createdAt  time.Time
+}
+
+type memoryRefreshToken struct {
+	id        int64
+	userID    int64
+	tokenHash string
+	familyID  string
+	expiresAt time.Time
+	createdAt time.Time
+	revokedAt *time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		memoryData: memoryData{
+			users:   make(map[int64]memoryUser),
+			apiKeys: make(map[int64]memoryAPIKey),
+			tokens:  make(map[int64]memoryRefreshToken),
+		},
+		lastID: make(map[string]int64),
+		locks:  make(map[string]bool),
+	}
+}
+
+type memoryTxKey struct{}
+
+// lock locks the store for a single operation and returns the unlock
+// function. In a transaction the store is already locked.
+func (s *MemoryStore) lock(ctx context.Context) (func(), error) {
+	if err := contextError(ctx); err != nil {
+		return nil, err
+	}
+	if ctx.Value(memoryTxKey{}) == s {
+		return func() {}, nil
+	}
+	s.mu.Lock()
+	return s.mu.Unlock, nil
+}
+
+// nextID returns the next id of a table, like a SERIAL column
+func (s *MemoryStore) nextID(table string) int64 {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// now returns the current time at the precision Postgres stores. Within a
+// transaction every call returns the same time, like now() does.
+func (s *MemoryStore) now(ctx context.Context) time.Time {
+	if ctx.Value(memoryTxKey{}) == s {
+		return s.timestamp
+	}
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// snapshot copies the data so a transaction or savepoint can be rolled back
+func (s *MemoryStore) snapshot() memoryData {
+	data := memoryData{
+		users:   make(map[int64]memoryUser, len(s.users)),
+		apiKeys: make(map[int64]memoryAPIKey, len(s.apiKeys)),
+		tokens:  make(map[int64]memoryRefreshToken, len(s.tokens)),
+	}
+	for id, user := range s.users {
+		data.users[id] = user
+	}
+	for id, key := range s.apiKeys {
+		data.apiKeys[id] = key
+	}
+	for id, token := range s.tokens {
+		data.tokens[id] = token
+	}
+	return data
+}
+
+// deleteUser removes a user along with its tokens and API keys
+func (s *MemoryStore) deleteUser(id int64) {
+	delete(s.users, id)
+	s.deleteCredentials(id)
+}
+
+// deleteCredentials removes the tokens and API keys of a user
+func (s *MemoryStore) deleteCredentials(userID int64) {
+	for tokenID, token := range s.tokens {
+		if token.userID == userID {
+			delete(s.tokens, tokenID)
+		}
+	}
+	for keyID, key := range s.apiKeys {
+		if key.ownerID == userID {
+			delete(s.apiKeys, keyID)
+		}
+	}
+}
+
+// MemoryTxManager implements TxManager for the MemoryStore. A transaction
+// holds the store for its whole duration, so transactions never conflict.
+type MemoryTxManager struct {
+	store *MemoryStore
+}
+
+// NewMemoryTxManager creates a new MemoryTxManager
+func NewMemoryTxManager(store *MemoryStore) *MemoryTxManager {
+	return &MemoryTxManager{store: store}
+}
+
+// WithTx implements the WithTx method of TxManager. Changes are rolled back
+// by restoring a snapshot, which a nested call takes as its savepoint.
+func (m *MemoryTxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	s := m.store
+	if ctx.Value(memoryTxKey{}) != s {
+		if err := contextError(ctx); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.timestamp = time.Now().UTC().Truncate(time.Microsecond)
+		ctx = context.WithValue(ctx, memoryTxKey{}, s)
+	}
+
+	saved := s.snapshot()
+	defer func() {
+		if r := recover(); r != nil {
+			s.memoryData = saved
+			panic(r)
+		}
+		if err != nil {
+			s.memoryData = saved
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// contextError maps a done context the way mapError does
+func contextError(ctx context.Context) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return models.ErrClientClosedRequest
+	case context.DeadlineExceeded:
+		return models.ErrQueryTimeout
+	}
+	return nil
+}
+
+// formatTime formats a timestamp the way it is scanned from Postgres
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// formatNullableTime formats an optional timestamp
+func formatNullableTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	value := formatTime(*t)
+	return &value
+}
@@ -0,0 +1,501 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"goapi/models"
+	"goapi/repository/users_sql"
+)
+
+// MemoryUserRepository implements UserRepository on a MemoryStore, with the
+// semantics of the Postgres queries
+type MemoryUserRepository struct {
+	store *MemoryStore
+}
+
+// NewMemoryUserRepository creates a new MemoryUserRepository
+func NewMemoryUserRepository(store *MemoryStore) *MemoryUserRepository {
+	return &MemoryUserRepository{store: store}
+}
+
+// Create implements the Create method of UserRepository
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.UserInput, passwordHash string) (*models.UserOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if !user.Role.IsValid() {
+		return nil, models.ErrInvalidRole
+	}
+	if r.emailTaken(user.Email, 0) {
+		return nil, models.ErrEmailTaken
+	}
+
+	now := r.store.now(ctx)
+	created := memoryUser{
+		id:           r.store.nextID("users"),
+		name:         user.Name,
+		email:        user.Email,
+		role:         user.Role,
+		passwordHash: passwordHash,
+		createdAt:    now,
+		updatedAt:    now,
+		version:      1,
+	}
+	r.store.users[created.id] = created
+	return created.output(), nil
+}
+
+// GetByID implements the GetByID method of UserRepository
+func (r *MemoryUserRepository) GetByID(ctx context.Context, id int) (*models.UserOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	user, ok := r.store.users[int64(id)]
+	if !ok || user.deletedAt != nil {
+		return nil, models.ErrUserNotFound
+	}
+	return user.output(), nil
+}
+
+// GetByIDIncludingDeleted implements the GetByIDIncludingDeleted method of UserRepository
+func (r *MemoryUserRepository) GetByIDIncludingDeleted(ctx context.Context, id int) (*models.UserOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	user, ok := r.store.users[int64(id)]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+	output := user.output()
+	output.DeletedAt = formatNullableTime(user.deletedAt)
+	return output, nil
+}
+
+// GetCredentialsByEmail implements the GetCredentialsByEmail method of UserRepository
+func (r *MemoryUserRepository) GetCredentialsByEmail(ctx context.Context, email string) (*models.UserCredentials, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	for _, user := range r.store.users {
+		if user.email == email && user.deletedAt == nil {
+			return &models.UserCredentials{
+				ID:           user.id,
+				Email:        user.email,
+				PasswordHash: user.passwordHash,
+				Role:         user.role,
+			}, nil
+		}
+	}
+	return nil, models.ErrUserNotFound
+}
+
+// List implements the List method of UserRepository
+func (r *MemoryUserRepository) List(ctx context.Context, params ListParams) ([]*models.UserOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	matched, err := r.filter(params.Filters, params.Deleted)
+	if err != nil {
+		return nil, err
+	}
+
+	backward := params.Cursor != nil && params.Cursor.Backward
+	if params.Cursor != nil {
+		if len(params.Cursor.Values) != len(params.Sort)-1 {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+		key, err := cursorKey(params.Sort, params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		after := matched[:0]
+		for _, user := range matched {
+			if compareSortKeys(params.Sort, sortKey(user, params.Sort), key, backward) > 0 {
+				after = append(after, user)
+			}
+		}
+		matched = after
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return compareSortKeys(params.Sort, sortKey(matched[i], params.Sort), sortKey(matched[j], params.Sort), backward) < 0
+	})
+
+	if params.Offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[params.Offset:]
+	if len(matched) > params.Limit {
+		matched = matched[:params.Limit]
+	}
+
+	var users []*models.UserOutput
+	for _, user := range matched {
+		// Listed users carry deleted_at but not their version
+		output := user.output()
+		output.DeletedAt = formatNullableTime(user.deletedAt)
+		output.Version = 0
+		users = append(users, output)
+	}
+	return users, nil
+}
+
+// Count implements the Count method of UserRepository
+func (r *MemoryUserRepository) Count(ctx context.Context, params ListParams) (int64, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	matched, err := r.filter(params.Filters, params.Deleted)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(matched)), nil
+}
+
+// Update implements the Update method of UserRepository. An empty role keeps
+// the current one.
+func (r *MemoryUserRepository) Update(ctx context.Context, user *models.UserOutput, expectedVersion int64) (*models.UserOutput, error) {
+	patch := &models.UserPatch{Name: &user.Name, Email: &user.Email}
+	if user.Role != "" {
+		patch.Role = &user.Role
+	}
+	return r.Patch(ctx, int(*user.ID), patch, expectedVersion)
+}
+
+// Patch implements the Patch method of UserRepository
+func (r *MemoryUserRepository) Patch(ctx context.Context, id int, patch *models.UserPatch, expectedVersion int64) (*models.UserOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	user, ok := r.live(int64(id), expectedVersion)
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+
+	if patch.Name != nil {
+		user.name = *patch.Name
+	}
+	if patch.Email != nil {
+		if r.emailTaken(*patch.Email, user.id) {
+			return nil, models.ErrEmailTaken
+		}
+		user.email = *patch.Email
+	}
+	if patch.Role != nil {
+		if !patch.Role.IsValid() {
+			return nil, models.ErrInvalidRole
+		}
+		user.role = *patch.Role
+	}
+	user.updatedAt = r.store.now(ctx)
+	user.version++
+
+	r.store.users[user.id] = user
+	return user.output(), nil
+}
+
+// SetPassword implements the SetPassword method of UserRepository
+func (r *MemoryUserRepository) SetPassword(ctx context.Context, id int, passwordHash string, expectedVersion int64) (*models.UserOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	user, ok := r.live(int64(id), expectedVersion)
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+
+	user.passwordHash = passwordHash
+	user.updatedAt = r.store.now(ctx)
+	user.version++
+
+	r.store.users[user.id] = user
+	return user.output(), nil
+}
+
+// Delete implements the Delete method of UserRepository
+func (r *MemoryUserRepository) Delete(ctx context.Context, id int, expectedVersion int64) error {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user, ok := r.live(int64(id), expectedVersion)
+	if !ok {
+		return models.ErrUserNotFound
+	}
+
+	now := r.store.now(ctx)
+	user.deletedAt = &now
+	r.store.users[user.id] = user
+	return nil
+}
+
+// Restore implements the Restore method of UserRepository. Anonymized users
+// cannot be restored.
+func (r *MemoryUserRepository) Restore(ctx context.Context, id int) (*models.UserOutput, error) {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	user, ok := r.store.users[int64(id)]
+	if !ok || user.deletedAt == nil || user.anonymizedAt != nil {
+		return nil, models.ErrUserNotFound
+	}
+	if r.emailTaken(user.email, user.id) {
+		return nil, models.ErrEmailTaken
+	}
+
+	user.deletedAt = nil
+	user.updatedAt = r.store.now(ctx)
+	user.version++
+	r.store.users[user.id] = user
+	return user.output(), nil
+}
+
+// Purge implements the Purge method of UserRepository. The user's refresh
+// tokens and API keys are removed with it.
+func (r *MemoryUserRepository) Purge(ctx context.Context, id int, expectedVersion int64) error {
+	unlock, err := r.store.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user, ok := r.store.users[int64(id)]
+	if !ok || (expectedVersion != 0 && user.version != expectedVersion) {
+		return models.ErrUserNotFound
+	}
+
+	r.store.deleteUser(user.id)
+	return nil
+}
+
+// live returns the user if it is not deleted and, with a non-zero
+// expectedVersion, still at that version
+func (r *MemoryUserRepository) live(id int64, expectedVersion int64) (memoryUser, bool) {
+	user, ok := r.store.users[id]
+	if !ok || user.deletedAt != nil {
+		return memoryUser{}, false
+	}
+	if expectedVersion != 0 && user.version != expectedVersion {
+		return memoryUser{}, false
+	}
+	return user, true
+}
+
+// emailTaken reports whether another live user has the email, which the
+// unique index on live users would reject
+func (r *MemoryUserRepository) emailTaken(email string, exceptID int64) bool {
+	for _, user := range r.store.users {
+		if user.id != exceptID && user.deletedAt == nil && user.email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the live or deleted users matching every filter
+func (r *MemoryUserRepository) filter(filters []users_sql.Filter, deleted bool) ([]memoryUser, error) {
+	var matched []memoryUser
+	for _, user := range r.store.users {
+		if (user.deletedAt != nil) != deleted {
+			continue
+		}
+
+		ok, err := user.matches(filters)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, user)
+		}
+	}
+	return matched, nil
+}
+
+// matches reports whether the user satisfies every filter
+func (u memoryUser) matches(filters []users_sql.Filter) (bool, error) {
+	for _, filter := range filters {
+		args, err := filter.Args()
+		if err != nil {
+			return false, err
+		}
+
+		value := u.field(filter.Field)
+		var ok bool
+		switch filter.Op {
+		case users_sql.OpLike:
+			// ILIKE '%value%'
+			ok = strings.Contains(strings.ToLower(value.(string)), strings.ToLower(args[0].(string)))
+		case users_sql.OpIn:
+			for _, arg := range args {
+				if compareValues(value, arg) == 0 {
+					ok = true
+					break
+				}
+			}
+		default:
+			ok = compareOp(filter.Op, compareValues(value, args[0]))
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// field returns the value of a filterable column: int64 for ids, time.Time
+// for timestamps and strings otherwise
+func (u memoryUser) field(name string) interface{} {
+	switch name {
+	case "id":
+		return u.id
+	case "name":
+		return u.name
+	case "email":
+		return u.email
+	case "role":
+		return string(u.role)
+	case "created_at":
+		return u.createdAt
+	case "updated_at":
+		return u.updatedAt
+	default:
+		return nil
+	}
+}
+
+// output converts the stored user to its output form
+func (u memoryUser) output() *models.UserOutput {
+	id := u.id
+	return &models.UserOutput{
+		ID:        &id,
+		Name:      u.name,
+		Email:     u.email,
+		Role:      u.role,
+		CreatedAt: formatTime(u.createdAt),
+		UpdatedAt: formatTime(u.updatedAt),
+		Version:   u.version,
+	}
+}
+
+// sortKey returns the values of the user's sort columns
+func sortKey(user memoryUser, fields []users_sql.SortField) []interface{} {
+	key := make([]interface{}, len(fields))
+	for i, field := range fields {
+		key[i] = user.field(field.Field)
+	}
+	return key
+}
+
+// cursorKey returns the sort key stored in a cursor, whose last field is the id
+func cursorKey(fields []users_sql.SortField, cursor *users_sql.Cursor) ([]interface{}, error) {
+	key := make([]interface{}, len(fields))
+	for i, field := range fields[:len(fields)-1] {
+		value, err := users_sql.ParseValue(field.Field, cursor.Values[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+		key[i] = value
+	}
+	key[len(fields)-1] = cursor.ID
+	return key, nil
+}
+
+// compareSortKeys orders two sort keys like the ORDER BY clause of ListSQL,
+// reversed when paging backward
+func compareSortKeys(fields []users_sql.SortField, a, b []interface{}, backward bool) int {
+	for i, field := range fields {
+		c := compareValues(a[i], b[i])
+		if field.Desc != backward {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues compares a stored column value with a value of the same
+// column, either stored or converted by users_sql. Timestamps arrive from
+// users_sql as strings in users_sql.TimestampLayout.
+func compareValues(a, b interface{}) int {
+	if s, ok := b.(string); ok {
+		if _, isTime := a.(time.Time); isTime {
+			t, err := time.Parse(users_sql.TimestampLayout, s)
+			if err != nil {
+				return -1
+			}
+			b = t
+		}
+	}
+
+	switch a := a.(type) {
+	case int64:
+		b := b.(int64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// compareOp applies a comparison operator to the result of compareValues
+func compareOp(op users_sql.FilterOp, c int) bool {
+	switch op {
+	case users_sql.OpEq:
+		return c == 0
+	case users_sql.OpNe:
+		return c != 0
+	case users_sql.OpGt:
+		return c > 0
+	case users_sql.OpGte:
+		return c >= 0
+	case users_sql.OpLt:
+		return c < 0
+	case users_sql.OpLte:
+		return c <= 0
+	}
+	return false
+}
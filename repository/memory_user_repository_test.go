@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"goapi/models"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	testUserRepository(t, func(t *testing.T) *Repositories {
+		return NewMemoryRepositories()
+	})
+}
+
+func TestMemoryTxManager_RollsBack(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	failure := errors.New("validation failed")
+
+	err := repos.Tx.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := repos.Users.Create(ctx, &models.UserInput{Name: "Jane Doe", Email: "jane@example.com", Role: models.RoleUser}, "hash"); err != nil {
+			return err
+		}
+
+		// A failed savepoint only undoes its own writes
+		if err := repos.Tx.WithTx(ctx, func(ctx context.Context) error {
+			if _, err := repos.Users.Create(ctx, &models.UserInput{Name: "John Doe", Email: "john@example.com", Role: models.RoleUser}, "hash"); err != nil {
+				return err
+			}
+			return failure
+		}); !errors.Is(err, failure) {
+			t.Errorf("nested WithTx() error = %v, want %v", err, failure)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+
+	if _, err := repos.Users.GetCredentialsByEmail(ctx, "john@example.com"); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("the rolled back savepoint kept its user: error = %v", err)
+	}
+
+	if err := repos.Tx.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := repos.Users.Create(ctx, &models.UserInput{Name: "Mary Doe", Email: "mary@example.com", Role: models.RoleUser}, "hash"); err != nil {
+			return err
+		}
+		return failure
+	}); !errors.Is(err, failure) {
+		t.Fatalf("WithTx() error = %v, want %v", err, failure)
+	}
+
+	count, err := repos.Users.Count(ctx, ListParams{})
+	if err != nil {
+		t.Fatalf("Count() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Count() = %d after a rolled back transaction, want 1", count)
+	}
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"goapi/models"
+	"goapi/repository/refresh_tokens_sql"
+)
+
+// RefreshTokenRepository defines the interface for refresh token data operations.
+// Missing tokens are reported as sql.ErrNoRows, other failures as the errors of mapError.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken, ttl time.Duration) error
+	Consume(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+}
+
+// PostgresRefreshTokenRepository implements RefreshTokenRepository for PostgreSQL
+type PostgresRefreshTokenRepository struct {
+	db *DB
+}
+
+// NewPostgresRefreshTokenRepository creates a new PostgresRefreshTokenRepository
+func NewPostgresRefreshTokenRepository(db *DB) *PostgresRefreshTokenRepository {
+	return &PostgresRefreshTokenRepository{db: db}
+}
+
+// Create implements the Create method of RefreshTokenRepository
+func (r *PostgresRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken, ttl time.Duration) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := refresh_tokens_sql.CreateSQL
+	err := r.db.conn(ctx).QueryRowContext(ctx, query, token.UserID, token.TokenHash, token.FamilyID, ttl.Seconds()).Scan(&token.ID, &token.ExpiresAt)
+	return mapError(ctx, err, sql.ErrNoRows)
+}
+
+// Consume implements the Consume method of RefreshTokenRepository
+func (r *PostgresRefreshTokenRepository) Consume(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	return scanRefreshToken(ctx, r.db.conn(ctx).QueryRowContext(ctx, refresh_tokens_sql.ConsumeSQL, tokenHash))
+}
+
+// GetByHash implements the GetByHash method of RefreshTokenRepository
+func (r *PostgresRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	return scanRefreshToken(ctx, r.db.conn(ctx).QueryRowContext(ctx, refresh_tokens_sql.GetByHashSQL, tokenHash))
+}
+
+// RevokeFamily implements the RevokeFamily method of RefreshTokenRepository
+func (r *PostgresRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, refresh_tokens_sql.RevokeFamilySQL, familyID)
+	return mapError(ctx, err, sql.ErrNoRows)
+}
+
+// scanRefreshToken scans a refresh token row, mapping errors with mapError
+func scanRefreshToken(ctx context.Context, row *sql.Row) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	var revokedAt sql.NullTime
+
+	err := row.Scan(&token.ID, &token.UserID, &token.TokenHash, &token.FamilyID, &token.ExpiresAt, &revokedAt)
+	if err != nil {
+		return nil, mapError(ctx, err, sql.ErrNoRows)
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	return token, nil
+}
@@ -0,0 +1,41 @@
+package refresh_tokens_sql
+
+const ConsumeSQL = `
+-- name: ConsumeRefreshToken
+-- Revokes an active, unexpired token so it can only be exchanged once
+-- Params:
+--   $1: token_hash (string)
+-- Returns: Single row with the consumed token, or no rows
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE
+    token_hash = $1 AND
+    revoked_at IS NULL AND
+    expires_at > now()
+RETURNING
+    id,
+    user_id,
+    token_hash,
+    family_id,
+    expires_at,
+    revoked_at`
+
+const SQLiteConsumeSQL = `
+-- name: ConsumeRefreshToken (SQLite)
+-- Revokes an active, unexpired token so it can only be exchanged once
+-- Params:
+--   $1: token_hash (string)
+-- Returns: Single row with the consumed token, or no rows
+UPDATE refresh_tokens
+SET revoked_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE
+    token_hash = $1 AND
+    revoked_at IS NULL AND
+    expires_at > strftime('%Y-%m-%d %H:%M:%f', 'now')
+RETURNING
+    id,
+    user_id,
+    token_hash,
+    family_id,
+    expires_at,
+    revoked_at`
@@ -0,0 +1,52 @@
+package refresh_tokens_sql
+
+const CreateSQL = `
+-- name: CreateRefreshToken
+-- Params:
+--   $1: user_id (int64)
+--   $2: token_hash (string)
+--   $3: family_id (string)
+--   $4: ttl (seconds)
+INSERT INTO refresh_tokens (
+    user_id,
+    token_hash,
+    family_id,
+    expires_at,
+    created_at
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    now() + make_interval(secs => $4),
+    now()
+)
+RETURNING
+    id,
+    expires_at
+    `
+
+const SQLiteCreateSQL = `
+-- name: CreateRefreshToken (SQLite)
+-- Params:
+--   $1: user_id (int64)
+--   $2: token_hash (string)
+--   $3: family_id (string)
+--   $4: ttl (seconds)
+INSERT INTO refresh_tokens (
+    user_id,
+    token_hash,
+    family_id,
+    expires_at,
+    created_at
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    strftime('%Y-%m-%d %H:%M:%f', 'now', '+' || $4 || ' seconds'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+RETURNING
+    id,
+    expires_at`
@@ -0,0 +1,16 @@
+package refresh_tokens_sql
+
+const GetByHashSQL = `
+-- name: GetRefreshTokenByHash
+-- Params:
+--   $1: token_hash (string)
+-- Returns: Single row with refresh token data
+SELECT
+    id,
+    user_id,
+    token_hash,
+    family_id,
+    expires_at,
+    revoked_at
+FROM refresh_tokens
+WHERE token_hash = $1`
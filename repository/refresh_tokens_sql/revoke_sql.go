@@ -0,0 +1,23 @@
+package refresh_tokens_sql
+
+const RevokeFamilySQL = `
+-- name: RevokeRefreshTokenFamily
+-- Params:
+--   $1: family_id (string)
+-- Returns: Number of rows affected
+UPDATE refresh_tokens
+SET revoked_at = now()
+WHERE
+    family_id = $1 AND
+    revoked_at IS NULL`
+
+const SQLiteRevokeFamilySQL = `
+-- name: RevokeRefreshTokenFamily (SQLite)
+-- Params:
+--   $1: family_id (string)
+-- Returns: Number of rows affected
+UPDATE refresh_tokens
+SET revoked_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE
+    family_id = $1 AND
+    revoked_at IS NULL`
@@ -0,0 +1,50 @@
+package repository
+
+// logComponent is the log component of the repositories, whose log level
+// can be changed on its own at runtime
+const logComponent = "repository"
+
+// Repositories bundles the repositories of one storage backend
+type Repositories struct {
+	Users         UserRepository
+	APIKeys       APIKeyRepository
+	RefreshTokens RefreshTokenRepository
+	Retention     RetentionRepository
+	Tx            TxManager
+}
+
+// NewPostgresRepositories creates the repositories backed by PostgreSQL
+func NewPostgresRepositories(db *DB, txMaxRetries int) *Repositories {
+	return &Repositories{
+		Users:         NewPostgresUserRepository(db),
+		APIKeys:       NewPostgresAPIKeyRepository(db),
+		RefreshTokens: NewPostgresRefreshTokenRepository(db),
+		Retention:     NewPostgresRetentionRepository(db),
+		Tx:            NewPostgresTxManager(db, txMaxRetries),
+	}
+}
+
+// NewSQLiteRepositories creates the repositories backed by a SQLite
+// database. Transactions are managed like those of PostgreSQL.
+func NewSQLiteRepositories(db *DB, txMaxRetries int) *Repositories {
+	return &Repositories{
+		Users:         NewSQLiteUserRepository(db),
+		APIKeys:       NewSQLiteAPIKeyRepository(db),
+		RefreshTokens: NewSQLiteRefreshTokenRepository(db),
+		Retention:     NewSQLiteRetentionRepository(db),
+		Tx:            NewPostgresTxManager(db, txMaxRetries),
+	}
+}
+
+// NewMemoryRepositories creates repositories that keep their data in memory.
+// They need no database, but their data is lost when the process exits.
+func NewMemoryRepositories() *Repositories {
+	store := NewMemoryStore()
+	return &Repositories{
+		Users:         NewMemoryUserRepository(store),
+		APIKeys:       NewMemoryAPIKeyRepository(store),
+		RefreshTokens: NewMemoryRefreshTokenRepository(store),
+		Retention:     NewMemoryRetentionRepository(store),
+		Tx:            NewMemoryTxManager(store),
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"goapi/logger"
+	"goapi/repository/users_sql"
+)
+
+// RetentionRepository defines the data operations of the user retention job
+type RetentionRepository interface {
+	// WithLock runs fn while holding the named advisory lock. It returns
+	// false without running fn when another instance holds the lock.
+	WithLock(ctx context.Context, name string, fn func() error) (bool, error)
+	PurgeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error)
+	AnonymizeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error)
+}
+
+// PostgresRetentionRepository implements RetentionRepository for PostgreSQL
+type PostgresRetentionRepository struct {
+	db *DB
+}
+
+// NewPostgresRetentionRepository creates a new PostgresRetentionRepository
+func NewPostgresRetentionRepository(db *DB) *PostgresRetentionRepository {
+	return &PostgresRetentionRepository{db: db}
+}
+
+// WithLock implements the WithLock method of RetentionRepository. Advisory
+// locks belong to a session, so the lock is taken and released on a
+// dedicated connection.
+func (r *PostgresRetentionRepository) WithLock(ctx context.Context, name string, fn func() error) (bool, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, users_sql.RetentionLockSQL, name).Scan(&acquired); err != nil {
+		return false, err
+	}
+	if !acquired {
+		return false, nil
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even after cancellation
+		if _, err := conn.ExecContext(context.Background(), users_sql.RetentionUnlockSQL, name); err != nil {
+			logger.FromContext(ctx).Component(logComponent).Error("Failed to release advisory lock", "lock", name, "error", err)
+		}
+	}()
+
+	return true, fn()
+}
+
+// PurgeDeleted implements the PurgeDeleted method of RetentionRepository
+func (r *PostgresRetentionRepository) PurgeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	return r.execBatch(ctx, users_sql.PurgeDeletedSQL, olderThan, limit)
+}
+
+// AnonymizeDeleted implements the AnonymizeDeleted method of RetentionRepository
+func (r *PostgresRetentionRepository) AnonymizeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	return r.execBatch(ctx, users_sql.AnonymizeDeletedSQL, olderThan, limit)
+}
+
+func (r *PostgresRetentionRepository) execBatch(ctx context.Context, query string, olderThan time.Duration, limit int) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.conn(ctx).ExecContext(ctx, query, olderThan.Seconds(), limit)
+	if err != nil {
+		return 0, mapError(ctx, err, sql.ErrNoRows)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"goapi/models"
+	"goapi/repository/api_keys_sql"
+)
+
+// SQLiteAPIKeyRepository implements APIKeyRepository for SQLite
+type SQLiteAPIKeyRepository struct {
+	db *DB
+}
+
+// NewSQLiteAPIKeyRepository creates a new SQLiteAPIKeyRepository
+func NewSQLiteAPIKeyRepository(db *DB) *SQLiteAPIKeyRepository {
+	return &SQLiteAPIKeyRepository{db: db}
+}
+
+// Create implements the Create method of APIKeyRepository. A zero ttl creates a key that never expires.
+func (r *SQLiteAPIKeyRepository) Create(ctx context.Context, ownerID int64, input *models.APIKeyInput, prefix, secretHash string, ttl time.Duration) (*models.APIKeyOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	seconds := sql.NullFloat64{Float64: ttl.Seconds(), Valid: ttl > 0}
+	row := r.db.conn(ctx).QueryRowContext(ctx, api_keys_sql.SQLiteCreateSQL, ownerID, input.Name, prefix, secretHash, models.FormatScopes(input.Scopes), seconds)
+	key, err := scanAPIKey(row)
+	if err != nil {
+		return nil, mapError(ctx, err, sql.ErrNoRows)
+	}
+	return key, nil
+}
+
+// GetByID implements the GetByID method of APIKeyRepository
+func (r *SQLiteAPIKeyRepository) GetByID(ctx context.Context, id int64) (*models.APIKeyOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	key, err := scanAPIKey(r.db.conn(ctx).QueryRowContext(ctx, api_keys_sql.GetByIDSQL, id))
+	if err != nil {
+		return nil, mapError(ctx, err, sql.ErrNoRows)
+	}
+	return key, nil
+}
+
+// GetActiveByPrefix implements the GetActiveByPrefix method of APIKeyRepository.
+// It returns the key along with its secret hash.
+func (r *SQLiteAPIKeyRepository) GetActiveByPrefix(ctx context.Context, prefix string) (*models.APIKeyOutput, string, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	var secretHash string
+	key, err := scanAPIKey(r.db.conn(ctx).QueryRowContext(ctx, api_keys_sql.SQLiteGetActiveByPrefixSQL, prefix), &secretHash)
+	if err != nil {
+		return nil, "", mapError(ctx, err, sql.ErrNoRows)
+	}
+	return key, secretHash, nil
+}
+
+// ListByOwner implements the ListByOwner method of APIKeyRepository
+func (r *SQLiteAPIKeyRepository) ListByOwner(ctx context.Context, ownerID int64) ([]*models.APIKeyOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.conn(ctx).QueryContext(ctx, api_keys_sql.ListByOwnerSQL, ownerID)
+	if err != nil {
+		return nil, mapError(ctx, err, sql.ErrNoRows)
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKeyOutput{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, mapError(ctx, err, sql.ErrNoRows)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(ctx, err, sql.ErrNoRows)
+	}
+
+	return keys, nil
+}
+
+// Revoke implements the Revoke method of APIKeyRepository
+func (r *SQLiteAPIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, api_keys_sql.SQLiteRevokeSQL, id)
+	return mapError(ctx, err, sql.ErrNoRows)
+}
+
+// Touch implements the Touch method of APIKeyRepository
+func (r *SQLiteAPIKeyRepository) Touch(ctx context.Context, id int64) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, api_keys_sql.SQLiteTouchSQL, id)
+	return mapError(ctx, err, sql.ErrNoRows)
+}
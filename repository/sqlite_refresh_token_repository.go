@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"goapi/models"
+	"goapi/repository/refresh_tokens_sql"
+)
+
+// SQLiteRefreshTokenRepository implements RefreshTokenRepository for SQLite
+type SQLiteRefreshTokenRepository struct {
+	db *DB
+}
+
+// NewSQLiteRefreshTokenRepository creates a new SQLiteRefreshTokenRepository
+func NewSQLiteRefreshTokenRepository(db *DB) *SQLiteRefreshTokenRepository {
+	return &SQLiteRefreshTokenRepository{db: db}
+}
+
+// Create implements the Create method of RefreshTokenRepository
+func (r *SQLiteRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken, ttl time.Duration) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := refresh_tokens_sql.SQLiteCreateSQL
+	err := r.db.conn(ctx).QueryRowContext(ctx, query, token.UserID, token.TokenHash, token.FamilyID, ttl.Seconds()).Scan(&token.ID, &token.ExpiresAt)
+	return mapError(ctx, err, sql.ErrNoRows)
+}
+
+// Consume implements the Consume method of RefreshTokenRepository
+func (r *SQLiteRefreshTokenRepository) Consume(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	return scanRefreshToken(ctx, r.db.conn(ctx).QueryRowContext(ctx, refresh_tokens_sql.SQLiteConsumeSQL, tokenHash))
+}
+
+// GetByHash implements the GetByHash method of RefreshTokenRepository
+func (r *SQLiteRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	return scanRefreshToken(ctx, r.db.conn(ctx).QueryRowContext(ctx, refresh_tokens_sql.GetByHashSQL, tokenHash))
+}
+
+// RevokeFamily implements the RevokeFamily method of RefreshTokenRepository
+func (r *SQLiteRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.conn(ctx).ExecContext(ctx, refresh_tokens_sql.SQLiteRevokeFamilySQL, familyID)
+	return mapError(ctx, err, sql.ErrNoRows)
+}
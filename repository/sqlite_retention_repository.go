@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"goapi/repository/users_sql"
+)
+
+// SQLiteRetentionRepository implements RetentionRepository for SQLite
+type SQLiteRetentionRepository struct {
+	db *DB
+
+	mu    sync.Mutex
+	locks map[string]bool
+}
+
+// NewSQLiteRetentionRepository creates a new SQLiteRetentionRepository
+func NewSQLiteRetentionRepository(db *DB) *SQLiteRetentionRepository {
+	return &SQLiteRetentionRepository{db: db, locks: make(map[string]bool)}
+}
+
+// WithLock implements the WithLock method of RetentionRepository. SQLite has
+// no advisory locks; a database file is meant to be served by a single
+// process, so the lock belongs to the process.
+func (r *SQLiteRetentionRepository) WithLock(ctx context.Context, name string, fn func() error) (bool, error) {
+	r.mu.Lock()
+	if r.locks[name] {
+		r.mu.Unlock()
+		return false, nil
+	}
+	r.locks[name] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.locks, name)
+		r.mu.Unlock()
+	}()
+
+	return true, fn()
+}
+
+// PurgeDeleted implements the PurgeDeleted method of RetentionRepository
+func (r *SQLiteRetentionRepository) PurgeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.conn(ctx).ExecContext(ctx, users_sql.SQLitePurgeDeletedSQL, olderThan.Seconds(), limit)
+	if err != nil {
+		return 0, mapError(ctx, err, sql.ErrNoRows)
+	}
+	return result.RowsAffected()
+}
+
+// AnonymizeDeleted implements the AnonymizeDeleted method of RetentionRepository.
+// The credentials of the batch are deleted before its users are anonymized,
+// all in one transaction.
+func (r *SQLiteRetentionRepository) AnonymizeDeleted(ctx context.Context, olderThan time.Duration, limit int) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, mapError(ctx, err, sql.ErrNoRows)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{users_sql.SQLiteDeleteBatchTokensSQL, users_sql.SQLiteDeleteBatchAPIKeysSQL} {
+		if _, err := tx.ExecContext(ctx, query, olderThan.Seconds(), limit); err != nil {
+			return 0, mapError(ctx, err, sql.ErrNoRows)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, users_sql.SQLiteAnonymizeDeletedSQL, olderThan.Seconds(), limit)
+	if err != nil {
+		return 0, mapError(ctx, err, sql.ErrNoRows)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return affected, mapError(ctx, tx.Commit(), sql.ErrNoRows)
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"goapi/logger"
+	"goapi/models"
+	"goapi/repository/users_sql"
+)
+
+// SQLiteUserRepository implements UserRepository for SQLite. It runs the
+// SQLite versions of the users_sql statements where the dialects differ.
+type SQLiteUserRepository struct {
+	db *DB
+}
+
+// NewSQLiteUserRepository creates a new SQLiteUserRepository
+func NewSQLiteUserRepository(db *DB) *SQLiteUserRepository {
+	return &SQLiteUserRepository{db: db}
+}
+
+// Create implements the Create method of UserRepository
+func (r *SQLiteUserRepository) Create(ctx context.Context, user *models.UserInput, passwordHash string) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	// Users created without a password cannot log in until one is set
+	hash := sql.NullString{String: passwordHash, Valid: passwordHash != ""}
+	row := r.db.conn(ctx).QueryRowContext(ctx, users_sql.SQLiteCreateUserSQL, user.Name, user.Email, hash, user.Role)
+	return r.scanUser(ctx, row)
+}
+
+// GetByID implements the GetByID method of UserRepository
+func (r *SQLiteUserRepository) GetByID(ctx context.Context, id int) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	logger.FromContext(ctx).Component(logComponent).Debug("Executing query", "query", users_sql.GetByIDSQL, "id", id)
+	return r.scanUser(ctx, r.db.conn(ctx).QueryRowContext(ctx, users_sql.GetByIDSQL, id))
+}
+
+// GetByIDIncludingDeleted implements the GetByIDIncludingDeleted method of UserRepository, see PostgresUserRepository.GetByIDIncludingDeleted
+func (r *SQLiteUserRepository) GetByIDIncludingDeleted(ctx context.Context, id int) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	logger.FromContext(ctx).Component(logComponent).Debug("Executing query", "query", users_sql.GetByIDIncludingDeletedSQL, "id", id)
+	user := &models.UserOutput{}
+	err := r.db.conn(ctx).QueryRowContext(ctx, users_sql.GetByIDIncludingDeletedSQL, id).Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.Version, &user.DeletedAt)
+	if err != nil {
+		return nil, r.mapError(ctx, err)
+	}
+	return user, nil
+}
+
+// GetCredentialsByEmail implements the GetCredentialsByEmail method of UserRepository
+func (r *SQLiteUserRepository) GetCredentialsByEmail(ctx context.Context, email string) (*models.UserCredentials, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	credentials := &models.UserCredentials{}
+	var passwordHash sql.NullString
+
+	err := r.db.conn(ctx).QueryRowContext(ctx, users_sql.GetCredentialsByEmailSQL, email).Scan(&credentials.ID, &credentials.Email, &passwordHash, &credentials.Role)
+	if err != nil {
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
+	}
+	credentials.PasswordHash = passwordHash.String
+	return credentials, nil
+}
+
+// List implements the List method of UserRepository
+func (r *SQLiteUserRepository) List(ctx context.Context, params ListParams) ([]*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query, args, err := users_sql.BuildListSQL(users_sql.SQLite, params.Filters, params.Sort, params.Cursor, params.Limit, params.Offset, params.Deleted)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
+	}
+	defer rows.Close()
+
+	var users []*models.UserOutput
+	for rows.Next() {
+		user := &models.UserOutput{}
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt); err != nil {
+			return nil, mapError(ctx, err, models.ErrUserNotFound)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
+	}
+
+	return users, nil
+}
+
+// Count implements the Count method of UserRepository
+func (r *SQLiteUserRepository) Count(ctx context.Context, params ListParams) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query, args, err := users_sql.BuildCountSQL(users_sql.SQLite, params.Filters, params.Deleted)
+	if err != nil {
+		return 0, err
+	}
+
+	var totalCount int64
+	err = r.db.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&totalCount)
+	return totalCount, mapError(ctx, err, models.ErrUserNotFound)
+}
+
+// Update implements the Update method of UserRepository, see PostgresUserRepository.Update
+func (r *SQLiteUserRepository) Update(ctx context.Context, user *models.UserOutput, expectedVersion int64) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	row := r.db.conn(ctx).QueryRowContext(ctx, users_sql.SQLiteUpdateSQL, user.Name, user.Email, user.Role, user.ID, expectedVersion)
+	return r.scanUser(ctx, row)
+}
+
+// SetPassword implements the SetPassword method of UserRepository, see PostgresUserRepository.SetPassword
+func (r *SQLiteUserRepository) SetPassword(ctx context.Context, id int, passwordHash string, expectedVersion int64) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	row := r.db.conn(ctx).QueryRowContext(ctx, users_sql.SQLiteSetPasswordSQL, passwordHash, id, expectedVersion)
+	return r.scanUser(ctx, row)
+}
+
+// Patch implements the Patch method of UserRepository, see PostgresUserRepository.Patch
+func (r *SQLiteUserRepository) Patch(ctx context.Context, id int, patch *models.UserPatch, expectedVersion int64) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	var name, email, role sql.NullString
+	if patch.Name != nil {
+		name = sql.NullString{String: *patch.Name, Valid: true}
+	}
+	if patch.Email != nil {
+		email = sql.NullString{String: *patch.Email, Valid: true}
+	}
+	if patch.Role != nil {
+		role = sql.NullString{String: string(*patch.Role), Valid: true}
+	}
+
+	row := r.db.conn(ctx).QueryRowContext(ctx, users_sql.SQLitePatchSQL, name, email, role, id, expectedVersion)
+	return r.scanUser(ctx, row)
+}
+
+// Delete implements the Delete method of UserRepository, see PostgresUserRepository.Delete
+func (r *SQLiteUserRepository) Delete(ctx context.Context, id int, expectedVersion int64) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.conn(ctx).ExecContext(ctx, users_sql.SQLiteDeleteSQL, id, expectedVersion)
+	return r.checkAffected(ctx, result, err)
+}
+
+// Restore implements the Restore method of UserRepository, see PostgresUserRepository.Restore
+func (r *SQLiteUserRepository) Restore(ctx context.Context, id int) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	return r.scanUser(ctx, r.db.conn(ctx).QueryRowContext(ctx, users_sql.SQLiteRestoreSQL, id))
+}
+
+// Purge implements the Purge method of UserRepository, see PostgresUserRepository.Purge
+func (r *SQLiteUserRepository) Purge(ctx context.Context, id int, expectedVersion int64) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.conn(ctx).ExecContext(ctx, users_sql.PurgeSQL, id, expectedVersion)
+	return r.checkAffected(ctx, result, err)
+}
+
+// scanUser scans a single user row as returned by the get and write statements
+func (r *SQLiteUserRepository) scanUser(ctx context.Context, row *sql.Row) (*models.UserOutput, error) {
+	user := &models.UserOutput{}
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.Version)
+	if err != nil {
+		return nil, r.mapError(ctx, err)
+	}
+	return user, nil
+}
+
+// checkAffected maps the outcome of a single row write, returning
+// models.ErrUserNotFound when no row was affected
+func (r *SQLiteUserRepository) checkAffected(ctx context.Context, result sql.Result, err error) error {
+	if err != nil {
+		return r.mapError(ctx, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
+}
+
+// mapError is mapError for the users table. SQLite does not say which
+// foreign key failed, but the only one of users is its role.
+func (r *SQLiteUserRepository) mapError(ctx context.Context, err error) error {
+	err = mapError(ctx, err, models.ErrUserNotFound)
+	if errors.Is(err, models.ErrReferenceViolation) {
+		return models.ErrInvalidRole
+	}
+	return err
+}
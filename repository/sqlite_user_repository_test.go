@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"goapi/config"
+)
+
+// newSQLiteDB opens a new database file in a temporary directory and
+// creates the schema of the SQLite migrations
+func newSQLiteDB(t *testing.T) (*sql.DB, *config.DBConfig) {
+	cfg := &config.DBConfig{SQLitePath: filepath.Join(t.TempDir(), "goapi.db")}
+	db, err := sql.Open("sqlite", cfg.GetSQLiteConnectionString())
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	schema, err := os.ReadFile("../migrations/sqlite/up/001_create_schema.sql")
+	if err != nil {
+		t.Fatalf("reading the schema: %v", err)
+	}
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatalf("creating the schema: %v", err)
+	}
+	return db, cfg
+}
+
+func newSQLiteRepositories(t *testing.T) *Repositories {
+	db, _ := newSQLiteDB(t)
+	return NewSQLiteRepositories(NewDB(db, 0), 0)
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	testUserRepository(t, newSQLiteRepositories)
+}
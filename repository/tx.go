@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"goapi/logger"
+	"goapi/models"
+
+	"github.com/lib/pq"
+)
+
+// TxManager runs units of work in a database transaction. Repository calls
+// made with the context handed to fn join the transaction.
+type TxManager interface {
+	// WithTx runs fn in a transaction, committing when it returns nil and
+	// rolling back otherwise. Nested calls run in a savepoint, so a failed
+	// inner unit of work does not abort the outer one. The outermost call
+	// retries fn on serialization failures and deadlocks, so fn must not
+	// have effects outside the database.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// txRetryDelay is the delay before the first retry, doubled after each one
+const txRetryDelay = 10 * time.Millisecond
+
+type txKey struct{}
+
+// txState is the transaction of a context and how deeply it is nested
+type txState struct {
+	tx    *sql.Tx
+	depth int
+}
+
+// txFromContext returns the transaction ctx runs in, or nil
+func txFromContext(ctx context.Context) *txState {
+	state, _ := ctx.Value(txKey{}).(*txState)
+	return state
+}
+
+// PostgresTxManager implements TxManager for PostgreSQL. SQLite supports
+// the same statements, so it serves SQLite too; a database that stays
+// locked past the busy timeout counts as a conflict there.
+type PostgresTxManager struct {
+	db         *DB
+	maxRetries int
+}
+
+// NewPostgresTxManager creates a new PostgresTxManager that retries a
+// conflicting transaction up to maxRetries times
+func NewPostgresTxManager(db *DB, maxRetries int) *PostgresTxManager {
+	return &PostgresTxManager{db: db, maxRetries: maxRetries}
+}
+
+// WithTx implements the WithTx method of TxManager
+func (m *PostgresTxManager) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if state := txFromContext(ctx); state != nil {
+		return m.withSavepoint(ctx, state, fn)
+	}
+
+	delay := txRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := m.run(ctx, fn)
+		if err == nil || !isRetryable(err) || attempt >= m.maxRetries {
+			return err
+		}
+
+		logger.FromContext(ctx).Component(logComponent).Debug("Transaction conflict, retrying", "attempt", attempt+1, "max_attempts", m.maxRetries+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// run runs fn in a new transaction
+func (m *PostgresTxManager) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return mapError(ctx, err, sql.ErrNoRows)
+	}
+	// Reads after the transaction must see what it wrote
+	m.db.wrote(ctx)
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, &txState{tx: tx})); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			logger.FromContext(ctx).Component(logComponent).Warn("Failed to roll back transaction", "error", rbErr)
+		}
+		return err
+	}
+
+	return mapError(ctx, tx.Commit(), sql.ErrNoRows)
+}
+
+// withSavepoint runs fn in a savepoint of the transaction of ctx
+func (m *PostgresTxManager) withSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	state.depth++
+	defer func() { state.depth-- }()
+	savepoint := fmt.Sprintf("sp_%d", state.depth)
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return mapError(ctx, err, sql.ErrNoRows)
+	}
+
+	if err := fn(ctx); err != nil {
+		// Roll back even when ctx is done, the outer unit of work may go on
+		if _, rbErr := state.tx.ExecContext(context.WithoutCancel(ctx), "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			logger.FromContext(ctx).Component(logComponent).Warn("Failed to roll back to savepoint", "savepoint", savepoint, "error", rbErr)
+		}
+		return err
+	}
+
+	_, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return mapError(ctx, err, sql.ErrNoRows)
+}
+
+// isRetryable reports whether err means the transaction lost a conflict with
+// another one and may succeed when run again
+func isRetryable(err error) bool {
+	if errors.Is(err, models.ErrTransactionConflict) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == pgSerializationFailure || pqErr.Code == pgDeadlockDetected)
+}
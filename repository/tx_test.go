@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"goapi/models"
+
+	"github.com/lib/pq"
+)
+
+// newStubTxManager returns a transaction manager on a stub database whose
+// first failCommits commits fail with a serialization failure
+func newStubTxManager(t *testing.T, failCommits, maxRetries int) (*PostgresTxManager, *stubConnector) {
+	connector := &stubConnector{failCommits: failCommits}
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+	return NewPostgresTxManager(NewDB(db, 0), maxRetries), connector
+}
+
+func TestWithTx_CommitsOrRollsBack(t *testing.T) {
+	manager, connector := newStubTxManager(t, 0, 0)
+
+	var inTx bool
+	if err := manager.WithTx(context.Background(), func(ctx context.Context) error {
+		inTx = txFromContext(ctx) != nil
+		return nil
+	}); err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	if !inTx {
+		t.Error("WithTx() did not hand fn a transaction")
+	}
+
+	failure := errors.New("validation failed")
+	if err := manager.WithTx(context.Background(), func(ctx context.Context) error { return failure }); !errors.Is(err, failure) {
+		t.Fatalf("WithTx() error = %v, want %v", err, failure)
+	}
+
+	want := []string{"BEGIN", "COMMIT", "BEGIN", "ROLLBACK"}
+	if got := connector.statements(); !slices.Equal(got, want) {
+		t.Errorf("WithTx() ran %q, want %q", got, want)
+	}
+}
+
+func TestWithTx_NestedCallsUseSavepoints(t *testing.T) {
+	manager, connector := newStubTxManager(t, 0, 0)
+	failure := errors.New("inner failure")
+
+	err := manager.WithTx(context.Background(), func(ctx context.Context) error {
+		if err := manager.WithTx(ctx, func(ctx context.Context) error {
+			// Deeper savepoints get their own names
+			return manager.WithTx(ctx, func(ctx context.Context) error { return nil })
+		}); err != nil {
+			return err
+		}
+
+		// A failed inner unit of work leaves the outer one going
+		if err := manager.WithTx(ctx, func(ctx context.Context) error { return failure }); !errors.Is(err, failure) {
+			t.Errorf("nested WithTx() error = %v, want %v", err, failure)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+
+	want := []string{
+		"BEGIN",
+		"SAVEPOINT sp_1",
+		"SAVEPOINT sp_2",
+		"RELEASE SAVEPOINT sp_2",
+		"RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT sp_1",
+		"ROLLBACK TO SAVEPOINT sp_1",
+		"COMMIT",
+	}
+	if got := connector.statements(); !slices.Equal(got, want) {
+		t.Errorf("WithTx() ran %q, want %q", got, want)
+	}
+}
+
+func TestWithTx_RetriesConflicts(t *testing.T) {
+	tests := []struct {
+		name        string
+		failCommits int
+		maxRetries  int
+		wantRuns    int
+		wantErr     error
+	}{
+		{name: "no conflict", failCommits: 0, maxRetries: 3, wantRuns: 1},
+		{name: "succeeds on a retry", failCommits: 2, maxRetries: 3, wantRuns: 3},
+		{name: "gives up after the retries", failCommits: 5, maxRetries: 2, wantRuns: 3, wantErr: models.ErrTransactionConflict},
+		{name: "retries disabled", failCommits: 1, maxRetries: 0, wantRuns: 1, wantErr: models.ErrTransactionConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, _ := newStubTxManager(t, tt.failCommits, tt.maxRetries)
+
+			runs := 0
+			err := manager.WithTx(context.Background(), func(ctx context.Context) error {
+				runs++
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WithTx() error = %v, want %v", err, tt.wantErr)
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("WithTx() ran fn %d times, want %d", runs, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestWithTx_RetriesConflictsReportedByFn(t *testing.T) {
+	manager, connector := newStubTxManager(t, 0, 3)
+
+	runs := 0
+	err := manager.WithTx(context.Background(), func(ctx context.Context) error {
+		runs++
+		if runs == 1 {
+			return &pq.Error{Code: pgDeadlockDetected}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+
+	want := []string{"BEGIN", "ROLLBACK", "BEGIN", "COMMIT"}
+	if got := connector.statements(); runs != 2 || !slices.Equal(got, want) {
+		t.Errorf("WithTx() ran fn %d times and %q, want 2 and %q", runs, got, want)
+	}
+}
+
+func TestWithTx_DoesNotRetryOtherErrors(t *testing.T) {
+	manager, _ := newStubTxManager(t, 0, 3)
+
+	runs := 0
+	err := manager.WithTx(context.Background(), func(ctx context.Context) error {
+		runs++
+		return models.ErrEmailTaken
+	})
+	if !errors.Is(err, models.ErrEmailTaken) || runs != 1 {
+		t.Errorf("WithTx() error = %v after %d runs, want %v after 1", err, runs, models.ErrEmailTaken)
+	}
+}
+
+func TestWithTx_RollsBackOnPanic(t *testing.T) {
+	manager, connector := newStubTxManager(t, 0, 0)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("WithTx() swallowed the panic")
+		}
+		want := []string{"BEGIN", "ROLLBACK"}
+		if got := connector.statements(); !slices.Equal(got, want) {
+			t.Errorf("WithTx() ran %q, want %q", got, want)
+		}
+	}()
+	manager.WithTx(context.Background(), func(ctx context.Context) error { panic("boom") })
+}
@@ -1,78 +1,142 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"goapi/logger"
 	"goapi/models"
 	"goapi/repository/users_sql"
 )
 
-// UserRepository defines the interface for user data operations
+// UserRepository defines the interface for user data operations. Methods
+// return domain errors: models.ErrUserNotFound for missing users and the
+// errors of mapError for database failures and cancellation.
 type UserRepository interface {
-	Create(user *models.UserInput) (*models.UserOutput, error)
-	GetByID(id int) (*models.UserOutput, error)
-	List(params ListParams) ([]*models.UserOutput, int64, error)
-	Update(user *models.UserOutput) error
-	Delete(id int) error
+	Create(ctx context.Context, user *models.UserInput, passwordHash string) (*models.UserOutput, error)
+	GetByID(ctx context.Context, id int) (*models.UserOutput, error)
+	GetByIDIncludingDeleted(ctx context.Context, id int) (*models.UserOutput, error)
+	GetCredentialsByEmail(ctx context.Context, email string) (*models.UserCredentials, error)
+	List(ctx context.Context, params ListParams) ([]*models.UserOutput, error)
+	Count(ctx context.Context, params ListParams) (int64, error)
+	Update(ctx context.Context, user *models.UserOutput, expectedVersion int64) (*models.UserOutput, error)
+	Patch(ctx context.Context, id int, patch *models.UserPatch, expectedVersion int64) (*models.UserOutput, error)
+	SetPassword(ctx context.Context, id int, passwordHash string, expectedVersion int64) (*models.UserOutput, error)
+	Delete(ctx context.Context, id int, expectedVersion int64) error
+	Restore(ctx context.Context, id int) (*models.UserOutput, error)
+	Purge(ctx context.Context, id int, expectedVersion int64) error
 }
 
 // PostgresUserRepository implements UserRepository for PostgreSQL
 type PostgresUserRepository struct {
-	db *sql.DB
+	db *DB
 }
 
 // NewPostgresUserRepository creates a new PostgresUserRepository
-func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
+func NewPostgresUserRepository(db *DB) *PostgresUserRepository {
 	return &PostgresUserRepository{db: db}
 }
 
 // Create implements the Create method of UserRepository
-func (r *PostgresUserRepository) Create(user *models.UserInput) (*models.UserOutput, error) {
+func (r *PostgresUserRepository) Create(ctx context.Context, user *models.UserInput, passwordHash string) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+	r.db.wrote(ctx)
+
 	query := users_sql.CreateUserSQL
 	var userResponse models.UserOutput
+	// Users created without a password cannot log in until one is set
+	hash := sql.NullString{String: passwordHash, Valid: passwordHash != ""}
 	// Execute the query and scan the result into the userResponse struct
-	err := r.db.QueryRow(query, user.Name, user.Email).Scan(&userResponse.ID, &userResponse.Name, &userResponse.Email, &userResponse.CreatedAt, &userResponse.UpdatedAt)
+	err := r.db.conn(ctx).QueryRowContext(ctx, query, user.Name, user.Email, hash, user.Role).Scan(&userResponse.ID, &userResponse.Name, &userResponse.Email, &userResponse.Role, &userResponse.CreatedAt, &userResponse.UpdatedAt, &userResponse.Version)
 	if err != nil {
-		return nil, err
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
 	}
 	return &userResponse, nil
 }
 
 // GetByID implements the GetByID method of UserRepository
-func (r *PostgresUserRepository) GetByID(id int) (*models.UserOutput, error) {
+func (r *PostgresUserRepository) GetByID(ctx context.Context, id int) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	user := &models.UserOutput{}
 	query := users_sql.GetByIDSQL
-	logger.Debug("Executing query: %s with id: %d", query, id)
+	log := logger.FromContext(ctx).Component(logComponent)
+	log.Debug("Executing query", "query", query, "id", id)
 
-	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := r.db.reader(ctx).QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.Version)
 	if err != nil {
-		logger.Error("Error retrieving user with id %d: %v", id, err)
-		return nil, err
+		log.Error("Error retrieving user", "id", id, "error", err)
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
+	}
+	log.Debug("User retrieved", "user", user)
+	return user, nil
+}
+
+// GetByIDIncludingDeleted implements the GetByIDIncludingDeleted method of
+// UserRepository. Unlike GetByID it also finds soft deleted users, with their
+// deleted_at, e.g. to check a precondition before purging one.
+func (r *PostgresUserRepository) GetByIDIncludingDeleted(ctx context.Context, id int) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	user := &models.UserOutput{}
+	query := users_sql.GetByIDIncludingDeletedSQL
+	logger.FromContext(ctx).Component(logComponent).Debug("Executing query", "query", query, "id", id)
+
+	err := r.db.reader(ctx).QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.Version, &user.DeletedAt)
+	if err != nil {
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
 	}
-	logger.Debug("User retrieved: %+v", user)
 	return user, nil
 }
 
+// GetCredentialsByEmail implements the GetCredentialsByEmail method of UserRepository
+func (r *PostgresUserRepository) GetCredentialsByEmail(ctx context.Context, email string) (*models.UserCredentials, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	credentials := &models.UserCredentials{}
+	var passwordHash sql.NullString
+
+	err := r.db.conn(ctx).QueryRowContext(ctx, users_sql.GetCredentialsByEmailSQL, email).Scan(&credentials.ID, &credentials.Email, &passwordHash, &credentials.Role)
+	if err != nil {
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
+	}
+	credentials.PasswordHash = passwordHash.String
+	return credentials, nil
+}
+
 // ListParams represents the parameters for listing users
 type ListParams struct {
 	Limit   int
 	Offset  int
-	Name    string
-	Email   string
-	OrderBy string
+	Filters []users_sql.Filter
+	// Sort must end with id, see users_sql.NormalizeSort
+	Sort []users_sql.SortField
+	// Cursor enables keyset pagination; rows come back in reverse order when it pages backward
+	Cursor *users_sql.Cursor
+	// Deleted lists soft deleted users instead of live ones
+	Deleted bool
 }
 
 // List implements the List method of UserRepository
-func (r *PostgresUserRepository) List(params ListParams) ([]*models.UserOutput, int64, error) {
-	query := users_sql.GetListSQL(params.OrderBy)
-	rows, err := r.db.Query(query, params.Limit, params.Offset, params.Name, params.Email)
+func (r *PostgresUserRepository) List(ctx context.Context, params ListParams) ([]*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query, args, err := users_sql.BuildListSQL(users_sql.Postgres, params.Filters, params.Sort, params.Cursor, params.Limit, params.Offset, params.Deleted)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
 	}
 	defer rows.Close()
 
 	var users []*models.UserOutput
-	var totalCount int64
 
 	for rows.Next() {
 		user := &models.UserOutput{}
@@ -80,32 +144,152 @@ func (r *PostgresUserRepository) List(params ListParams) ([]*models.UserOutput,
 			&user.ID,
 			&user.Name,
 			&user.Email,
+			&user.Role,
 			&user.CreatedAt,
 			&user.UpdatedAt,
-			&totalCount,
+			&user.DeletedAt,
 		); err != nil {
-			return nil, 0, err
+			return nil, mapError(ctx, err, models.ErrUserNotFound)
 		}
 		users = append(users, user)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
 	}
 
-	return users, totalCount, nil
+	return users, nil
 }
 
-// Update implements the Update method of UserRepository
-func (r *PostgresUserRepository) Update(user *models.UserOutput) error {
+// Count implements the Count method of UserRepository
+func (r *PostgresUserRepository) Count(ctx context.Context, params ListParams) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query, args, err := users_sql.BuildCountSQL(users_sql.Postgres, params.Filters, params.Deleted)
+	if err != nil {
+		return 0, err
+	}
+
+	var totalCount int64
+	err = r.db.reader(ctx).QueryRowContext(ctx, query, args...).Scan(&totalCount)
+	return totalCount, mapError(ctx, err, models.ErrUserNotFound)
+}
+
+// Update implements the Update method of UserRepository and returns the
+// persisted user. A non-zero expectedVersion only updates the user if it is
+// still at that version.
+func (r *PostgresUserRepository) Update(ctx context.Context, user *models.UserOutput, expectedVersion int64) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+	r.db.wrote(ctx)
+
 	query := users_sql.UpdateSQL
-	_, err := r.db.Exec(query, user.Name, user.Email, user.ID)
-	return err
+	updated := &models.UserOutput{}
+	err := r.db.conn(ctx).QueryRowContext(ctx, query, user.Name, user.Email, user.Role, user.ID, expectedVersion).Scan(&updated.ID, &updated.Name, &updated.Email, &updated.Role, &updated.CreatedAt, &updated.UpdatedAt, &updated.Version)
+	if err != nil {
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
+	}
+	return updated, nil
 }
 
-// Delete implements the Delete method of UserRepository
-func (r *PostgresUserRepository) Delete(id int) error {
+// SetPassword implements the SetPassword method of UserRepository. It
+// replaces the password hash of a live user and returns the updated user.
+// A non-zero expectedVersion only sets it if the user is still at that version.
+func (r *PostgresUserRepository) SetPassword(ctx context.Context, id int, passwordHash string, expectedVersion int64) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+	r.db.wrote(ctx)
+
+	query := users_sql.SetPasswordSQL
+	updated := &models.UserOutput{}
+	err := r.db.conn(ctx).QueryRowContext(ctx, query, passwordHash, id, expectedVersion).Scan(&updated.ID, &updated.Name, &updated.Email, &updated.Role, &updated.CreatedAt, &updated.UpdatedAt, &updated.Version)
+	if err != nil {
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
+	}
+	return updated, nil
+}
+
+// Patch implements the Patch method of UserRepository. A non-zero
+// expectedVersion only patches the user if it is still at that version.
+func (r *PostgresUserRepository) Patch(ctx context.Context, id int, patch *models.UserPatch, expectedVersion int64) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+	r.db.wrote(ctx)
+
+	var name, email, role sql.NullString
+	if patch.Name != nil {
+		name = sql.NullString{String: *patch.Name, Valid: true}
+	}
+	if patch.Email != nil {
+		email = sql.NullString{String: *patch.Email, Valid: true}
+	}
+	if patch.Role != nil {
+		role = sql.NullString{String: string(*patch.Role), Valid: true}
+	}
+
+	user := &models.UserOutput{}
+	err := r.db.conn(ctx).QueryRowContext(ctx, users_sql.PatchSQL, name, email, role, id, expectedVersion).Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.Version)
+	if err != nil {
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// Delete implements the Delete method of UserRepository. It returns
+// models.ErrUserNotFound when the user does not exist or is already deleted.
+// A non-zero expectedVersion only deletes the user if it is still at that version.
+func (r *PostgresUserRepository) Delete(ctx context.Context, id int, expectedVersion int64) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+	r.db.wrote(ctx)
+
 	query := users_sql.DeleteSQL
-	_, err := r.db.Exec(query, id)
-	return err
+	result, err := r.db.conn(ctx).ExecContext(ctx, query, id, expectedVersion)
+	return r.checkAffected(ctx, result, err)
+}
+
+// Restore implements the Restore method of UserRepository. It returns
+// models.ErrUserNotFound when the user does not exist or is not deleted.
+func (r *PostgresUserRepository) Restore(ctx context.Context, id int) (*models.UserOutput, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+	r.db.wrote(ctx)
+
+	user := &models.UserOutput{}
+	err := r.db.conn(ctx).QueryRowContext(ctx, users_sql.RestoreSQL, id).Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.Version)
+	if err != nil {
+		return nil, mapError(ctx, err, models.ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// Purge implements the Purge method of UserRepository. It permanently
+// deletes the user whether or not it was soft deleted. A non-zero
+// expectedVersion only deletes the user if it is still at that version.
+func (r *PostgresUserRepository) Purge(ctx context.Context, id int, expectedVersion int64) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+	r.db.wrote(ctx)
+
+	query := users_sql.PurgeSQL
+	result, err := r.db.conn(ctx).ExecContext(ctx, query, id, expectedVersion)
+	return r.checkAffected(ctx, result, err)
+}
+
+// checkAffected maps the outcome of a single row write, returning
+// models.ErrUserNotFound when no row was affected
+func (r *PostgresUserRepository) checkAffected(ctx context.Context, result sql.Result, err error) error {
+	if err != nil {
+		return mapError(ctx, err, models.ErrUserNotFound)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return models.ErrUserNotFound
+	}
+	return nil
 }
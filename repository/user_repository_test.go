@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"goapi/models"
+	"goapi/repository/users_sql"
+)
+
+// testUserRepository checks the behavior every UserRepository backend must
+// share. newRepos returns an empty store for each subtest.
+func testUserRepository(t *testing.T, newRepos func(t *testing.T) *Repositories) {
+	ctx := context.Background()
+
+	create := func(t *testing.T, users UserRepository, name, email string, role models.Role) *models.UserOutput {
+		t.Helper()
+		user, err := users.Create(ctx, &models.UserInput{Name: name, Email: email, Role: role}, "hash-"+name)
+		if err != nil {
+			t.Fatalf("Create(%q) error = %v", email, err)
+		}
+		return user
+	}
+
+	names := func(users []*models.UserOutput) []string {
+		var result []string
+		for _, user := range users {
+			result = append(result, user.Name)
+		}
+		return result
+	}
+
+	t.Run("create and get", func(t *testing.T) {
+		users := newRepos(t).Users
+		created := create(t, users, "Jane Doe", "jane@example.com", models.RoleUser)
+
+		if created.ID == nil || created.Version != 1 {
+			t.Fatalf("Create() = id %v version %d, want an id and version 1", created.ID, created.Version)
+		}
+		if created.CreatedAt == "" || created.UpdatedAt != created.CreatedAt {
+			t.Errorf("Create() timestamps = %q, %q, want equal and set", created.CreatedAt, created.UpdatedAt)
+		}
+
+		got, err := users.GetByID(ctx, int(*created.ID))
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		if got.Email != "jane@example.com" || got.Role != models.RoleUser || got.CreatedAt != created.CreatedAt {
+			t.Errorf("GetByID() = %+v, want the created user", got)
+		}
+
+		credentials, err := users.GetCredentialsByEmail(ctx, "jane@example.com")
+		if err != nil || credentials.PasswordHash != "hash-Jane Doe" {
+			t.Errorf("GetCredentialsByEmail() = %+v, %v, want the stored hash", credentials, err)
+		}
+
+		if _, err := users.GetByID(ctx, 999); !errors.Is(err, models.ErrUserNotFound) {
+			t.Errorf("GetByID() of a missing user error = %v, want %v", err, models.ErrUserNotFound)
+		}
+	})
+
+	t.Run("email is unique among live users", func(t *testing.T) {
+		users := newRepos(t).Users
+		first := create(t, users, "Jane Doe", "jane@example.com", models.RoleUser)
+		other := create(t, users, "John Doe", "john@example.com", models.RoleUser)
+
+		if _, err := users.Create(ctx, &models.UserInput{Name: "Jane Again", Email: "jane@example.com", Role: models.RoleUser}, "hash"); !errors.Is(err, models.ErrEmailTaken) {
+			t.Fatalf("Create() with a taken email error = %v, want %v", err, models.ErrEmailTaken)
+		}
+		email := "jane@example.com"
+		if _, err := users.Patch(ctx, int(*other.ID), &models.UserPatch{Email: &email}, 0); !errors.Is(err, models.ErrEmailTaken) {
+			t.Fatalf("Patch() to a taken email error = %v, want %v", err, models.ErrEmailTaken)
+		}
+
+		// A deleted user frees its email, and cannot be restored while it is taken
+		if err := users.Delete(ctx, int(*first.ID), 0); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		create(t, users, "Jane Again", "jane@example.com", models.RoleUser)
+		if _, err := users.Restore(ctx, int(*first.ID)); !errors.Is(err, models.ErrEmailTaken) {
+			t.Errorf("Restore() with a taken email error = %v, want %v", err, models.ErrEmailTaken)
+		}
+	})
+
+	t.Run("writes bump the version", func(t *testing.T) {
+		users := newRepos(t).Users
+		created := create(t, users, "Jane Doe", "jane@example.com", models.RoleUser)
+		id := int(*created.ID)
+
+		name := "Jane Smith"
+		patched, err := users.Patch(ctx, id, &models.UserPatch{Name: &name}, created.Version)
+		if err != nil {
+			t.Fatalf("Patch() error = %v", err)
+		}
+		if patched.Name != name || patched.Email != created.Email || patched.Version != 2 {
+			t.Errorf("Patch() = %+v, want the new name, the old email and version 2", patched)
+		}
+		createdAt, _ := time.Parse(time.RFC3339Nano, created.UpdatedAt)
+		updatedAt, _ := time.Parse(time.RFC3339Nano, patched.UpdatedAt)
+		if patched.CreatedAt != created.CreatedAt || updatedAt.Before(createdAt) {
+			t.Errorf("Patch() timestamps = %q, %q, want created_at kept and updated_at moved on", patched.CreatedAt, patched.UpdatedAt)
+		}
+
+		if _, err := users.Patch(ctx, id, &models.UserPatch{Name: &name}, created.Version); !errors.Is(err, models.ErrUserNotFound) {
+			t.Errorf("Patch() at a stale version error = %v, want %v", err, models.ErrUserNotFound)
+		}
+
+		updated, err := users.Update(ctx, &models.UserOutput{ID: created.ID, Name: "Jane Roe", Email: "roe@example.com"}, patched.Version)
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		if updated.Role != models.RoleUser || updated.Version != 3 {
+			t.Errorf("Update() = %+v, want the role kept and version 3", updated)
+		}
+
+		withPassword, err := users.SetPassword(ctx, id, "new-hash", updated.Version)
+		if err != nil {
+			t.Fatalf("SetPassword() error = %v", err)
+		}
+		if withPassword.Version != 4 {
+			t.Errorf("SetPassword() version = %d, want 4", withPassword.Version)
+		}
+		credentials, err := users.GetCredentialsByEmail(ctx, "roe@example.com")
+		if err != nil || credentials.PasswordHash != "new-hash" {
+			t.Errorf("GetCredentialsByEmail() after SetPassword() = %+v, %v, want the new hash", credentials, err)
+		}
+		if _, err := users.SetPassword(ctx, id, "other-hash", updated.Version); !errors.Is(err, models.ErrUserNotFound) {
+			t.Errorf("SetPassword() at a stale version error = %v, want %v", err, models.ErrUserNotFound)
+		}
+	})
+
+	t.Run("soft deleted users", func(t *testing.T) {
+		users := newRepos(t).Users
+		created := create(t, users, "Jane Doe", "jane@example.com", models.RoleUser)
+		create(t, users, "John Doe", "john@example.com", models.RoleUser)
+		id := int(*created.ID)
+
+		if err := users.Delete(ctx, id, created.Version+1); !errors.Is(err, models.ErrUserNotFound) {
+			t.Fatalf("Delete() at a stale version error = %v, want %v", err, models.ErrUserNotFound)
+		}
+		if err := users.Delete(ctx, id, created.Version); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if err := users.Delete(ctx, id, 0); !errors.Is(err, models.ErrUserNotFound) {
+			t.Errorf("second Delete() error = %v, want %v", err, models.ErrUserNotFound)
+		}
+
+		if _, err := users.GetByID(ctx, id); !errors.Is(err, models.ErrUserNotFound) {
+			t.Errorf("GetByID() of a deleted user error = %v, want %v", err, models.ErrUserNotFound)
+		}
+		if _, err := users.GetCredentialsByEmail(ctx, "jane@example.com"); !errors.Is(err, models.ErrUserNotFound) {
+			t.Errorf("GetCredentialsByEmail() of a deleted user error = %v, want %v", err, models.ErrUserNotFound)
+		}
+		if _, err := users.SetPassword(ctx, id, "hash", 0); !errors.Is(err, models.ErrUserNotFound) {
+			t.Errorf("SetPassword() of a deleted user error = %v, want %v", err, models.ErrUserNotFound)
+		}
+		deleted, err := users.GetByIDIncludingDeleted(ctx, id)
+		if err != nil || deleted.DeletedAt == nil {
+			t.Fatalf("GetByIDIncludingDeleted() = %+v, %v, want the user with deleted_at", deleted, err)
+		}
+
+		sort := users_sql.NormalizeSort(nil)
+		live, err := users.List(ctx, ListParams{Limit: 10, Sort: sort})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		trash, err := users.List(ctx, ListParams{Limit: 10, Sort: sort, Deleted: true})
+		if err != nil {
+			t.Fatalf("List(deleted) error = %v", err)
+		}
+		if got := names(live); !slices.Equal(got, []string{"John Doe"}) {
+			t.Errorf("List() = %q, want only the live user", got)
+		}
+		if got := names(trash); !slices.Equal(got, []string{"Jane Doe"}) || trash[0].DeletedAt == nil {
+			t.Errorf("List(deleted) = %q, want only the deleted user with deleted_at", got)
+		}
+
+		restored, err := users.Restore(ctx, id)
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if restored.DeletedAt != nil || restored.Version != created.Version+1 {
+			t.Errorf("Restore() = %+v, want a live user at the next version", restored)
+		}
+
+		if err := users.Purge(ctx, id, 0); err != nil {
+			t.Fatalf("Purge() error = %v", err)
+		}
+		if _, err := users.GetByIDIncludingDeleted(ctx, id); !errors.Is(err, models.ErrUserNotFound) {
+			t.Errorf("GetByIDIncludingDeleted() of a purged user error = %v, want %v", err, models.ErrUserNotFound)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		users := newRepos(t).Users
+		create(t, users, "Alice Admin", "alice@example.com", models.RoleAdmin)
+		bob := create(t, users, "Bob Manager", "bob@example.com", models.RoleManager)
+		create(t, users, "Carol User", "carol@corp.test", models.RoleUser)
+
+		tests := []struct {
+			name    string
+			filters []users_sql.Filter
+			want    []string
+		}{
+			{name: "none", want: []string{"Alice Admin", "Bob Manager", "Carol User"}},
+			{name: "equal", filters: []users_sql.Filter{{Field: "role", Op: users_sql.OpEq, Value: "admin"}}, want: []string{"Alice Admin"}},
+			{name: "not equal", filters: []users_sql.Filter{{Field: "role", Op: users_sql.OpNe, Value: "admin"}}, want: []string{"Bob Manager", "Carol User"}},
+			{name: "like is case insensitive", filters: []users_sql.Filter{{Field: "email", Op: users_sql.OpLike, Value: "EXAMPLE"}}, want: []string{"Alice Admin", "Bob Manager"}},
+			{name: "like is literal", filters: []users_sql.Filter{{Field: "name", Op: users_sql.OpLike, Value: "_"}}},
+			{name: "in", filters: []users_sql.Filter{{Field: "role", Op: users_sql.OpIn, Value: "user,manager"}}, want: []string{"Bob Manager", "Carol User"}},
+			{name: "greater than", filters: []users_sql.Filter{{Field: "id", Op: users_sql.OpGt, Value: "1"}}, want: []string{"Bob Manager", "Carol User"}},
+			{name: "combined", filters: []users_sql.Filter{
+				{Field: "email", Op: users_sql.OpLike, Value: "example"},
+				{Field: "id", Op: users_sql.OpGte, Value: "2"},
+			}, want: []string{"Bob Manager"}},
+			{name: "time after", filters: []users_sql.Filter{{Field: "created_at", Op: users_sql.OpGt, Value: "2000-01-01"}}, want: []string{"Alice Admin", "Bob Manager", "Carol User"}},
+			{name: "time before", filters: []users_sql.Filter{{Field: "updated_at", Op: users_sql.OpLt, Value: "2000-01-01T00:00:00Z"}}},
+			{name: "time up to a created user", filters: []users_sql.Filter{{Field: "created_at", Op: users_sql.OpLte, Value: bob.CreatedAt}, {Field: "id", Op: users_sql.OpLte, Value: "2"}}, want: []string{"Alice Admin", "Bob Manager"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				params := ListParams{Limit: 10, Filters: tt.filters, Sort: users_sql.NormalizeSort(nil)}
+				listed, err := users.List(ctx, params)
+				if err != nil {
+					t.Fatalf("List() error = %v", err)
+				}
+				if got := names(listed); !slices.Equal(got, tt.want) {
+					t.Errorf("List() = %q, want %q", got, tt.want)
+				}
+
+				count, err := users.Count(ctx, params)
+				if err != nil {
+					t.Fatalf("Count() error = %v", err)
+				}
+				if count != int64(len(tt.want)) {
+					t.Errorf("Count() = %d, want %d", count, len(tt.want))
+				}
+			})
+		}
+	})
+
+	t.Run("sort and pages", func(t *testing.T) {
+		users := newRepos(t).Users
+		for _, user := range []struct{ name, email string }{
+			{"Carol", "carol@example.com"},
+			{"Alice", "alice@example.com"},
+			{"Bob", "bob@example.com"},
+			{"Alice", "alice2@example.com"},
+			{"Dave", "dave@example.com"},
+		} {
+			create(t, users, user.name, user.email, models.RoleUser)
+		}
+
+		byName := users_sql.NormalizeSort([]users_sql.SortField{{Field: "name"}})
+		byNameDesc := users_sql.NormalizeSort([]users_sql.SortField{{Field: "name", Desc: true}})
+
+		list := func(t *testing.T, params ListParams) []*models.UserOutput {
+			t.Helper()
+			listed, err := users.List(ctx, params)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			return listed
+		}
+		emails := func(users []*models.UserOutput) []string {
+			var result []string
+			for _, user := range users {
+				result = append(result, user.Email)
+			}
+			return result
+		}
+
+		// Equal names are ordered by id
+		all := list(t, ListParams{Limit: 10, Sort: byName})
+		want := []string{"alice@example.com", "alice2@example.com", "bob@example.com", "carol@example.com", "dave@example.com"}
+		if got := emails(all); !slices.Equal(got, want) {
+			t.Fatalf("List(name) = %q, want %q", got, want)
+		}
+		if got := names(list(t, ListParams{Limit: 10, Sort: byNameDesc})); !slices.Equal(got, []string{"Dave", "Carol", "Bob", "Alice", "Alice"}) {
+			t.Errorf("List(-name) = %q, want names descending", got)
+		}
+		if got := emails(list(t, ListParams{Limit: 2, Offset: 1, Sort: byName})); !slices.Equal(got, want[1:3]) {
+			t.Errorf("List(offset 1, limit 2) = %q, want %q", got, want[1:3])
+		}
+
+		cursorAt := func(user *models.UserOutput, backward bool) *users_sql.Cursor {
+			return &users_sql.Cursor{Sort: users_sql.FormatSort(byName), Values: []string{user.Name}, ID: *user.ID, Backward: backward}
+		}
+
+		// The cursor on the first Alice resumes on the second one
+		next := list(t, ListParams{Limit: 2, Sort: byName, Cursor: cursorAt(all[0], false)})
+		if got := emails(next); !slices.Equal(got, want[1:3]) {
+			t.Errorf("List(after the first row) = %q, want %q", got, want[1:3])
+		}
+
+		// Paging backward returns the rows before the cursor, nearest first
+		previous := list(t, ListParams{Limit: 2, Sort: byName, Cursor: cursorAt(all[3], true)})
+		if got := emails(previous); !slices.Equal(got, []string{want[2], want[1]}) {
+			t.Errorf("List(before the fourth row) = %q, want %q", got, []string{want[2], want[1]})
+		}
+
+		if got := list(t, ListParams{Limit: 2, Sort: byName, Cursor: cursorAt(all[4], false)}); len(got) != 0 {
+			t.Errorf("List(after the last row) = %q, want none", emails(got))
+		}
+	})
+}
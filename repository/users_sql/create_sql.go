@@ -5,15 +5,21 @@ const CreateUserSQL = `
 -- Params:
 --   $1: name (string)
 --   $2: email (string)
+--   $3: password_hash (string, nullable)
+--   $4: role (string)
 INSERT INTO users (
     name,
     email,
+    password_hash,
+    role,
     created_at,
     updated_at
 )
 VALUES (
     $1,
     $2,
+    $3,
+    $4,
     now(),
     now()
 )
@@ -21,6 +27,40 @@ RETURNING
     id,
     name,
     email,
+    role,
     created_at,
-    updated_at
+    updated_at,
+    version
     `
+
+const SQLiteCreateUserSQL = `
+-- name: CreateUser (SQLite)
+-- Params:
+--   $1: name (string)
+--   $2: email (string)
+--   $3: password_hash (string, nullable)
+--   $4: role (string)
+INSERT INTO users (
+    name,
+    email,
+    password_hash,
+    role,
+    created_at,
+    updated_at
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    strftime('%Y-%m-%d %H:%M:%f', 'now')
+)
+RETURNING
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version`
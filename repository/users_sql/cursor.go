@@ -0,0 +1,37 @@
+package users_sql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Cursor marks a position in a sorted user list. It holds the sort key of
+// the row the page starts after (or before, when paging backward) and the
+// row id, which breaks ties between equal sort keys.
+type Cursor struct {
+	Sort     string   `json:"s"`
+	Values   []string `json:"v,omitempty"`
+	ID       int64    `json:"id"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+// Encode returns the opaque form of the cursor handed to clients
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by Encode
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cursor, nil
+}
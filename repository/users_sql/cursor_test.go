@@ -0,0 +1,67 @@
+package users_sql
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := &Cursor{Sort: "name ASC", Values: []string{"Jane Doe"}, ID: 42, Backward: true}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, cursor) {
+		t.Errorf("DecodeCursor() = %+v, want %+v", decoded, cursor)
+	}
+}
+
+func TestGetCursor(t *testing.T) {
+	byName := []SortField{{Field: "name"}}
+	nameCursor := (&Cursor{Sort: "name,id", Values: []string{"Jane Doe"}, ID: 42}).Encode()
+
+	tests := []struct {
+		name    string
+		params  SearchParams
+		wantID  int64
+		wantErr bool
+	}{
+		{name: "offset pagination ignores the cursor", params: SearchParams{Cursor: "garbage"}},
+		{name: "first page", params: SearchParams{CursorMode: true}},
+		{name: "matching sort", params: SearchParams{CursorMode: true, Cursor: nameCursor, Sort: byName}, wantID: 42},
+		{name: "not base64", params: SearchParams{CursorMode: true, Cursor: "not a cursor!", Sort: byName}, wantErr: true},
+		{
+			name:    "not JSON",
+			params:  SearchParams{CursorMode: true, Cursor: base64.RawURLEncoding.EncodeToString([]byte("{tampered")), Sort: byName},
+			wantErr: true,
+		},
+		{
+			name:    "tampered to drop the sort key",
+			params:  SearchParams{CursorMode: true, Cursor: (&Cursor{Sort: "name,id", ID: 42}).Encode(), Sort: byName},
+			wantErr: true,
+		},
+		{name: "default sort", params: SearchParams{CursorMode: true, Cursor: nameCursor}, wantErr: true},
+		{name: "different sort field", params: SearchParams{CursorMode: true, Cursor: nameCursor, Sort: []SortField{{Field: "email"}}}, wantErr: true},
+		{name: "different sort direction", params: SearchParams{CursorMode: true, Cursor: nameCursor, Sort: []SortField{{Field: "name", Desc: true}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := tt.params.GetCursor()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantID == 0 {
+				if !tt.wantErr && cursor != nil {
+					t.Errorf("GetCursor() = %+v, want nil", cursor)
+				}
+				return
+			}
+			if cursor == nil || cursor.ID != tt.wantID {
+				t.Errorf("GetCursor() = %+v, want id %d", cursor, tt.wantID)
+			}
+		})
+	}
+}
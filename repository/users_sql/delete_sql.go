@@ -4,7 +4,24 @@ const DeleteSQL = `
 -- name: SoftDeleteUser
 -- Params:
 --   $1: id (int64)
+--   $2: expected version (int64) - 0 skips the version check
 -- Returns: Number of rows affected
 UPDATE users
 SET deleted_at = NOW()
-WHERE id = $1`
+WHERE 
+    id = $1 AND
+    deleted_at IS NULL AND
+    ($2 = 0 OR version = $2)`
+
+const SQLiteDeleteSQL = `
+-- name: SoftDeleteUser (SQLite)
+-- Params:
+--   $1: id (int64)
+--   $2: expected version (int64) - 0 skips the version check
+-- Returns: Number of rows affected
+UPDATE users
+SET deleted_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE
+    id = $1 AND
+    deleted_at IS NULL AND
+    ($2 = 0 OR version = $2)`
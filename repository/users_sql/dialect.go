@@ -0,0 +1,11 @@
+package users_sql
+
+// Dialect is the SQL flavour a statement is written for
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	// SQLite stores timestamps as UTC text in the format of strftime's
+	// '%Y-%m-%d %H:%M:%f', which sorts chronologically
+	SQLite
+)
@@ -0,0 +1,262 @@
+package users_sql
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterOp is a comparison operator usable in a list filter
+type FilterOp string
+
+const (
+	OpEq   FilterOp = "eq"
+	OpNe   FilterOp = "ne"
+	OpGt   FilterOp = "gt"
+	OpGte  FilterOp = "gte"
+	OpLt   FilterOp = "lt"
+	OpLte  FilterOp = "lte"
+	OpLike FilterOp = "like"
+	OpIn   FilterOp = "in"
+)
+
+// sqlOperators maps the comparison operators to SQL
+var sqlOperators = map[FilterOp]string{
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpGt:  ">",
+	OpGte: ">=",
+	OpLt:  "<",
+	OpLte: "<=",
+}
+
+type columnType int
+
+const (
+	intColumn columnType = iota
+	textColumn
+	timeColumn
+)
+
+// column describes a users column that can be filtered and sorted on
+type column struct {
+	kind      columnType
+	ops       []FilterOp
+	defaultOp FilterOp
+}
+
+// columns is the whitelist of filterable and sortable columns. Only these
+// names are ever written into the SQL; values are always bound as parameters.
+var columns = map[string]column{
+	"id":         {kind: intColumn, ops: []FilterOp{OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn}, defaultOp: OpEq},
+	"name":       {kind: textColumn, ops: []FilterOp{OpEq, OpNe, OpLike, OpIn}, defaultOp: OpLike},
+	"email":      {kind: textColumn, ops: []FilterOp{OpEq, OpNe, OpLike, OpIn}, defaultOp: OpLike},
+	"role":       {kind: textColumn, ops: []FilterOp{OpEq, OpNe, OpIn}, defaultOp: OpEq},
+	"created_at": {kind: timeColumn, ops: []FilterOp{OpGt, OpGte, OpLt, OpLte}, defaultOp: OpGte},
+	"updated_at": {kind: timeColumn, ops: []FilterOp{OpGt, OpGte, OpLt, OpLte}, defaultOp: OpGte},
+}
+
+// reservedParams are query parameters that are not filters
+var reservedParams = map[string]bool{
+	"limit":         true,
+	"offset":        true,
+	"order":         true,
+	"sort":          true,
+	"cursor":        true,
+	"include_total": true,
+}
+
+// MaxInValues limits the number of values in an "in" filter
+const MaxInValues = 100
+
+// TimestampLayout is the form time filter values are bound in. Timestamps
+// are stored without time zone, in UTC.
+const TimestampLayout = "2006-01-02 15:04:05.999999"
+
+// Filter restricts the listed users, e.g. created_at[gte]=2024-01-01
+type Filter struct {
+	Field string
+	Op    FilterOp
+	Value string
+}
+
+// SortField is one key of a multi-field sort, e.g. -created_at
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+var filterKeyPattern = regexp.MustCompile(`^([a-z_]+)(?:\[([a-z]+)\])?$`)
+
+// ParseFilters extracts the filters from the query string. Keys are either
+// a column ("name=jo") using the column's default operator, or a column
+// with an operator ("created_at[lt]=2024-01-01").
+func ParseFilters(query url.Values) ([]Filter, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if !reservedParams[key] {
+			keys = append(keys, key)
+		}
+	}
+	// Keep the generated SQL stable
+	sort.Strings(keys)
+
+	var filters []Filter
+	for _, key := range keys {
+		match := filterKeyPattern.FindStringSubmatch(key)
+		if match == nil {
+			return nil, fmt.Errorf("invalid filter %q", key)
+		}
+
+		col, ok := columns[match[1]]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter field %q", match[1])
+		}
+
+		op := FilterOp(match[2])
+		if op == "" {
+			op = col.defaultOp
+		}
+
+		for _, value := range query[key] {
+			// An empty plain filter means no filter, as before
+			if value == "" && match[2] == "" {
+				continue
+			}
+			filter := Filter{Field: match[1], Op: op, Value: value}
+			if err := filter.Validate(); err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+	}
+
+	return filters, nil
+}
+
+// ParseSort parses a comma separated list of columns, each optionally
+// prefixed with "-" for descending order, e.g. "-created_at,name"
+func ParseSort(value string) ([]SortField, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		field := SortField{Field: strings.TrimLeft(part, "+-"), Desc: strings.HasPrefix(part, "-")}
+		if err := field.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("duplicate sort field %q", field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+
+	return fields, nil
+}
+
+// FormatSort returns the canonical string form of a sort, as accepted by ParseSort
+func FormatSort(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.Field
+		if field.Desc {
+			parts[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Validate checks the filter against the column whitelist
+func (f Filter) Validate() error {
+	_, err := f.args()
+	return err
+}
+
+// Args returns the filter values converted to the column type, as they are
+// bound: int64 for ids, strings in TimestampLayout for times and strings
+// otherwise. A like filter holds the plain substring.
+func (f Filter) Args() ([]interface{}, error) {
+	return f.args()
+}
+
+// ParseValue converts a raw value of a column, such as a cursor value, the
+// way Args converts filter values
+func ParseValue(field, value string) (interface{}, error) {
+	col, ok := columns[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field)
+	}
+	return col.parse(value)
+}
+
+// Validate checks the sort field against the column whitelist
+func (s SortField) Validate() error {
+	if _, ok := columns[s.Field]; !ok {
+		return fmt.Errorf("unknown sort field %q", s.Field)
+	}
+	return nil
+}
+
+// args validates the filter and converts its value to typed SQL arguments
+func (f Filter) args() ([]interface{}, error) {
+	col, ok := columns[f.Field]
+	if !ok {
+		return nil, fmt.Errorf("unknown filter field %q", f.Field)
+	}
+
+	allowed := false
+	for _, op := range col.ops {
+		if op == f.Op {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, fmt.Errorf("operator %q is not supported for field %q", f.Op, f.Field)
+	}
+
+	values := []string{f.Value}
+	if f.Op == OpIn {
+		values = strings.Split(f.Value, ",")
+		if len(values) > MaxInValues {
+			return nil, fmt.Errorf("filter %s[in] accepts at most %d values", f.Field, MaxInValues)
+		}
+	}
+
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		arg, err := col.parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for field %q: %v", value, f.Field, err)
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
+
+// parse converts a raw filter value to the column's type
+func (c column) parse(value string) (interface{}, error) {
+	switch c.kind {
+	case intColumn:
+		return strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	case timeColumn:
+		value = strings.TrimSpace(value)
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC().Format(TimestampLayout), nil
+			}
+		}
+		return nil, fmt.Errorf("expected an RFC 3339 timestamp or a YYYY-MM-DD date")
+	default:
+		return value, nil
+	}
+}
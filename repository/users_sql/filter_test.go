@@ -0,0 +1,144 @@
+package users_sql
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFilters(t *testing.T) {
+	tooMany := make([]string, MaxInValues+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint(i + 1)
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []Filter
+		wantErr string
+	}{
+		{name: "no filters", query: "limit=10&offset=0&sort=name&order=ASC&cursor=&include_total=true"},
+		{name: "default operator", query: "name=jo", want: []Filter{{Field: "name", Op: OpLike, Value: "jo"}}},
+		{name: "empty plain filter is ignored", query: "name=&email="},
+		{
+			name:  "explicit operators sorted by key",
+			query: "role[ne]=admin&created_at[gte]=2024-01-01",
+			want: []Filter{
+				{Field: "created_at", Op: OpGte, Value: "2024-01-01"},
+				{Field: "role", Op: OpNe, Value: "admin"},
+			},
+		},
+		{
+			name:  "repeated key",
+			query: "id[gt]=1&id[gt]=5",
+			want:  []Filter{{Field: "id", Op: OpGt, Value: "1"}, {Field: "id", Op: OpGt, Value: "5"}},
+		},
+		{name: "in", query: "id[in]=1,2,3", want: []Filter{{Field: "id", Op: OpIn, Value: "1,2,3"}}},
+		{name: "in at the value limit", query: "id[in]=" + strings.Join(tooMany[:MaxInValues], ","), want: []Filter{{Field: "id", Op: OpIn, Value: strings.Join(tooMany[:MaxInValues], ",")}}},
+		{name: "in over the value limit", query: "id[in]=" + strings.Join(tooMany, ","), wantErr: "at most 100 values"},
+		{name: "unknown field", query: "password_hash=x", wantErr: `unknown filter field "password_hash"`},
+		{name: "unknown operator", query: "name[regex]=x", wantErr: `operator "regex" is not supported`},
+		{name: "operator not allowed for the field", query: "created_at[like]=2024", wantErr: `operator "like" is not supported for field "created_at"`},
+		{name: "malformed key", query: "name[eq=x", wantErr: "invalid filter"},
+		{name: "uppercase key", query: "NAME=x", wantErr: "invalid filter"},
+		{name: "invalid integer", query: "id=abc", wantErr: `invalid value "abc" for field "id"`},
+		{name: "invalid timestamp", query: "updated_at[lt]=yesterday", wantErr: `invalid value "yesterday" for field "updated_at"`},
+		{name: "empty explicit filter is validated", query: "id[eq]=", wantErr: `invalid value "" for field "id"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+
+			got, err := ParseFilters(query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseFilters() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseFilters() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseFilters() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []SortField
+		wantErr string
+	}{
+		{name: "empty", value: ""},
+		{name: "single field", value: "name", want: []SortField{{Field: "name"}}},
+		{
+			name:  "mixed directions",
+			value: "-created_at, +name,email",
+			want:  []SortField{{Field: "created_at", Desc: true}, {Field: "name"}, {Field: "email"}},
+		},
+		{name: "unknown field", value: "name,password_hash", wantErr: `unknown sort field "password_hash"`},
+		{name: "duplicate field", value: "name,-name", wantErr: `duplicate sort field "name"`},
+		{name: "empty field", value: "name,", wantErr: `unknown sort field ""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSort(tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSort() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSort() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort() = %+v, want %+v", got, tt.want)
+			}
+			if len(got) > 0 && FormatSort(got) != strings.NewReplacer(" ", "", "+", "").Replace(tt.value) {
+				t.Errorf("FormatSort() = %q, want the canonical form of %q", FormatSort(got), tt.value)
+			}
+		})
+	}
+}
+
+func TestNormalizeSort(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []SortField
+		want   []SortField
+	}{
+		{name: "empty", want: []SortField{{Field: "id"}}},
+		{name: "appends id", fields: []SortField{{Field: "name", Desc: true}}, want: []SortField{{Field: "name", Desc: true}, {Field: "id"}}},
+		{name: "keeps an explicit id direction", fields: []SortField{{Field: "id", Desc: true}}, want: []SortField{{Field: "id", Desc: true}}},
+		{
+			name:   "drops fields after id",
+			fields: []SortField{{Field: "role"}, {Field: "id"}, {Field: "name"}},
+			want:   []SortField{{Field: "role"}, {Field: "id"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeSort(tt.fields)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeSort() = %+v, want %+v", got, tt.want)
+			}
+			if last := got[len(got)-1]; last.Field != "id" {
+				t.Errorf("NormalizeSort() ends with %q, want id", last.Field)
+			}
+		})
+	}
+}
@@ -9,9 +9,29 @@ SELECT
     id,
     name,
     email,
+    role,
     created_at,
-    updated_at
+    updated_at,
+    version
 FROM users
 WHERE 
     id = $1 AND 
     deleted_at IS NULL`
+
+const GetByIDIncludingDeletedSQL = `
+-- name: GetUserByIDIncludingDeleted
+-- Params:
+--   $1: id (int64)
+-- Returns: Single row with user data, deleted_at being set on soft deleted users
+SELECT
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version,
+    deleted_at
+FROM users
+WHERE
+    id = $1`
@@ -0,0 +1,16 @@
+package users_sql
+
+const GetCredentialsByEmailSQL = `
+-- name: GetUserCredentialsByEmail
+-- Params:
+--   $1: email (string)
+-- Returns: Single row with the user's id, email, password hash and role
+SELECT
+    id,
+    email,
+    password_hash,
+    role
+FROM users
+WHERE 
+    email = $1 AND 
+    deleted_at IS NULL`
@@ -2,62 +2,226 @@ package users_sql
 
 import (
 	"fmt"
+	"strings"
 )
 
 const ListSQL = `
 -- name: ListUsers
--- Params:
---   $1: limit (int)
---   $2: offset (int)
--- Optional Params (used in WHERE clause):
---   $3: name (string) - for ILIKE search
---   $4: email (string) - for ILIKE search
+-- Built by BuildListSQL: filters, keyset condition and ORDER BY only use
+-- whitelisted columns, and every value is bound as a parameter
 -- Returns: Multiple rows of user data with pagination
-WITH filtered_users AS (
-    SELECT
-        id,
-        name,
-        email,
-        created_at,
-        updated_at
-    FROM users
-    WHERE
-        deleted_at IS NULL
-        AND (
-            ($3 IS NULL OR $3 = '') AND ($4 IS NULL OR $4 = '')
-            OR ($3 IS NOT NULL AND $3 != '' AND name ILIKE '%' || $3 || '%')
-            OR ($4 IS NOT NULL AND $4 != '' AND email ILIKE '%' || $4 || '%')
-        )
-)
 SELECT
     id,
     name,
     email,
+    role,
     created_at,
     updated_at,
-    (SELECT COUNT(*) FROM filtered_users) as total_count
-FROM filtered_users
-%s -- This will be replaced with ORDER BY clause
-LIMIT $1
-OFFSET $2`
+    deleted_at
+FROM users
+WHERE
+    deleted_at IS NULL`
 
-// GetListSQL returns the formatted SQL with the ORDER BY clause
-func GetListSQL(orderBy string) string {
-	return fmt.Sprintf(ListSQL, "ORDER BY "+orderBy)
-}
+const ListDeletedSQL = `
+-- name: ListDeletedUsers
+-- Built by BuildListSQL like ListSQL, for soft deleted users
+-- Returns: Multiple rows of deleted user data with pagination
+SELECT
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    deleted_at
+FROM users
+WHERE
+    deleted_at IS NOT NULL`
 
 const CountSQL = `
 -- name: CountUsers
--- Params:
---   $1: name (string) - for ILIKE search
---   $2: email (string) - for ILIKE search
+-- Built by BuildCountSQL with the same filters as ListSQL
 -- Returns: Total count of matching users
 SELECT COUNT(*)
 FROM users
 WHERE
-    deleted_at IS NULL
-    AND CASE 
-        WHEN $1 != '' THEN name ILIKE '%' || $1 || '%'
-        WHEN $2 != '' THEN email ILIKE '%' || $2 || '%'
-        ELSE true
-    END`
+    deleted_at IS NULL`
+
+const CountDeletedSQL = `
+-- name: CountDeletedUsers
+-- Built by BuildCountSQL with the same filters as ListDeletedSQL
+-- Returns: Total count of matching deleted users
+SELECT COUNT(*)
+FROM users
+WHERE
+    deleted_at IS NOT NULL`
+
+// queryBuilder accumulates SQL fragments and their positional arguments
+type queryBuilder struct {
+	dialect Dialect
+	sql     strings.Builder
+	args    []interface{}
+}
+
+// bind adds a parameter and returns its placeholder
+func (b *queryBuilder) bind(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// bindValue adds a parameter compared with field. SQLite compares
+// timestamps as text, so their values are brought to the stored format.
+func (b *queryBuilder) bindValue(field string, value interface{}) string {
+	placeholder := b.bind(value)
+	if b.dialect == SQLite && columns[field].kind == timeColumn {
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:%%f', %s)", placeholder)
+	}
+	return placeholder
+}
+
+// like returns the case insensitive LIKE condition of the dialect. SQLite's
+// LIKE ignores ASCII case but has no default escape character.
+func (b *queryBuilder) like(field, pattern string) string {
+	if b.dialect == SQLite {
+		return fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, field, b.bind(pattern))
+	}
+	return fmt.Sprintf("%s ILIKE %s", field, b.bind(pattern))
+}
+
+// where appends the filter conditions
+func (b *queryBuilder) where(filters []Filter) error {
+	for _, filter := range filters {
+		args, err := filter.args()
+		if err != nil {
+			return err
+		}
+
+		b.sql.WriteString("\n    AND ")
+		switch filter.Op {
+		case OpLike:
+			b.sql.WriteString(b.like(filter.Field, "%"+escapeLike(args[0].(string))+"%"))
+		case OpIn:
+			placeholders := make([]string, len(args))
+			for i, arg := range args {
+				placeholders[i] = b.bind(arg)
+			}
+			fmt.Fprintf(&b.sql, "%s IN (%s)", filter.Field, strings.Join(placeholders, ", "))
+		default:
+			fmt.Fprintf(&b.sql, "%s %s %s", filter.Field, sqlOperators[filter.Op], b.bindValue(filter.Field, args[0]))
+		}
+	}
+	return nil
+}
+
+// keyset appends the condition selecting the rows after the cursor in sort order.
+// Sort directions may differ per field, so the row comparison is expanded:
+// (a > x) OR (a = x AND b > y) OR (a = x AND b = y AND id > z)
+func (b *queryBuilder) keyset(sort []SortField, cursor *Cursor) error {
+	values := make([]string, len(sort))
+	for i, field := range sort[:len(sort)-1] {
+		arg, err := columns[field.Field].parse(cursor.Values[i])
+		if err != nil {
+			return fmt.Errorf("invalid cursor")
+		}
+		values[i] = b.bindValue(field.Field, arg)
+	}
+	values[len(sort)-1] = b.bind(cursor.ID)
+
+	branches := make([]string, len(sort))
+	for i, field := range sort {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = %s", sort[j].Field, values[j]))
+		}
+
+		operator := ">"
+		if field.Desc != cursor.Backward {
+			operator = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s %s", field.Field, operator, values[i]))
+		branches[i] = "(" + strings.Join(terms, " AND ") + ")"
+	}
+
+	b.sql.WriteString("\n    AND (" + strings.Join(branches, " OR ") + ")")
+	return nil
+}
+
+// orderBy appends the ORDER BY clause, reversed when paging backward
+func (b *queryBuilder) orderBy(sort []SortField, backward bool) {
+	parts := make([]string, len(sort))
+	for i, field := range sort {
+		direction := ASC
+		if field.Desc != backward {
+			direction = DESC
+		}
+		parts[i] = fmt.Sprintf("%s %s", field.Field, direction)
+	}
+	b.sql.WriteString("\nORDER BY " + strings.Join(parts, ", "))
+}
+
+// BuildListSQL compiles the list query in the given dialect, over soft
+// deleted users when deleted is set. sort must be normalized with
+// NormalizeSort. Rows come back in reverse order when the cursor pages backward.
+func BuildListSQL(dialect Dialect, filters []Filter, sort []SortField, cursor *Cursor, limit, offset int, deleted bool) (string, []interface{}, error) {
+	b := &queryBuilder{dialect: dialect}
+	if deleted {
+		b.sql.WriteString(ListDeletedSQL)
+	} else {
+		b.sql.WriteString(ListSQL)
+	}
+
+	if err := b.where(filters); err != nil {
+		return "", nil, err
+	}
+
+	backward := false
+	if cursor != nil {
+		if len(cursor.Values) != len(sort)-1 {
+			return "", nil, fmt.Errorf("invalid cursor")
+		}
+		if err := b.keyset(sort, cursor); err != nil {
+			return "", nil, err
+		}
+		backward = cursor.Backward
+	}
+
+	b.orderBy(sort, backward)
+	fmt.Fprintf(&b.sql, "\nLIMIT %s\nOFFSET %s", b.bind(limit), b.bind(offset))
+
+	return b.sql.String(), b.args, nil
+}
+
+// BuildCountSQL compiles the count query for the given filters in the given
+// dialect, over soft deleted users when deleted is set
+func BuildCountSQL(dialect Dialect, filters []Filter, deleted bool) (string, []interface{}, error) {
+	b := &queryBuilder{dialect: dialect}
+	if deleted {
+		b.sql.WriteString(CountDeletedSQL)
+	} else {
+		b.sql.WriteString(CountSQL)
+	}
+
+	if err := b.where(filters); err != nil {
+		return "", nil, err
+	}
+
+	return b.sql.String(), b.args, nil
+}
+
+// NormalizeSort ends the sort with id, which is unique, so that every row
+// has a distinct position. Fields after an explicit id are dropped.
+func NormalizeSort(fields []SortField) []SortField {
+	normalized := make([]SortField, 0, len(fields)+1)
+	for _, field := range fields {
+		normalized = append(normalized, field)
+		if field.Field == "id" {
+			return normalized
+		}
+	}
+	return append(normalized, SortField{Field: "id"})
+}
+
+// escapeLike escapes the LIKE wildcards so filter values match literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
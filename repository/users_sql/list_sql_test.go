@@ -0,0 +1,168 @@
+package users_sql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// whereClause returns the part of a built query after the fixed ListSQL or CountSQL
+func whereClause(query, base string) string {
+	return strings.TrimSpace(strings.TrimPrefix(query, base))
+}
+
+func TestBuildListSQL(t *testing.T) {
+	byRoleNameDesc := NormalizeSort([]SortField{{Field: "role"}, {Field: "name", Desc: true}})
+
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		filters  []Filter
+		sort     []SortField
+		cursor   *Cursor
+		deleted  bool
+		base     string
+		want     string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "default sort",
+			sort:     NormalizeSort(nil),
+			want:     "ORDER BY id ASC\nLIMIT $1\nOFFSET $2",
+			wantArgs: []interface{}{10, 0},
+		},
+		{
+			name: "filters are numbered before limit and offset",
+			filters: []Filter{
+				{Field: "name", Op: OpLike, Value: "50%_off"},
+				{Field: "role", Op: OpIn, Value: "admin,manager"},
+				{Field: "created_at", Op: OpGte, Value: "2024-01-01"},
+			},
+			sort: NormalizeSort(nil),
+			want: "AND name ILIKE $1\n    AND role IN ($2, $3)\n    AND created_at >= $4\n" +
+				"ORDER BY id ASC\nLIMIT $5\nOFFSET $6",
+			wantArgs: []interface{}{`%50\%\_off%`, "admin", "manager", "2024-01-01 00:00:00", 10, 0},
+		},
+		{
+			name:    "keyset after filters, expanded per sort field",
+			filters: []Filter{{Field: "id", Op: OpNe, Value: "3"}},
+			sort:    byRoleNameDesc,
+			cursor:  &Cursor{Values: []string{"user", "Jane"}, ID: 7},
+			want: "AND id <> $1\n" +
+				"    AND ((role > $2) OR (role = $2 AND name < $3) OR (role = $2 AND name = $3 AND id > $4))\n" +
+				"ORDER BY role ASC, name DESC, id ASC\nLIMIT $5\nOFFSET $6",
+			wantArgs: []interface{}{int64(3), "user", "Jane", int64(7), 10, 0},
+		},
+		{
+			name:   "backward cursor reverses the comparison and the order",
+			sort:   byRoleNameDesc,
+			cursor: &Cursor{Values: []string{"user", "Jane"}, ID: 7, Backward: true},
+			want: "AND ((role < $1) OR (role = $1 AND name > $2) OR (role = $1 AND name = $2 AND id < $3))\n" +
+				"ORDER BY role DESC, name ASC, id DESC\nLIMIT $4\nOFFSET $5",
+			wantArgs: []interface{}{"user", "Jane", int64(7), 10, 0},
+		},
+		{
+			name:     "deleted users",
+			filters:  []Filter{{Field: "email", Op: OpEq, Value: "jane@example.com"}},
+			sort:     NormalizeSort(nil),
+			deleted:  true,
+			base:     ListDeletedSQL,
+			want:     "AND email = $1\nORDER BY id ASC\nLIMIT $2\nOFFSET $3",
+			wantArgs: []interface{}{"jane@example.com", 10, 0},
+		},
+		{
+			name:    "SQLite escapes LIKE and compares timestamps as stored",
+			dialect: SQLite,
+			filters: []Filter{
+				{Field: "name", Op: OpLike, Value: "50%_off"},
+				{Field: "created_at", Op: OpGte, Value: "2024-01-01"},
+			},
+			sort: NormalizeSort(nil),
+			want: "AND name LIKE $1 ESCAPE '\\'\n    AND created_at >= strftime('%Y-%m-%d %H:%M:%f', $2)\n" +
+				"ORDER BY id ASC\nLIMIT $3\nOFFSET $4",
+			wantArgs: []interface{}{`%50\%\_off%`, "2024-01-01 00:00:00", 10, 0},
+		},
+		{
+			name:    "SQLite keyset on a timestamp",
+			dialect: SQLite,
+			sort:    NormalizeSort([]SortField{{Field: "created_at"}}),
+			cursor:  &Cursor{Values: []string{"2024-01-01T10:00:00Z"}, ID: 7},
+			want: "AND ((created_at > strftime('%Y-%m-%d %H:%M:%f', $1)) OR (created_at = strftime('%Y-%m-%d %H:%M:%f', $1) AND id > $2))\n" +
+				"ORDER BY created_at ASC, id ASC\nLIMIT $3\nOFFSET $4",
+			wantArgs: []interface{}{"2024-01-01 10:00:00", int64(7), 10, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := tt.base
+			if base == "" {
+				base = ListSQL
+			}
+			query, args, err := BuildListSQL(tt.dialect, tt.filters, tt.sort, tt.cursor, 10, 0, tt.deleted)
+			if err != nil {
+				t.Fatalf("BuildListSQL() error = %v", err)
+			}
+			if !strings.HasPrefix(query, base) {
+				t.Fatalf("BuildListSQL() = %s, want it to start with %s", query, base)
+			}
+			if got := whereClause(query, base); got != tt.want {
+				t.Errorf("BuildListSQL() clauses =\n%s\nwant\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("BuildListSQL() args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBuildListSQLRejectsInvalidInput(t *testing.T) {
+	sort := NormalizeSort([]SortField{{Field: "created_at"}})
+
+	tests := []struct {
+		name    string
+		filters []Filter
+		cursor  *Cursor
+	}{
+		{name: "unknown filter field", filters: []Filter{{Field: "password_hash", Op: OpEq, Value: "x"}}},
+		{name: "unsupported operator", filters: []Filter{{Field: "role", Op: OpLike, Value: "adm"}}},
+		{name: "cursor with too few values", cursor: &Cursor{ID: 7}},
+		{name: "cursor with too many values", cursor: &Cursor{Values: []string{"2024-01-01", "x"}, ID: 7}},
+		{name: "cursor value of the wrong type", cursor: &Cursor{Values: []string{"not a time"}, ID: 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := BuildListSQL(Postgres, tt.filters, sort, tt.cursor, 10, 0, false); err == nil {
+				t.Error("BuildListSQL() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestBuildCountSQL(t *testing.T) {
+	query, args, err := BuildCountSQL(Postgres, []Filter{
+		{Field: "email", Op: OpEq, Value: "jane@example.com"},
+		{Field: "id", Op: OpIn, Value: "1, 2"},
+	}, false)
+	if err != nil {
+		t.Fatalf("BuildCountSQL() error = %v", err)
+	}
+
+	want := "AND email = $1\n    AND id IN ($2, $3)"
+	if got := whereClause(query, CountSQL); got != want {
+		t.Errorf("BuildCountSQL() clauses =\n%s\nwant\n%s", got, want)
+	}
+	if wantArgs := []interface{}{"jane@example.com", int64(1), int64(2)}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("BuildCountSQL() args = %#v, want %#v", args, wantArgs)
+	}
+
+	query, _, err = BuildCountSQL(Postgres, nil, true)
+	if err != nil || query != CountDeletedSQL {
+		t.Errorf("BuildCountSQL() of deleted users = %s, %v, want CountDeletedSQL", query, err)
+	}
+
+	if _, _, err := BuildCountSQL(Postgres, []Filter{{Field: "id", Op: OpLike, Value: "1"}}, false); err == nil {
+		t.Error("BuildCountSQL() with an unsupported operator error = nil, want an error")
+	}
+}
@@ -0,0 +1,59 @@
+package users_sql
+
+const PatchSQL = `
+-- name: PatchUser
+-- Params:
+--   $1: name (string, nullable) - NULL keeps the current name
+--   $2: email (string, nullable) - NULL keeps the current email
+--   $3: role (string, nullable) - NULL keeps the current role
+--   $4: id (int64)
+--   $5: expected version (int64) - 0 skips the version check
+-- Returns: Single row with the updated user data
+UPDATE users
+SET
+    name = COALESCE($1, name),
+    email = COALESCE($2, email),
+    role = COALESCE($3, role),
+    updated_at = now(),
+    version = version + 1
+WHERE 
+    id = $4 AND 
+    deleted_at IS NULL AND
+    ($5 = 0 OR version = $5)
+RETURNING
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version`
+
+const SQLitePatchSQL = `
+-- name: PatchUser (SQLite)
+-- Params:
+--   $1: name (string, nullable) - NULL keeps the current name
+--   $2: email (string, nullable) - NULL keeps the current email
+--   $3: role (string, nullable) - NULL keeps the current role
+--   $4: id (int64)
+--   $5: expected version (int64) - 0 skips the version check
+-- Returns: Single row with the updated user data
+UPDATE users
+SET
+    name = COALESCE($1, name),
+    email = COALESCE($2, email),
+    role = COALESCE($3, role),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    version = version + 1
+WHERE
+    id = $4 AND
+    deleted_at IS NULL AND
+    ($5 = 0 OR version = $5)
+RETURNING
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version`
@@ -0,0 +1,14 @@
+package users_sql
+
+const PurgeSQL = `
+-- name: PurgeUser
+-- Permanently deletes a user, live or soft deleted. Refresh tokens and
+-- API keys are removed by their ON DELETE CASCADE foreign keys.
+-- Params:
+--   $1: id (int64)
+--   $2: expected version (int64) - 0 skips the version check
+-- Returns: Number of rows affected
+DELETE FROM users
+WHERE 
+    id = $1 AND
+    ($2 = 0 OR version = $2)`
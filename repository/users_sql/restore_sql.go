@@ -0,0 +1,49 @@
+package users_sql
+
+const RestoreSQL = `
+-- name: RestoreUser
+-- Params:
+--   $1: id (int64)
+-- Anonymized users cannot be restored
+-- Returns: Single row with the restored user data
+UPDATE users
+SET
+    deleted_at = NULL,
+    updated_at = now(),
+    version = version + 1
+WHERE 
+    id = $1 AND 
+    deleted_at IS NOT NULL AND
+    anonymized_at IS NULL
+RETURNING
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version`
+
+const SQLiteRestoreSQL = `
+-- name: RestoreUser (SQLite)
+-- Params:
+--   $1: id (int64)
+-- Anonymized users cannot be restored
+-- Returns: Single row with the restored user data
+UPDATE users
+SET
+    deleted_at = NULL,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    version = version + 1
+WHERE
+    id = $1 AND
+    deleted_at IS NOT NULL AND
+    anonymized_at IS NULL
+RETURNING
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version`
@@ -0,0 +1,133 @@
+package users_sql
+
+const RetentionLockSQL = `
+-- name: TryRetentionLock
+-- Session level advisory lock, so only one instance runs the retention job
+-- Params:
+--   $1: lock name (string)
+-- Returns: Whether the lock was acquired
+SELECT pg_try_advisory_lock(hashtext($1))`
+
+const RetentionUnlockSQL = `
+-- name: ReleaseRetentionLock
+-- Params:
+--   $1: lock name (string)
+SELECT pg_advisory_unlock(hashtext($1))`
+
+const PurgeDeletedSQL = `
+-- name: PurgeDeletedUsers
+-- Permanently deletes a batch of users soft deleted before the retention
+-- period. Refresh tokens and API keys go with them through ON DELETE CASCADE.
+-- Params:
+--   $1: retention period (seconds)
+--   $2: batch size (int)
+-- Returns: Number of rows affected
+DELETE FROM users
+WHERE id IN (
+    SELECT id
+    FROM users
+    WHERE deleted_at < now() - make_interval(secs => $1)
+    ORDER BY deleted_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)`
+
+const AnonymizeDeletedSQL = `
+-- name: AnonymizeDeletedUsers
+-- Strips the personal data of a batch of users soft deleted before the
+-- retention period, keeping the row for referential history
+-- Params:
+--   $1: retention period (seconds)
+--   $2: batch size (int)
+-- Returns: Number of rows affected
+WITH batch AS (
+    SELECT id
+    FROM users
+    WHERE
+        deleted_at < now() - make_interval(secs => $1) AND
+        anonymized_at IS NULL
+    ORDER BY deleted_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+),
+deleted_tokens AS (
+    DELETE FROM refresh_tokens WHERE user_id IN (SELECT id FROM batch)
+),
+deleted_api_keys AS (
+    DELETE FROM api_keys WHERE owner_id IN (SELECT id FROM batch)
+)
+UPDATE users
+SET
+    name = 'Deleted user',
+    email = 'deleted-' || users.id || '@anonymized.invalid',
+    password_hash = NULL,
+    anonymized_at = now(),
+    updated_at = now(),
+    version = version + 1
+FROM batch
+WHERE users.id = batch.id`
+
+const SQLitePurgeDeletedSQL = `
+-- name: PurgeDeletedUsers (SQLite)
+-- Refresh tokens and API keys go with the users through ON DELETE CASCADE.
+-- SQLite has a single writer, so no rows need to be skipped.
+-- Params:
+--   $1: retention period (seconds)
+--   $2: batch size (int)
+-- Returns: Number of rows affected
+DELETE FROM users
+WHERE id IN (
+    SELECT id
+    FROM users
+    WHERE deleted_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || $1 || ' seconds')
+    ORDER BY deleted_at
+    LIMIT $2
+)`
+
+// The SQLite anonymization runs as three statements in one transaction,
+// since SQLite has no data modifying CTEs. They select the same batch, as
+// only the last one changes users.
+
+const SQLiteAnonymizeBatchSQL = `
+    SELECT id
+    FROM users
+    WHERE
+        deleted_at < strftime('%Y-%m-%d %H:%M:%f', 'now', '-' || $1 || ' seconds') AND
+        anonymized_at IS NULL
+    ORDER BY deleted_at
+    LIMIT $2`
+
+const SQLiteDeleteBatchTokensSQL = `
+-- name: DeleteAnonymizedRefreshTokens (SQLite)
+-- Params:
+--   $1: retention period (seconds)
+--   $2: batch size (int)
+DELETE FROM refresh_tokens
+WHERE user_id IN (` + SQLiteAnonymizeBatchSQL + `
+)`
+
+const SQLiteDeleteBatchAPIKeysSQL = `
+-- name: DeleteAnonymizedAPIKeys (SQLite)
+-- Params:
+--   $1: retention period (seconds)
+--   $2: batch size (int)
+DELETE FROM api_keys
+WHERE owner_id IN (` + SQLiteAnonymizeBatchSQL + `
+)`
+
+const SQLiteAnonymizeDeletedSQL = `
+-- name: AnonymizeDeletedUsers (SQLite)
+-- Params:
+--   $1: retention period (seconds)
+--   $2: batch size (int)
+-- Returns: Number of rows affected
+UPDATE users
+SET
+    name = 'Deleted user',
+    email = 'deleted-' || id || '@anonymized.invalid',
+    password_hash = NULL,
+    anonymized_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    version = version + 1
+WHERE id IN (` + SQLiteAnonymizeBatchSQL + `
+)`
@@ -10,11 +10,20 @@ const (
 )
 
 type SearchParams struct {
-	Limit  int
-	Offset int
-	Order  SortOrder
-	Name   string
-	Email  string
+	Limit   int
+	Offset  int
+	Filters []Filter
+	Sort    []SortField
+	// Order sets the direction of the default sort by id when Sort is empty
+	Order SortOrder
+
+	// CursorMode switches to keyset pagination. An empty Cursor requests the first page.
+	CursorMode   bool
+	Cursor       string
+	IncludeTotal bool
+
+	// Deleted lists soft deleted users instead of live ones
+	Deleted bool
 }
 
 const (
@@ -36,26 +45,52 @@ func (p *SearchParams) Validate() error {
 		return fmt.Errorf("order must be either ASC or DESC")
 	}
 
-	if p.Name != "" && p.Email != "" {
-		return fmt.Errorf("cannot search by both name and email")
+	for _, filter := range p.Filters {
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range p.Sort {
+		if err := field.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if p.CursorMode && p.Offset != 0 {
+		return fmt.Errorf("cannot use offset with cursor pagination")
 	}
 
 	return nil
 }
 
-// GetOrderBy returns the ORDER BY clause based on the search parameters
-func (p *SearchParams) GetOrderBy() string {
-	if p.Order == "" {
-		p.Order = ASC
+// GetCursor decodes the cursor and checks it was issued for the current sort
+func (p *SearchParams) GetCursor() (*Cursor, error) {
+	if !p.CursorMode || p.Cursor == "" {
+		return nil, nil
 	}
 
-	// Default to ordering by id if no specific field is being searched
-	orderField := "id"
-	if p.Name != "" {
-		orderField = "name"
-	} else if p.Email != "" {
-		orderField = "email"
+	cursor, err := DecodeCursor(p.Cursor)
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Sprintf("%s %s", orderField, p.Order)
+	sort := p.GetSort()
+	if cursor.Sort != FormatSort(sort) {
+		return nil, fmt.Errorf("cursor does not match the requested sort")
+	}
+	if len(cursor.Values) != len(sort)-1 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return cursor, nil
+}
+
+// GetSort returns the normalized sort, which always ends with id.
+// Without an explicit sort, results are ordered by id in the requested order.
+func (p *SearchParams) GetSort() []SortField {
+	if len(p.Sort) == 0 {
+		return NormalizeSort([]SortField{{Field: "id", Desc: p.Order == DESC}})
+	}
+	return NormalizeSort(p.Sort)
 }
@@ -0,0 +1,51 @@
+package users_sql
+
+const SetPasswordSQL = `
+-- name: SetUserPassword
+-- Params:
+--   $1: password_hash (string)
+--   $2: id (int64)
+--   $3: expected version (int64) - 0 skips the version check
+-- Returns: Single row with the updated user data
+UPDATE users
+SET
+    password_hash = $1,
+    updated_at = now(),
+    version = version + 1
+WHERE
+    id = $2 AND
+    deleted_at IS NULL AND
+    ($3 = 0 OR version = $3)
+RETURNING
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version`
+
+const SQLiteSetPasswordSQL = `
+-- name: SetUserPassword (SQLite)
+-- Params:
+--   $1: password_hash (string)
+--   $2: id (int64)
+--   $3: expected version (int64) - 0 skips the version check
+-- Returns: Single row with the updated user data
+UPDATE users
+SET
+    password_hash = $1,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    version = version + 1
+WHERE
+    id = $2 AND
+    deleted_at IS NULL AND
+    ($3 = 0 OR version = $3)
+RETURNING
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version`
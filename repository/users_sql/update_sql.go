@@ -5,14 +5,55 @@ const UpdateSQL = `
 -- Params:
 --   $1: name (string)
 --   $2: email (string)
---   $3: updated_at (timestamp)
+--   $3: role (string) - empty keeps the current role
 --   $4: id (int64)
--- Returns: Number of rows affected
+--   $5: expected version (int64) - 0 skips the version check
+-- Returns: Single row with the updated user data
 UPDATE users
 SET
     name = $1,
     email = $2,
-    updated_at = $3
+    role = COALESCE(NULLIF($3, ''), role),
+    updated_at = now(),
+    version = version + 1
 WHERE 
     id = $4 AND 
-    deleted_at IS NULL`
+    deleted_at IS NULL AND
+    ($5 = 0 OR version = $5)
+RETURNING
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version`
+
+const SQLiteUpdateSQL = `
+-- name: UpdateUser (SQLite)
+-- Params:
+--   $1: name (string)
+--   $2: email (string)
+--   $3: role (string) - empty keeps the current role
+--   $4: id (int64)
+--   $5: expected version (int64) - 0 skips the version check
+-- Returns: Single row with the updated user data
+UPDATE users
+SET
+    name = $1,
+    email = $2,
+    role = COALESCE(NULLIF($3, ''), role),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f', 'now'),
+    version = version + 1
+WHERE
+    id = $4 AND
+    deleted_at IS NULL AND
+    ($5 = 0 OR version = $5)
+RETURNING
+    id,
+    name,
+    email,
+    role,
+    created_at,
+    updated_at,
+    version`
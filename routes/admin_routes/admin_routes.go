@@ -0,0 +1,30 @@
+package admin_routes
+
+import (
+	"goapi/handlers"
+	"goapi/logger"
+	"goapi/middleware"
+	"goapi/models"
+	"goapi/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRoutes configures the operational routes. db is nil when the
+// repositories do not use a database.
+func SetupAdminRoutes(router *gin.Engine, db *repository.DB) {
+	// Initialize handlers
+	var stats handlers.StatsProvider
+	if db != nil {
+		stats = db
+	}
+	adminHandler := handlers.NewAdminHandler(stats, logger.Default())
+
+	// Admin routes, the pool statistics need a database
+	if db != nil {
+		router.GET("/admin/db/stats", middleware.RequirePermission(models.PermSystemMonitor), adminHandler.GetDBStats)
+	}
+	router.GET("/admin/log-level", middleware.RequirePermission(models.PermSystemMonitor), adminHandler.GetLogLevel)
+	router.PUT("/admin/log-level", middleware.RequirePermission(models.PermSystemConfigure), adminHandler.SetLogLevel)
+	router.DELETE("/admin/log-level", middleware.RequirePermission(models.PermSystemConfigure), adminHandler.ResetLogLevel)
+}
@@ -0,0 +1,19 @@
+package api_key_routes
+
+import (
+	"goapi/handlers"
+	"goapi/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAPIKeyRoutes configures all API key routes
+func SetupAPIKeyRoutes(router *gin.Engine, apiKeyService services.APIKeyService) {
+	// Initialize handlers
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+
+	// API key routes
+	router.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+	router.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+	router.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+}
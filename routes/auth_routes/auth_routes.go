@@ -0,0 +1,25 @@
+package auth_routes
+
+import (
+	"goapi/config"
+	"goapi/handlers"
+	"goapi/repository"
+	"goapi/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAuthRoutes configures all authentication routes
+func SetupAuthRoutes(router *gin.Engine, repos *repository.Repositories, cfg *config.AuthConfig) {
+	// Initialize services
+	authService := services.NewAuthService(repos.Users, repos.RefreshTokens, repos.Tx, cfg)
+
+	// Initialize handlers
+	authHandler := handlers.NewAuthHandler(authService)
+
+	// Auth routes
+	auth := router.Group("/auth")
+	auth.POST("/login", authHandler.Login)
+	auth.POST("/refresh", authHandler.Refresh)
+	auth.POST("/logout", authHandler.Logout)
+}
@@ -1,11 +1,19 @@
 package routes
 
 import (
-	"database/sql"
+	"goapi/config"
 	"goapi/middleware"
+	"goapi/models"
+	"goapi/repository"
+	"goapi/routes/admin_routes"
+	"goapi/routes/api_key_routes"
+	"goapi/routes/auth_routes"
 	"goapi/routes/user_routes"
+	"goapi/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -23,24 +31,49 @@ func HelloWorldHandler(c *gin.Context) {
 	})
 }
 
-// SetupRouter configures all the routes for the application
-func SetupRouter(db *sql.DB) *gin.Engine {
+// SetupRouter configures all the routes for the application. store is nil
+// when the repositories do not use a database.
+func SetupRouter(cfg *config.Config, repos *repository.Repositories, store *repository.DB) *gin.Engine {
 	// Create a new gin router without default middleware
 	router := gin.New()
 
+	// Internal error details are only returned to clients in development
+	models.ExposeInternalErrors(cfg.Environment == config.Dev)
+
+	// Report validation errors with the JSON names of the fields
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(models.JSONFieldName)
+	}
+
+	// Identify each request and give it a logger carrying its id
+	router.Use(middleware.RequestID())
 	// Use our custom logger middleware
 	router.Use(middleware.Logger())
 	// Use recovery middleware to handle panics
 	router.Use(gin.Recovery())
+	// Track writes so later reads of the same request skip the replicas
+	router.Use(middleware.DBSession())
 
 	// Swagger documentation - placed before auth middleware to be publicly accessible
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Auth routes - placed before auth middleware so credentials can be obtained
+	auth_routes.SetupAuthRoutes(router, repos, &cfg.Auth)
+
+	// API keys are shared by the authorization middleware and their own routes
+	apiKeyService := services.NewAPIKeyService(repos.APIKeys, repos.Users)
+
 	// Use our custom authorization middleware
-	router.Use(gin.HandlerFunc(middleware.AuthMiddleware()))
+	router.Use(middleware.AuthMiddleware(&cfg.Auth, apiKeyService))
 
 	// Setup user routes
-	user_routes.SetupUserRoutes(router, db)
+	user_routes.SetupUserRoutes(router, repos)
+
+	// Setup API key routes
+	api_key_routes.SetupAPIKeyRoutes(router, apiKeyService)
+
+	// Setup admin routes
+	admin_routes.SetupAdminRoutes(router, store)
 
 	return router
 }
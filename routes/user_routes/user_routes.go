@@ -1,9 +1,9 @@
 package user_routes
 
 import (
-	"database/sql"
-
 	"goapi/handlers"
+	"goapi/middleware"
+	"goapi/models"
 	"goapi/repository"
 	"goapi/services"
 
@@ -11,20 +11,23 @@ import (
 )
 
 // SetupUserRoutes configures all user-related routes
-func SetupUserRoutes(router *gin.Engine, db *sql.DB) {
-	// Initialize repositories
-	userRepo := repository.NewPostgresUserRepository(db)
-
+func SetupUserRoutes(router *gin.Engine, repos *repository.Repositories) {
 	// Initialize services
-	userService := services.NewUserService(userRepo)
+	userService := services.NewUserService(repos.Users, repos.Tx)
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService)
 
-	// User routes
-	router.POST("/users", userHandler.CreateUser)
-	router.GET("/users", userHandler.ListUsers)
+	// User routes - reading and updating a user, and setting its password, is
+	// also allowed on the caller's own record, which the service checks
+	router.POST("/users", middleware.RequirePermission(models.PermUsersCreate), userHandler.CreateUser)
+	router.GET("/users", middleware.RequirePermission(models.PermUsersList), userHandler.ListUsers)
+	router.GET("/users/deleted", middleware.RequirePermission(models.PermUsersRestore), userHandler.ListDeletedUsers)
 	router.GET("/users/:id", userHandler.GetUserByID)
 	router.PUT("/users/:id", userHandler.UpdateUser)
-	router.DELETE("/users/:id", userHandler.DeleteUser)
+	router.PATCH("/users/:id", userHandler.PatchUser)
+	router.PUT("/users/:id/password", userHandler.SetUserPassword)
+	// Hard deletes are checked for users:purge by the service
+	router.DELETE("/users/:id", middleware.RequirePermission(models.PermUsersDelete), userHandler.DeleteUser)
+	router.POST("/users/:id/restore", middleware.RequirePermission(models.PermUsersRestore), userHandler.RestoreUser)
 }
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"goapi/logger"
+	"goapi/models"
+	"goapi/repository"
+)
+
+// apiKeyTag starts every API key so leaked keys are easy to recognize
+const apiKeyTag = "gak"
+
+// APIKeyService defines the interface for API key operations
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, input *models.APIKeyInput) (*models.APIKeyCreated, error)
+	ListAPIKeys(ctx context.Context) ([]*models.APIKeyOutput, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	Authenticate(ctx context.Context, key string) (*models.Principal, error)
+}
+
+// apiKeyService implements APIKeyService
+type apiKeyService struct {
+	keys  repository.APIKeyRepository
+	users repository.UserRepository
+}
+
+// NewAPIKeyService creates a new instance of APIKeyService
+func NewAPIKeyService(keys repository.APIKeyRepository, users repository.UserRepository) APIKeyService {
+	return &apiKeyService{
+		keys:  keys,
+		users: users,
+	}
+}
+
+// keyManager returns the caller if it may manage API keys. Keys cannot
+// manage keys: minting one would let a scoped or expiring key outlive itself,
+// and listing or revoking them is left to the logged in owner.
+func keyManager(ctx context.Context) (*models.Principal, error) {
+	principal, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, models.ErrUnauthorized
+	}
+	if principal.IsAPIKey() {
+		return nil, models.ErrForbidden
+	}
+	return principal, nil
+}
+
+// CreateAPIKey mints a new key owned by the caller. The plain key is only returned here.
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, input *models.APIKeyInput) (*models.APIKeyCreated, error) {
+	principal, err := keyManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([]models.Permission, 0, len(input.Scopes))
+	seen := make(map[models.Permission]bool, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		if !scope.IsValid() {
+			return nil, models.ErrInvalidScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	input.Scopes = scopes
+
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, err
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(input.ExpiresIn) * time.Second
+	key, err := s.keys.Create(ctx, principal.UserID, input, prefix, hashToken(secret), ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyCreated{
+		APIKeyOutput: *key,
+		Key:          strings.Join([]string{apiKeyTag, prefix, secret}, "_"),
+	}, nil
+}
+
+// ListAPIKeys lists the caller's keys
+func (s *apiKeyService) ListAPIKeys(ctx context.Context) ([]*models.APIKeyOutput, error) {
+	principal, err := keyManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.keys.ListByOwner(ctx, principal.UserID)
+}
+
+// RevokeAPIKey revokes one of the caller's keys. Callers allowed to manage
+// API keys can revoke any key.
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id int64) error {
+	principal, err := keyManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := s.keys.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrAPIKeyNotFound
+		}
+		return err
+	}
+
+	// Do not reveal keys owned by someone else
+	if key.OwnerID != principal.UserID && !principal.Can(models.PermAPIKeysManage) {
+		return models.ErrAPIKeyNotFound
+	}
+
+	return s.keys.Revoke(ctx, id)
+}
+
+// Authenticate resolves a plain API key to the principal of its owner, limited to the key's scopes
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (*models.Principal, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyTag {
+		return nil, models.ErrInvalidAPIKey
+	}
+	prefix, secret := parts[1], parts[2]
+
+	apiKey, secretHash, err := s.keys.GetActiveByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(secretHash)) != 1 {
+		return nil, models.ErrInvalidAPIKey
+	}
+
+	owner, err := s.users.GetByID(ctx, int(apiKey.OwnerID))
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, models.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if err := s.keys.Touch(ctx, *apiKey.ID); err != nil {
+		logger.FromContext(ctx).Component(logComponent).Warn("Failed to record API key usage", "api_key_id", *apiKey.ID, "error", err)
+	}
+
+	return &models.Principal{
+		UserID:   apiKey.OwnerID,
+		Role:     owner.Role,
+		APIKeyID: *apiKey.ID,
+		Scopes:   apiKey.Scopes,
+	}, nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"goapi/models"
+	"goapi/repository"
+)
+
+func TestAPIKeyService_KeysCannotManageKeys(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+	owner := addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleAdmin)
+	service := NewAPIKeyService(repos.APIKeys, repos.Users)
+	bearer := asUser(*owner.ID, models.RoleAdmin)
+
+	created, err := service.CreateAPIKey(bearer, &models.APIKeyInput{Name: "deploy", Scopes: []models.Permission{models.PermUsersRead}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	principal, err := service.Authenticate(context.Background(), created.Key)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	apiKey := models.WithPrincipal(context.Background(), principal)
+
+	if _, err := service.CreateAPIKey(apiKey, &models.APIKeyInput{Name: "copy", Scopes: []models.Permission{models.PermUsersRead}}); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("CreateAPIKey() with an API key error = %v, want %v", err, models.ErrForbidden)
+	}
+	if _, err := service.ListAPIKeys(apiKey); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("ListAPIKeys() with an API key error = %v, want %v", err, models.ErrForbidden)
+	}
+	if err := service.RevokeAPIKey(apiKey, *created.ID); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("RevokeAPIKey() with an API key error = %v, want %v", err, models.ErrForbidden)
+	}
+
+	keys, err := service.ListAPIKeys(bearer)
+	if err != nil {
+		t.Fatalf("ListAPIKeys() error = %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("ListAPIKeys() returned %d keys, want 1", len(keys))
+	}
+	if err := service.RevokeAPIKey(bearer, *created.ID); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+	if _, err := service.Authenticate(context.Background(), created.Key); !errors.Is(err, models.ErrInvalidAPIKey) {
+		t.Errorf("Authenticate() with a revoked key error = %v, want %v", err, models.ErrInvalidAPIKey)
+	}
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+	owner := addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleManager)
+	service := NewAPIKeyService(repos.APIKeys, repos.Users)
+
+	created, err := service.CreateAPIKey(asUser(*owner.ID, models.RoleManager), &models.APIKeyInput{
+		Name:   "reports",
+		Scopes: []models.Permission{models.PermUsersList, models.PermUsersList},
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if len(created.Scopes) != 1 {
+		t.Errorf("CreateAPIKey() scopes = %v, want duplicates removed", created.Scopes)
+	}
+
+	principal, err := service.Authenticate(context.Background(), created.Key)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if principal.UserID != *owner.ID || principal.Role != models.RoleManager {
+		t.Errorf("Authenticate() = %+v, want the owner with their role", principal)
+	}
+	// The key is limited to its scopes, even for permissions its owner holds
+	if !principal.Can(models.PermUsersList) || principal.Can(models.PermUsersRead) {
+		t.Errorf("Authenticate() principal scopes = %v, want only %v", principal.Scopes, models.PermUsersList)
+	}
+
+	for _, key := range []string{"", "gak_unknown_secret", created.Key + "x", "other_" + created.Prefix + "_secret"} {
+		if _, err := service.Authenticate(context.Background(), key); !errors.Is(err, models.ErrInvalidAPIKey) {
+			t.Errorf("Authenticate(%q) error = %v, want %v", key, err, models.ErrInvalidAPIKey)
+		}
+	}
+
+	if _, err := service.CreateAPIKey(asUser(*owner.ID, models.RoleManager), &models.APIKeyInput{
+		Name:   "bad",
+		Scopes: []models.Permission{"users:everything"},
+	}); !errors.Is(err, models.ErrInvalidScope) {
+		t.Errorf("CreateAPIKey() with an unknown scope error = %v, want %v", err, models.ErrInvalidScope)
+	}
+}
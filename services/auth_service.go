@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"goapi/config"
+	"goapi/logger"
+	"goapi/models"
+	"goapi/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const tokenTypeBearer = "Bearer"
+
+// AuthService defines the interface for authentication operations
+type AuthService interface {
+	Login(ctx context.Context, input *models.LoginInput) (*models.TokenOutput, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.TokenOutput, error)
+	Logout(ctx context.Context, refreshToken string) error
+}
+
+// authService implements AuthService
+type authService struct {
+	users  repository.UserRepository
+	tokens repository.RefreshTokenRepository
+	tx     repository.TxManager
+	config *config.AuthConfig
+}
+
+// NewAuthService creates a new instance of AuthService
+func NewAuthService(users repository.UserRepository, tokens repository.RefreshTokenRepository, tx repository.TxManager, cfg *config.AuthConfig) AuthService {
+	return &authService{
+		users:  users,
+		tokens: tokens,
+		tx:     tx,
+		config: cfg,
+	}
+}
+
+// Login verifies the user's credentials and starts a new token family
+func (s *authService) Login(ctx context.Context, input *models.LoginInput) (*models.TokenOutput, error) {
+	credentials, err := s.users.GetCredentialsByEmail(ctx, input.Email)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			checkPassword("", input.Password)
+			return nil, models.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if !checkPassword(credentials.PasswordHash, input.Password) {
+		return nil, models.ErrInvalidCredentials
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, credentials.ID, credentials.Email, credentials.Role, familyID)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// can only be used once; presenting an already rotated token revokes the
+// whole family since it indicates the token has leaked.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*models.TokenOutput, error) {
+	tokenHash := hashToken(refreshToken)
+
+	// The old token is only used up if its successor is stored
+	var output *models.TokenOutput
+	err := s.tx.WithTx(ctx, func(ctx context.Context) error {
+		consumed, err := s.tokens.Consume(ctx, tokenHash)
+		if err != nil {
+			return err
+		}
+
+		user, err := s.users.GetByID(ctx, int(consumed.UserID))
+		if err != nil {
+			if errors.Is(err, models.ErrUserNotFound) {
+				return models.ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		output, err = s.issueTokens(ctx, *user.ID, user.Email, user.Role, consumed.FamilyID)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		existing, err := s.tokens.GetByHash(ctx, tokenHash)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, models.ErrInvalidRefreshToken
+			}
+			return nil, err
+		}
+
+		if existing.RevokedAt != nil {
+			logger.FromContext(ctx).Component(logComponent).Warn("Refresh token reuse detected, revoking token family", "user_id", existing.UserID)
+			if err := s.tokens.RevokeFamily(ctx, existing.FamilyID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, models.ErrInvalidRefreshToken
+	}
+
+	return output, nil
+}
+
+// Logout revokes the refresh token and every token rotated from the same login
+func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+	existing, err := s.tokens.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	return s.tokens.RevokeFamily(ctx, existing.FamilyID)
+}
+
+// issueTokens signs a new access token and persists a new refresh token in the given family
+func (s *authService) issueTokens(ctx context.Context, userID int64, email string, role models.Role, familyID string) (*models.TokenOutput, error) {
+	accessToken, err := s.signAccessToken(userID, email, role)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyID,
+	}
+	if err := s.tokens.Create(ctx, token, s.config.RefreshTokenTTL); err != nil {
+		return nil, err
+	}
+
+	return &models.TokenOutput{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    int64(s.config.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (s *authService) signAccessToken(userID int64, email string, role models.Role) (string, error) {
+	key, err := s.config.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := &models.TokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   strconv.FormatInt(userID, 10),
+			Issuer:    s.config.Issuer,
+			Audience:  jwt.ClaimStrings{s.config.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.AccessTokenTTL)),
+		},
+		Email: email,
+		Role:  role,
+	}
+
+	return jwt.NewWithClaims(s.config.SigningMethod(), claims).SignedString(key)
+}
+
+// randomToken returns n random bytes encoded as URL safe base64
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex encoded SHA-256 of a token, which is what gets stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"goapi/config"
+	"goapi/logger"
+	"goapi/models"
+	"goapi/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testPassword = "correct-horse"
+
+var testAuthConfig = &config.AuthConfig{
+	Algorithm:       config.HS256,
+	Secret:          "test-secret",
+	Issuer:          "goapi-test",
+	Audience:        "goapi-test-clients",
+	AccessTokenTTL:  time.Minute,
+	RefreshTokenTTL: time.Hour,
+}
+
+func init() {
+	logger.InitLogger(logger.Options{})
+}
+
+// newTestAuthService returns an auth service backed by the in-memory store
+func newTestAuthService() (AuthService, *repository.Repositories) {
+	repos := repository.NewMemoryRepositories()
+	return NewAuthService(repos.Users, repos.RefreshTokens, repos.Tx, testAuthConfig), repos
+}
+
+// addUser stores a user with the role and, unless it is empty, the
+// password, hashed like the service does
+func addUser(t *testing.T, repos *repository.Repositories, name, email, password string, role models.Role) *models.UserOutput {
+	t.Helper()
+	var hash string
+	if password != "" {
+		var err error
+		if hash, err = hashPassword(password); err != nil {
+			t.Fatalf("hashPassword() error = %v", err)
+		}
+	}
+	user, err := repos.Users.Create(context.Background(), &models.UserInput{Name: name, Email: email, Role: role}, hash)
+	if err != nil {
+		t.Fatalf("Create(%q) error = %v", email, err)
+	}
+	return user
+}
+
+func TestLogin(t *testing.T) {
+	svc, repos := newTestAuthService()
+	addUser(t, repos, "Alice", "alice@example.com", testPassword, models.RoleManager)
+	addUser(t, repos, "Bob", "bob@example.com", "", models.RoleUser)
+
+	tests := []struct {
+		name    string
+		input   models.LoginInput
+		wantErr error
+	}{
+		{"valid credentials", models.LoginInput{Email: "alice@example.com", Password: testPassword}, nil},
+		{"wrong password", models.LoginInput{Email: "alice@example.com", Password: "wrong-password"}, models.ErrInvalidCredentials},
+		{"unknown email", models.LoginInput{Email: "carol@example.com", Password: testPassword}, models.ErrInvalidCredentials},
+		{"user without password", models.LoginInput{Email: "bob@example.com", Password: testPassword}, models.ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := svc.Login(context.Background(), &tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.TokenType != "Bearer" {
+				t.Fatalf("Login() = %+v, want a bearer token pair", tokens)
+			}
+
+			// The access token carries the user's role
+			claims := &models.TokenClaims{}
+			if _, err := jwt.ParseWithClaims(tokens.AccessToken, claims, func(*jwt.Token) (interface{}, error) {
+				return testAuthConfig.VerificationKey()
+			}); err != nil {
+				t.Fatalf("parse access token: %v", err)
+			}
+			if claims.Subject != "1" || claims.Role != models.RoleManager {
+				t.Errorf("access token subject = %q, role = %q, want %q, %q", claims.Subject, claims.Role, "1", models.RoleManager)
+			}
+		})
+	}
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	svc, repos := newTestAuthService()
+	addUser(t, repos, "Alice", "alice@example.com", testPassword, models.RoleManager)
+	ctx := context.Background()
+
+	login, err := svc.Login(ctx, &models.LoginInput{Email: "alice@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	rotated, err := svc.Refresh(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if rotated.RefreshToken == login.RefreshToken {
+		t.Fatal("Refresh() returned the same refresh token")
+	}
+
+	// Replaying the rotated token revokes the whole family, including the
+	// token it was rotated into
+	if _, err := svc.Refresh(ctx, login.RefreshToken); !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() with a rotated token error = %v, want %v", err, models.ErrInvalidRefreshToken)
+	}
+	if _, err := svc.Refresh(ctx, rotated.RefreshToken); !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() after reuse error = %v, want %v", err, models.ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefreshRejectsUnknownToken(t *testing.T) {
+	svc, _ := newTestAuthService()
+
+	if _, err := svc.Refresh(context.Background(), "not-a-token"); !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() error = %v, want %v", err, models.ErrInvalidRefreshToken)
+	}
+}
+
+func TestLogoutRevokesFamily(t *testing.T) {
+	svc, repos := newTestAuthService()
+	addUser(t, repos, "Alice", "alice@example.com", testPassword, models.RoleManager)
+	ctx := context.Background()
+
+	login, err := svc.Login(ctx, &models.LoginInput{Email: "alice@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	rotated, err := svc.Refresh(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// Logging out with any token of the family ends the session
+	if err := svc.Logout(ctx, login.RefreshToken); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if _, err := svc.Refresh(ctx, rotated.RefreshToken); !errors.Is(err, models.ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() after logout error = %v, want %v", err, models.ErrInvalidRefreshToken)
+	}
+
+	// Logging out with an unknown token is not an error
+	if err := svc.Logout(ctx, "not-a-token"); err != nil {
+		t.Fatalf("Logout() with an unknown token error = %v", err)
+	}
+}
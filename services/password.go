@@ -0,0 +1,27 @@
+package services
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyPasswordHash is compared against when a login targets an unknown user
+// so that both paths take roughly the same time.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("goapi-dummy-password"), bcrypt.DefaultCost)
+
+// hashPassword returns the bcrypt hash of a plain text password
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPassword reports whether password matches the stored bcrypt hash
+func checkPassword(hash, password string) bool {
+	if hash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
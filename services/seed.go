@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"goapi/models"
+	"goapi/repository"
+)
+
+// EnsureAdmin creates an admin with the given credentials unless a user with
+// the email exists. It bypasses authorization, so it is only meant for
+// bootstrapping a fresh store.
+func EnsureAdmin(ctx context.Context, users repository.UserRepository, email, password string) error {
+	if _, err := users.GetCredentialsByEmail(ctx, email); !errors.Is(err, models.ErrUserNotFound) {
+		return err
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	_, err = users.Create(ctx, &models.UserInput{
+		Name:  "Administrator",
+		Email: email,
+		Role:  models.RoleAdmin,
+	}, hash)
+	return err
+}
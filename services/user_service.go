@@ -2,48 +2,129 @@ package services
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 
 	"goapi/models"
 	"goapi/repository"
 	"goapi/repository/users_sql"
-
-	"github.com/lib/pq"
 )
 
+// logComponent is the log component of the services, whose log level can
+// be changed on its own at runtime
+const logComponent = "services"
+
 // UserService defines the interface for user-related business operations
 type UserService interface {
 	CreateUser(ctx context.Context, user *models.UserInput) (*models.UserOutput, error)
 	GetUserByID(ctx context.Context, id int64) (*models.UserOutput, error)
-	UpdateUser(ctx context.Context, user *models.UserOutput) error
-	DeleteUser(ctx context.Context, id int64) error
+	UpdateUser(ctx context.Context, user *models.UserOutput, match *models.VersionMatch) (*models.UserOutput, error)
+	PatchUser(ctx context.Context, id int64, apply PatchFunc, match *models.VersionMatch) (*models.UserOutput, error)
+	SetPassword(ctx context.Context, id int64, password string, match *models.VersionMatch) (*models.UserOutput, error)
+	DeleteUser(ctx context.Context, id int64, match *models.VersionMatch) error
+	RestoreUser(ctx context.Context, id int64) (*models.UserOutput, error)
+	PurgeUser(ctx context.Context, id int64, match *models.VersionMatch) error
 	ListUsers(ctx context.Context, params users_sql.SearchParams) (*models.UserList, error)
 }
 
+// PatchFunc returns the changes of a partial update, computed from the
+// current user
+type PatchFunc func(current *models.UserOutput) (*models.UserPatch, error)
+
 // userService implements UserService
 type userService struct {
 	repo repository.UserRepository
+	tx   repository.TxManager
 }
 
 // NewUserService creates a new instance of UserService
-func NewUserService(repo repository.UserRepository) UserService {
+func NewUserService(repo repository.UserRepository, tx repository.TxManager) UserService {
 	return &userService{
 		repo: repo,
+		tx:   tx,
+	}
+}
+
+// authorize returns the caller if it holds the permission. When userID is
+// set, callers acting on their own record only need it in their API key scopes.
+func authorize(ctx context.Context, permission models.Permission, userID *int64) (*models.Principal, error) {
+	principal, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, models.ErrUnauthorized
+	}
+
+	if userID != nil && principal.IsSelf(*userID) && principal.HasScope(permission) {
+		return principal, nil
+	}
+	if !principal.Can(permission) {
+		return nil, models.ErrForbidden
 	}
+	return principal, nil
+}
+
+// checkPrecondition verifies an If-Match precondition against the stored
+// user and returns the version the write must apply to, or 0 without one.
+// It is called in the transaction of the write, so it reads the primary.
+// Soft deleted users only match when includeDeleted is set, for writes
+// such as purges that apply to them.
+func (s *userService) checkPrecondition(ctx context.Context, id int64, match *models.VersionMatch, includeDeleted bool) (int64, error) {
+	if match == nil {
+		return 0, nil
+	}
+
+	getByID := s.repo.GetByID
+	if includeDeleted {
+		getByID = s.repo.GetByIDIncludingDeleted
+	}
+	current, err := getByID(ctx, int(id))
+	if err != nil {
+		return 0, err
+	}
+	if !match.Matches(current.Version) {
+		return 0, models.ErrPreconditionFailed
+	}
+	return current.Version, nil
+}
+
+// writeError maps a failed conditional write. With a version the user was
+// found by checkPrecondition, so a missing row means it changed meanwhile.
+func writeError(err error, expectedVersion int64) error {
+	if errors.Is(err, models.ErrUserNotFound) && expectedVersion != 0 {
+		return models.ErrPreconditionFailed
+	}
+	return err
 }
 
 // CreateUser creates a new user
 func (s *userService) CreateUser(ctx context.Context, user *models.UserInput) (*models.UserOutput, error) {
-	// Create user in repository
-	createdUser, err := s.repo.Create(user)
+	principal, err := authorize(ctx, models.PermUsersCreate, nil)
 	if err != nil {
-		var pqErr *pq.Error
-		if errors.As(err, &pqErr) {
-			if pqErr.Code == "23505" { // Unique violation
-				return nil, models.ErrDuplicate
-			}
+		return nil, err
+	}
+
+	// New users get the least privileged role unless the caller may assign roles
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if !user.Role.IsValid() {
+		return nil, models.ErrInvalidRole
+	}
+	if user.Role != models.RoleUser && !principal.Can(models.PermRolesAssign) {
+		return nil, models.ErrForbidden
+	}
+
+	// Hash the password, if one was provided
+	var passwordHash string
+	if user.Password != "" {
+		hash, err := hashPassword(user.Password)
+		if err != nil {
+			return nil, err
 		}
+		passwordHash = hash
+	}
+
+	// Create user in repository
+	createdUser, err := s.repo.Create(ctx, user, passwordHash)
+	if err != nil {
 		return nil, err
 	}
 
@@ -53,12 +134,13 @@ func (s *userService) CreateUser(ctx context.Context, user *models.UserInput) (*
 
 // GetUserByID retrieves a user by their ID
 func (s *userService) GetUserByID(ctx context.Context, id int64) (*models.UserOutput, error) {
+	if _, err := authorize(ctx, models.PermUsersRead, &id); err != nil {
+		return nil, err
+	}
+
 	// get user in repository
-	createdUser, err := s.repo.GetByID(int(id))
+	createdUser, err := s.repo.GetByID(ctx, int(id))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, models.ErrNotFound
-		}
 		return nil, err
 	}
 
@@ -67,57 +149,302 @@ func (s *userService) GetUserByID(ctx context.Context, id int64) (*models.UserOu
 
 }
 
-// UpdateUser updates an existing user
-func (s *userService) UpdateUser(ctx context.Context, user *models.UserOutput) error {
+// UpdateUser updates an existing user and returns it as persisted
+func (s *userService) UpdateUser(ctx context.Context, user *models.UserOutput, match *models.VersionMatch) (*models.UserOutput, error) {
+	principal, err := authorize(ctx, models.PermUsersUpdate, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Validate user data
 	if user.Name == "" {
-		return models.ErrInvalidName
+		return nil, models.ErrInvalidName
 	}
 	if user.Email == "" {
-		return models.ErrInvalidEmail
+		return nil, models.ErrInvalidEmail
+	}
+	if user.Role != "" && !user.Role.IsValid() {
+		return nil, models.ErrInvalidRole
 	}
 
-	// Update user in repository
-	return s.repo.Update(user)
+	var updated *models.UserOutput
+	err = s.tx.WithTx(ctx, func(ctx context.Context) error {
+		// Changing a role requires permission to assign roles
+		if user.Role != "" && !principal.Can(models.PermRolesAssign) {
+			current, err := s.repo.GetByID(ctx, int(*user.ID))
+			if err != nil {
+				return err
+			}
+			if current.Role != user.Role {
+				return models.ErrForbidden
+			}
+		}
+
+		expectedVersion, err := s.checkPrecondition(ctx, *user.ID, match, false)
+		if err != nil {
+			return err
+		}
+
+		// Update user in repository
+		updated, err = s.repo.Update(ctx, user, expectedVersion)
+		return writeError(err, expectedVersion)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// PatchUser loads the user, asks apply for the fields to change and
+// updates only those, returning the updated user
+func (s *userService) PatchUser(ctx context.Context, id int64, apply PatchFunc, match *models.VersionMatch) (*models.UserOutput, error) {
+	principal, err := authorize(ctx, models.PermUsersUpdate, &id)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.UserOutput
+	err = s.tx.WithTx(ctx, func(ctx context.Context) error {
+		// Patches are applied to the current representation of the user
+		current, err := s.repo.GetByID(ctx, int(id))
+		if err != nil {
+			return err
+		}
+
+		// Fail before computing the patch; the write checks the version again
+		var expectedVersion int64
+		if match != nil {
+			if !match.Matches(current.Version) {
+				return models.ErrPreconditionFailed
+			}
+			expectedVersion = current.Version
+		}
+
+		patch, err := apply(current)
+		if err != nil {
+			return err
+		}
+
+		// Validate user data
+		if patch.Name != nil && *patch.Name == "" {
+			return models.ErrInvalidName
+		}
+		if patch.Email != nil && *patch.Email == "" {
+			return models.ErrInvalidEmail
+		}
+		if patch.Role != nil && !patch.Role.IsValid() {
+			return models.ErrInvalidRole
+		}
+
+		// Nothing to change, return the current user as is
+		if patch.IsEmpty() {
+			user = current
+			return nil
+		}
+
+		// Changing a role requires permission to assign roles
+		if patch.Role != nil && *patch.Role != current.Role && !principal.Can(models.PermRolesAssign) {
+			return models.ErrForbidden
+		}
+
+		user, err = s.repo.Patch(ctx, int(id), patch, expectedVersion)
+		return writeError(err, expectedVersion)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// SetPassword sets the password of a user, e.g. one created without a
+// password, who cannot log in until then. Users can set their own.
+func (s *userService) SetPassword(ctx context.Context, id int64, password string, match *models.VersionMatch) (*models.UserOutput, error) {
+	if _, err := authorize(ctx, models.PermUsersUpdate, &id); err != nil {
+		return nil, err
+	}
+
+	// Hash before the transaction, bcrypt being slow on purpose
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.UserOutput
+	err = s.tx.WithTx(ctx, func(ctx context.Context) error {
+		expectedVersion, err := s.checkPrecondition(ctx, id, match, false)
+		if err != nil {
+			return err
+		}
+
+		updated, err = s.repo.SetPassword(ctx, int(id), passwordHash, expectedVersion)
+		return writeError(err, expectedVersion)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 // DeleteUser deletes a user by their ID
-func (s *userService) DeleteUser(ctx context.Context, id int64) error {
-	return s.repo.Delete(int(id))
+func (s *userService) DeleteUser(ctx context.Context, id int64, match *models.VersionMatch) error {
+	if _, err := authorize(ctx, models.PermUsersDelete, nil); err != nil {
+		return err
+	}
+
+	return s.tx.WithTx(ctx, func(ctx context.Context) error {
+		expectedVersion, err := s.checkPrecondition(ctx, id, match, false)
+		if err != nil {
+			return err
+		}
+
+		return writeError(s.repo.Delete(ctx, int(id), expectedVersion), expectedVersion)
+	})
+}
+
+// RestoreUser undoes the soft deletion of a user
+func (s *userService) RestoreUser(ctx context.Context, id int64) (*models.UserOutput, error) {
+	if _, err := authorize(ctx, models.PermUsersRestore, nil); err != nil {
+		return nil, err
+	}
+
+	// Fails with models.ErrEmailTaken if another user took the email since the deletion
+	return s.repo.Restore(ctx, int(id))
+}
+
+// PurgeUser permanently deletes a user, including soft deleted ones
+func (s *userService) PurgeUser(ctx context.Context, id int64, match *models.VersionMatch) error {
+	if _, err := authorize(ctx, models.PermUsersPurge, nil); err != nil {
+		return err
+	}
+
+	return s.tx.WithTx(ctx, func(ctx context.Context) error {
+		expectedVersion, err := s.checkPrecondition(ctx, id, match, true)
+		if err != nil {
+			return err
+		}
+
+		return writeError(s.repo.Purge(ctx, int(id), expectedVersion), expectedVersion)
+	})
 }
 
 // ListUsers retrieves a list of users with pagination and filtering
 func (s *userService) ListUsers(ctx context.Context, params users_sql.SearchParams) (*models.UserList, error) {
+	if _, err := authorize(ctx, models.PermUsersList, nil); err != nil {
+		return nil, err
+	}
+	if params.Deleted {
+		if _, err := authorize(ctx, models.PermUsersRestore, nil); err != nil {
+			return nil, err
+		}
+	}
+
 	// Validate search parameters
 	if err := params.Validate(); err != nil {
-		return nil, err
+		return nil, models.ErrInvalidSearchParams.Wrap(err)
+	}
+	cursor, err := params.GetCursor()
+	if err != nil {
+		return nil, models.ErrInvalidSearchParams.Wrap(err)
 	}
 
 	// Convert search params to repository params
 	repoParams := repository.ListParams{
 		Limit:   params.Limit,
 		Offset:  params.Offset,
-		Name:    params.Name,
-		Email:   params.Email,
-		OrderBy: params.GetOrderBy(),
+		Filters: params.Filters,
+		Sort:    params.GetSort(),
+		Cursor:  cursor,
+		Deleted: params.Deleted,
+	}
+
+	// In cursor mode fetch one extra row to know whether there is another page
+	if params.CursorMode {
+		repoParams.Limit++
 	}
 
 	// Get users from repository with pagination and filtering
-	users, totalCount, err := s.repo.List(repoParams)
+	users, err := s.repo.List(ctx, repoParams)
 	if err != nil {
 		return nil, err
 	}
 
+	result := &models.UserList{
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}
+
+	if params.CursorMode {
+		backward := cursor != nil && cursor.Backward
+		hasMore := len(users) > params.Limit
+		if hasMore {
+			users = users[:params.Limit]
+		}
+		// Backward pages are fetched in reverse order
+		if backward {
+			for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+				users[i], users[j] = users[j], users[i]
+			}
+		}
+
+		if len(users) > 0 {
+			// Paging backward always leaves the page we came from ahead of us
+			if hasMore || backward {
+				result.NextCursor = newCursor(repoParams.Sort, users[len(users)-1], false).Encode()
+			}
+			// Paging forward from a cursor always leaves the page we came from behind us
+			if (hasMore && backward) || (!backward && cursor != nil) {
+				result.PrevCursor = newCursor(repoParams.Sort, users[0], true).Encode()
+			}
+		}
+	}
+
+	if params.IncludeTotal {
+		totalCount, err := s.repo.Count(ctx, repoParams)
+		if err != nil {
+			return nil, err
+		}
+		result.TotalCount = &totalCount
+	}
+
 	// Convert []*models.UserOutput to []models.UserOutput
-	userOutputs := make([]models.UserOutput, len(users))
+	result.Users = make([]models.UserOutput, len(users))
 	for i, user := range users {
-		userOutputs[i] = *user
+		result.Users[i] = *user
 	}
 
-	return &models.UserList{
-		Users:      userOutputs,
-		TotalCount: totalCount,
-		Limit:      params.Limit,
-		Offset:     params.Offset,
-	}, nil
+	return result, nil
+}
+
+// newCursor builds the cursor positioned on user for the given normalized sort
+func newCursor(sort []users_sql.SortField, user *models.UserOutput, backward bool) *users_sql.Cursor {
+	cursor := &users_sql.Cursor{
+		Sort:     users_sql.FormatSort(sort),
+		ID:       *user.ID,
+		Backward: backward,
+	}
+
+	// The last sort field is always id, which the cursor stores separately
+	for _, field := range sort[:len(sort)-1] {
+		cursor.Values = append(cursor.Values, sortValue(user, field.Field))
+	}
+	return cursor
+}
+
+// sortValue returns the value of a sortable column of user
+func sortValue(user *models.UserOutput, field string) string {
+	switch field {
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "role":
+		return string(user.Role)
+	case "created_at":
+		return user.CreatedAt
+	case "updated_at":
+		return user.UpdatedAt
+	default:
+		return ""
+	}
 }
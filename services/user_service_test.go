@@ -0,0 +1,592 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"goapi/models"
+	"goapi/repository"
+	"goapi/repository/users_sql"
+)
+
+// newTestUserService returns a user service backed by the in-memory store
+func newTestUserService(t *testing.T) (UserService, *repository.Repositories) {
+	t.Helper()
+	repos := repository.NewMemoryRepositories()
+	return NewUserService(repos.Users, repos.Tx), repos
+}
+
+// asUser returns a context authenticated as the user with the role
+func asUser(userID int64, role models.Role) context.Context {
+	return models.WithPrincipal(context.Background(), &models.Principal{UserID: userID, Role: role})
+}
+
+func TestCreateUser_RoleAssignment(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		role     models.Role
+		wantRole models.Role
+		wantErr  error
+	}{
+		{name: "user without users:create", ctx: asUser(1, models.RoleUser), wantErr: models.ErrForbidden},
+		{name: "admin gets the user role by default", ctx: asUser(1, models.RoleAdmin), wantRole: models.RoleUser},
+		{name: "admin assigns a role", ctx: asUser(1, models.RoleAdmin), role: models.RoleManager, wantRole: models.RoleManager},
+		{name: "unknown role", ctx: asUser(1, models.RoleAdmin), role: "owner", wantErr: models.ErrInvalidRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestUserService(t)
+			user, err := svc.CreateUser(tt.ctx, &models.UserInput{Name: "Jane Doe", Email: "jane@example.com", Role: tt.role})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateUser() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && user.Role != tt.wantRole {
+				t.Errorf("CreateUser() role = %q, want %q", user.Role, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestGetUserByID_Authorization(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+	addUser(t, repos, "John Doe", "john@example.com", "", models.RoleUser)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      int64
+		wantErr error
+	}{
+		{name: "unauthenticated", ctx: context.Background(), id: 1, wantErr: models.ErrUnauthorized},
+		{name: "user reads self", ctx: asUser(1, models.RoleUser), id: 1},
+		{name: "user reads another user", ctx: asUser(1, models.RoleUser), id: 2, wantErr: models.ErrForbidden},
+		{name: "manager reads another user", ctx: asUser(1, models.RoleManager), id: 2},
+		{name: "admin reads missing user", ctx: asUser(1, models.RoleAdmin), id: 999, wantErr: models.ErrUserNotFound},
+		{
+			name: "API key without the users:read scope reads self",
+			ctx: models.WithPrincipal(context.Background(), &models.Principal{
+				UserID: 1, Role: models.RoleAdmin, APIKeyID: 1, Scopes: []models.Permission{models.PermUsersList},
+			}),
+			id:      1,
+			wantErr: models.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := svc.GetUserByID(tt.ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetUserByID() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && *user.ID != tt.id {
+				t.Errorf("GetUserByID() id = %d, want %d", *user.ID, tt.id)
+			}
+		})
+	}
+}
+
+func TestUpdateUser_RoleChangeRequiresRolesAssign(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	jane := addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+
+	update := &models.UserOutput{ID: jane.ID, Name: "Jane Admin", Email: jane.Email, Role: models.RoleAdmin}
+	if _, err := svc.UpdateUser(asUser(1, models.RoleUser), update, nil); !errors.Is(err, models.ErrForbidden) {
+		t.Fatalf("UpdateUser() promoting self error = %v, want %v", err, models.ErrForbidden)
+	}
+
+	// Users can update their own record as long as the role stays the same
+	update.Role = models.RoleUser
+	if _, err := svc.UpdateUser(asUser(1, models.RoleUser), update, nil); err != nil {
+		t.Fatalf("UpdateUser() on self error = %v", err)
+	}
+
+	update.Role = models.RoleManager
+	updated, err := svc.UpdateUser(asUser(99, models.RoleAdmin), update, nil)
+	if err != nil {
+		t.Fatalf("UpdateUser() by an admin error = %v", err)
+	}
+	if stored, _ := repos.Users.GetByID(context.Background(), 1); !reflect.DeepEqual(updated, stored) || stored.Role != models.RoleManager || stored.Name != "Jane Admin" {
+		t.Errorf("UpdateUser() = %+v, want the stored user %+v with the new name and role", updated, stored)
+	}
+}
+
+func TestUpdateAndDelete_MissingUser(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+	admin := asUser(99, models.RoleAdmin)
+
+	missing := int64(2)
+	if _, err := svc.UpdateUser(admin, &models.UserOutput{ID: &missing, Name: "John Doe", Email: "john@example.com"}, nil); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("UpdateUser() of a missing user error = %v, want %v", err, models.ErrUserNotFound)
+	}
+	if err := svc.DeleteUser(admin, missing, nil); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("DeleteUser() of a missing user error = %v, want %v", err, models.ErrUserNotFound)
+	}
+
+	if err := svc.DeleteUser(admin, 1, nil); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	id := int64(1)
+	if _, err := svc.UpdateUser(admin, &models.UserOutput{ID: &id, Name: "Jane Roe", Email: "jane@example.com"}, nil); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("UpdateUser() of a deleted user error = %v, want %v", err, models.ErrUserNotFound)
+	}
+	if err := svc.DeleteUser(admin, 1, nil); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("DeleteUser() of a deleted user error = %v, want %v", err, models.ErrUserNotFound)
+	}
+}
+
+func TestSetPassword(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	addUser(t, repos, "Alice", "alice@example.com", "", models.RoleUser)
+	addUser(t, repos, "Bob", "bob@example.com", testPassword, models.RoleUser)
+	addUser(t, repos, "Carol", "carol@example.com", "", models.RoleUser)
+	auth := NewAuthService(repos.Users, repos.RefreshTokens, repos.Tx, testAuthConfig)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      int64
+		wantErr error
+	}{
+		{name: "own password", ctx: asUser(1, models.RoleUser), id: 1},
+		{name: "another user's password", ctx: asUser(1, models.RoleUser), id: 2, wantErr: models.ErrForbidden},
+		{name: "manager sets another user's password", ctx: asUser(1, models.RoleManager), id: 2, wantErr: models.ErrForbidden},
+		{name: "admin sets another user's password", ctx: asUser(2, models.RoleAdmin), id: 3},
+		{name: "unauthenticated", ctx: context.Background(), id: 1, wantErr: models.ErrUnauthorized},
+		{name: "missing user", ctx: asUser(1, models.RoleAdmin), id: 999, wantErr: models.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.SetPassword(tt.ctx, tt.id, "new-password", nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetPassword() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Users created without a password can now log in with the new one
+	for _, email := range []string{"alice@example.com", "carol@example.com"} {
+		if _, err := auth.Login(context.Background(), &models.LoginInput{Email: email, Password: "new-password"}); err != nil {
+			t.Fatalf("Login(%q) with the new password error = %v", email, err)
+		}
+	}
+	// The other user's password is unchanged
+	if _, err := auth.Login(context.Background(), &models.LoginInput{Email: "bob@example.com", Password: testPassword}); err != nil {
+		t.Fatalf("Login() with the old password error = %v", err)
+	}
+}
+
+// setName returns a PatchFunc renaming the user
+func setName(name string) PatchFunc {
+	return func(*models.UserOutput) (*models.UserPatch, error) {
+		return &models.UserPatch{Name: &name}, nil
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	admin, user := models.RoleAdmin, models.RoleUser
+	setRole := func(role *models.Role) PatchFunc {
+		return func(*models.UserOutput) (*models.UserPatch, error) {
+			return &models.UserPatch{Role: role}, nil
+		}
+	}
+	updateOnlyKey := models.WithPrincipal(context.Background(), &models.Principal{
+		UserID: 1, Role: models.RoleUser, APIKeyID: 1, Scopes: []models.Permission{models.PermUsersUpdate},
+	})
+	invalidPatch := models.NewAppError(http.StatusBadRequest, "invalid patch", nil)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		id       int64
+		apply    PatchFunc
+		wantErr  error
+		wantName string
+	}{
+		{name: "user patches self", ctx: asUser(1, models.RoleUser), id: 1, apply: setName("Jane Roe"), wantName: "Jane Roe"},
+		{name: "API key with only users:update patches its owner", ctx: updateOnlyKey, id: 1, apply: setName("Jane Roe"), wantName: "Jane Roe"},
+		{name: "API key with only users:update patches another user", ctx: updateOnlyKey, id: 2, apply: setName("Jane Roe"), wantErr: models.ErrForbidden},
+		{name: "user patches another user", ctx: asUser(1, models.RoleUser), id: 2, apply: setName("Jane Roe"), wantErr: models.ErrForbidden},
+		{name: "manager patches another user", ctx: asUser(3, models.RoleManager), id: 2, apply: setName("John Roe"), wantErr: models.ErrForbidden},
+		{name: "admin patches another user", ctx: asUser(3, models.RoleAdmin), id: 2, apply: setName("John Roe"), wantName: "John Roe"},
+		{name: "unauthenticated", ctx: context.Background(), id: 1, apply: setName("Jane Roe"), wantErr: models.ErrUnauthorized},
+		{name: "missing user", ctx: asUser(3, models.RoleAdmin), id: 999, apply: setName("Jane Roe"), wantErr: models.ErrUserNotFound},
+		{name: "user promotes self", ctx: asUser(1, models.RoleUser), id: 1, apply: setRole(&admin), wantErr: models.ErrForbidden},
+		{name: "user keeps own role", ctx: asUser(1, models.RoleUser), id: 1, apply: setRole(&user), wantName: "Jane Doe"},
+		{name: "admin assigns a role", ctx: asUser(3, models.RoleAdmin), id: 2, apply: setRole(&admin), wantName: "John Doe"},
+		{name: "empty name", ctx: asUser(1, models.RoleUser), id: 1, apply: setName(""), wantErr: models.ErrInvalidName},
+		{
+			name: "invalid patch",
+			ctx:  asUser(1, models.RoleUser),
+			id:   1,
+			apply: func(*models.UserOutput) (*models.UserPatch, error) {
+				return nil, invalidPatch
+			},
+			wantErr: invalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repos := newTestUserService(t)
+			addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+			addUser(t, repos, "John Doe", "john@example.com", "", models.RoleUser)
+
+			var seen *models.UserOutput
+			apply := func(current *models.UserOutput) (*models.UserPatch, error) {
+				seen = current
+				return tt.apply(current)
+			}
+
+			updated, err := svc.PatchUser(tt.ctx, tt.id, apply, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PatchUser() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if seen == nil || *seen.ID != tt.id {
+				t.Errorf("PatchUser() applied the patch to %+v, want user %d", seen, tt.id)
+			}
+			if updated.Name != tt.wantName {
+				t.Errorf("PatchUser() name = %q, want %q", updated.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	admin := asUser(99, models.RoleAdmin)
+	writes := map[string]func(svc UserService, match *models.VersionMatch) error{
+		"UpdateUser": func(svc UserService, match *models.VersionMatch) error {
+			id := int64(1)
+			_, err := svc.UpdateUser(admin, &models.UserOutput{ID: &id, Name: "Jane Roe", Email: "jane@example.com"}, match)
+			return err
+		},
+		"PatchUser": func(svc UserService, match *models.VersionMatch) error {
+			_, err := svc.PatchUser(admin, 1, setName("Jane Roe"), match)
+			return err
+		},
+		"SetPassword": func(svc UserService, match *models.VersionMatch) error {
+			_, err := svc.SetPassword(admin, 1, "new-password", match)
+			return err
+		},
+		"DeleteUser": func(svc UserService, match *models.VersionMatch) error {
+			return svc.DeleteUser(admin, 1, match)
+		},
+	}
+
+	tests := []struct {
+		name    string
+		match   *models.VersionMatch
+		wantErr error
+	}{
+		{name: "no precondition"},
+		{name: "current version", match: &models.VersionMatch{Versions: []int64{1}}},
+		{name: "one of several versions", match: &models.VersionMatch{Versions: []int64{7, 1}}},
+		{name: "any version", match: &models.VersionMatch{Any: true}},
+		{name: "stale version", match: &models.VersionMatch{Versions: []int64{2}}, wantErr: models.ErrPreconditionFailed},
+		{name: "no parsable version", match: &models.VersionMatch{}, wantErr: models.ErrPreconditionFailed},
+	}
+
+	for write, do := range writes {
+		for _, tt := range tests {
+			t.Run(write+"/"+tt.name, func(t *testing.T) {
+				svc, repos := newTestUserService(t)
+				addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+
+				if err := do(svc, tt.match); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("%s() error = %v, want %v", write, err, tt.wantErr)
+				}
+				if tt.wantErr != nil {
+					if user, err := repos.Users.GetByID(context.Background(), 1); err != nil || user.Version != 1 {
+						t.Errorf("%s() changed the user despite the failed precondition", write)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestIfMatch_WriteBumpsVersion(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+	admin := asUser(99, models.RoleAdmin)
+
+	patched, err := svc.PatchUser(admin, 1, setName("Jane Roe"), &models.VersionMatch{Versions: []int64{1}})
+	if err != nil {
+		t.Fatalf("PatchUser() error = %v", err)
+	}
+	if patched.Version != 2 {
+		t.Fatalf("PatchUser() version = %d, want 2", patched.Version)
+	}
+
+	// The ETag read before the patch no longer matches
+	if _, err := svc.SetPassword(admin, 1, "new-password", &models.VersionMatch{Versions: []int64{1}}); !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Fatalf("SetPassword() with the old version error = %v, want %v", err, models.ErrPreconditionFailed)
+	}
+	updated, err := svc.SetPassword(admin, 1, "new-password", &models.VersionMatch{Versions: []int64{2}})
+	if err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	if updated.Version != 3 {
+		t.Errorf("SetPassword() version = %d, want 3", updated.Version)
+	}
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+	addUser(t, repos, "John Doe", "john@example.com", "", models.RoleUser)
+	admin := asUser(99, models.RoleAdmin)
+
+	if err := svc.DeleteUser(admin, 1, nil); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := svc.GetUserByID(admin, 1); !errors.Is(err, models.ErrUserNotFound) {
+		t.Fatalf("GetUserByID() of a deleted user error = %v, want %v", err, models.ErrUserNotFound)
+	}
+	if _, err := svc.PatchUser(admin, 1, setName("Jane Roe"), nil); !errors.Is(err, models.ErrUserNotFound) {
+		t.Fatalf("PatchUser() of a deleted user error = %v, want %v", err, models.ErrUserNotFound)
+	}
+
+	live, err := svc.ListUsers(admin, users_sql.SearchParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	deleted, err := svc.ListUsers(admin, users_sql.SearchParams{Limit: 10, Deleted: true})
+	if err != nil {
+		t.Fatalf("ListUsers() of deleted users error = %v", err)
+	}
+	if len(live.Users) != 1 || *live.Users[0].ID != 2 {
+		t.Errorf("ListUsers() = %+v, want only user 2", live.Users)
+	}
+	if len(deleted.Users) != 1 || *deleted.Users[0].ID != 1 || deleted.Users[0].DeletedAt == nil {
+		t.Errorf("ListUsers() of deleted users = %+v, want only user 1 with deleted_at", deleted.Users)
+	}
+
+	restored, err := svc.RestoreUser(admin, 1)
+	if err != nil {
+		t.Fatalf("RestoreUser() error = %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Errorf("RestoreUser() deleted_at = %v, want nil", *restored.DeletedAt)
+	}
+	if _, err := svc.GetUserByID(admin, 1); err != nil {
+		t.Errorf("GetUserByID() of a restored user error = %v", err)
+	}
+	if _, err := svc.RestoreUser(admin, 1); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("RestoreUser() of a live user error = %v, want %v", err, models.ErrUserNotFound)
+	}
+}
+
+func TestRestoreUser_EmailReused(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+	admin := asUser(99, models.RoleAdmin)
+
+	if err := svc.DeleteUser(admin, 1, nil); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	// Emails are only unique among live users
+	if _, err := svc.CreateUser(admin, &models.UserInput{Name: "Jane Roe", Email: "jane@example.com"}); err != nil {
+		t.Fatalf("CreateUser() with the email of a deleted user error = %v", err)
+	}
+	if _, err := svc.RestoreUser(admin, 1); !errors.Is(err, models.ErrEmailTaken) {
+		t.Fatalf("RestoreUser() error = %v, want %v", err, models.ErrEmailTaken)
+	}
+}
+
+func TestSoftDeletedUsers_AdminOnly(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+	manager := asUser(99, models.RoleManager)
+
+	if _, err := svc.ListUsers(manager, users_sql.SearchParams{Limit: 10, Deleted: true}); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("ListUsers() of deleted users as a manager error = %v, want %v", err, models.ErrForbidden)
+	}
+	if _, err := svc.RestoreUser(manager, 1); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("RestoreUser() as a manager error = %v, want %v", err, models.ErrForbidden)
+	}
+	if err := svc.PurgeUser(manager, 1, nil); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("PurgeUser() as a manager error = %v, want %v", err, models.ErrForbidden)
+	}
+}
+
+func TestPurgeUser_IfMatchOnDeletedUser(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	addUser(t, repos, "Jane Doe", "jane@example.com", "", models.RoleUser)
+	admin := asUser(99, models.RoleAdmin)
+
+	if err := svc.DeleteUser(admin, 1, nil); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	deleted, err := repos.Users.GetByIDIncludingDeleted(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetByIDIncludingDeleted() error = %v", err)
+	}
+	if deleted.DeletedAt == nil {
+		t.Fatal("GetByIDIncludingDeleted() DeletedAt = nil, want the deletion time")
+	}
+
+	stale := &models.VersionMatch{Versions: []int64{deleted.Version + 1}}
+	if err := svc.PurgeUser(admin, 1, stale); !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Fatalf("PurgeUser() with a stale version error = %v, want %v", err, models.ErrPreconditionFailed)
+	}
+	current := &models.VersionMatch{Versions: []int64{deleted.Version}}
+	if err := svc.PurgeUser(admin, 1, current); err != nil {
+		t.Fatalf("PurgeUser() with the current version error = %v", err)
+	}
+	if _, err := repos.Users.GetByIDIncludingDeleted(context.Background(), 1); !errors.Is(err, models.ErrUserNotFound) {
+		t.Fatalf("GetByIDIncludingDeleted() after purge error = %v, want %v", err, models.ErrUserNotFound)
+	}
+}
+
+// pageThrough lists every user in cursor mode, following next_cursor, and
+// returns their ids in the order they were served along with the cursors
+func pageThrough(t *testing.T, svc UserService, params users_sql.SearchParams) ([]int64, []string) {
+	t.Helper()
+	ctx := asUser(1, models.RoleAdmin)
+	params.CursorMode = true
+
+	var ids []int64
+	var cursors []string
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatal("ListUsers() never stopped returning a next cursor")
+		}
+		list, err := svc.ListUsers(ctx, params)
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+		for _, user := range list.Users {
+			ids = append(ids, *user.ID)
+		}
+		if list.NextCursor == "" {
+			return ids, cursors
+		}
+		cursors = append(cursors, list.NextCursor)
+		params.Cursor = list.NextCursor
+	}
+}
+
+func TestListUsers_CursorPagingWithTies(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	// Several users share a name and a role so pages must break ties by the
+	// following sort fields and finally by id
+	for i, u := range []struct {
+		name string
+		role models.Role
+	}{
+		{"Bob", models.RoleUser}, {"Alice", models.RoleManager}, {"Bob", models.RoleAdmin}, {"Alice", models.RoleUser},
+		{"Bob", models.RoleUser}, {"Carol", models.RoleUser}, {"Bob", models.RoleUser},
+	} {
+		id := int64(i + 1)
+		addUser(t, repos, u.name, fmt.Sprintf("user%d@example.com", id), "", u.role)
+	}
+
+	tests := []struct {
+		name    string
+		sort    string
+		filters []users_sql.Filter
+		order   users_sql.SortOrder
+		wantIDs []int64
+	}{
+		{name: "by id", wantIDs: []int64{1, 2, 3, 4, 5, 6, 7}},
+		{name: "by id descending", order: users_sql.DESC, wantIDs: []int64{7, 6, 5, 4, 3, 2, 1}},
+		{
+			name:    "by name with a filter",
+			sort:    "name",
+			filters: []users_sql.Filter{{Field: "name", Op: users_sql.OpLike, Value: "o"}},
+			wantIDs: []int64{1, 3, 5, 7, 6},
+		},
+		{name: "by name descending", sort: "-name", wantIDs: []int64{6, 1, 3, 5, 7, 2, 4}},
+		{name: "by role then name descending", sort: "role,-name", wantIDs: []int64{3, 2, 6, 1, 5, 7, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort, err := users_sql.ParseSort(tt.sort)
+			if err != nil {
+				t.Fatalf("ParseSort() error = %v", err)
+			}
+			for _, limit := range []int{1, 2, 3} {
+				ids, _ := pageThrough(t, svc, users_sql.SearchParams{Limit: limit, Sort: sort, Filters: tt.filters, Order: tt.order})
+				if !reflect.DeepEqual(ids, tt.wantIDs) {
+					t.Errorf("paged ids with limit %d = %v, want %v with no duplicates or gaps", limit, ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestListUsers_CursorPagingBackward(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	for i := int64(1); i <= 5; i++ {
+		addUser(t, repos, fmt.Sprintf("User %d", i), fmt.Sprintf("user%d@example.com", i), "", models.RoleUser)
+	}
+	ctx := asUser(1, models.RoleAdmin)
+
+	_, cursors := pageThrough(t, svc, users_sql.SearchParams{Limit: 2})
+	last, err := svc.ListUsers(ctx, users_sql.SearchParams{Limit: 2, CursorMode: true, Cursor: cursors[len(cursors)-1]})
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+
+	// Following prev_cursor from the last page serves the previous page again
+	prev, err := svc.ListUsers(ctx, users_sql.SearchParams{Limit: 2, CursorMode: true, Cursor: last.PrevCursor})
+	if err != nil {
+		t.Fatalf("ListUsers() with prev_cursor error = %v", err)
+	}
+	var ids []int64
+	for _, user := range prev.Users {
+		ids = append(ids, *user.ID)
+	}
+	if !reflect.DeepEqual(ids, []int64{3, 4}) {
+		t.Errorf("previous page ids = %v, want [3 4]", ids)
+	}
+	if prev.NextCursor == "" || prev.PrevCursor == "" {
+		t.Errorf("previous page cursors = %q, %q, want both", prev.NextCursor, prev.PrevCursor)
+	}
+}
+
+func TestListUsers_RejectsForeignCursor(t *testing.T) {
+	svc, repos := newTestUserService(t)
+	for i := int64(1); i <= 3; i++ {
+		addUser(t, repos, fmt.Sprintf("User %d", i), fmt.Sprintf("user%d@example.com", i), "", models.RoleUser)
+	}
+	ctx := asUser(1, models.RoleAdmin)
+
+	byEmail := []users_sql.SortField{{Field: "email"}}
+	_, cursors := pageThrough(t, svc, users_sql.SearchParams{Limit: 1, Sort: byEmail})
+
+	tests := []struct {
+		name   string
+		params users_sql.SearchParams
+	}{
+		{name: "different sort", params: users_sql.SearchParams{Limit: 1, Cursor: cursors[0]}},
+		{name: "different order", params: users_sql.SearchParams{Limit: 1, Sort: []users_sql.SortField{{Field: "email", Desc: true}}, Cursor: cursors[0]}},
+		{name: "additional sort field", params: users_sql.SearchParams{Limit: 1, Sort: []users_sql.SortField{{Field: "email"}, {Field: "name"}}, Cursor: cursors[0]}},
+		{name: "tampered", params: users_sql.SearchParams{Limit: 1, Sort: byEmail, Cursor: cursors[0][:len(cursors[0])-3]}},
+		{name: "with an offset", params: users_sql.SearchParams{Limit: 1, Offset: 1, Sort: byEmail, Cursor: cursors[0]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.CursorMode = true
+			_, err := svc.ListUsers(ctx, tt.params)
+			appErr, ok := models.IsAppError(err)
+			if !ok || appErr.Code != http.StatusBadRequest {
+				t.Fatalf("ListUsers() error = %v, want a 400", err)
+			}
+		})
+	}
+}